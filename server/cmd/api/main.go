@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	"gatekeepr/internal/auditsink"
 	"gatekeepr/internal/auth"
 	"gatekeepr/internal/database"
 	"gatekeepr/internal/handlers"
 	authMiddleware "gatekeepr/internal/middleware"
+	"gatekeepr/internal/notify"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -25,7 +30,103 @@ func main() {
 	}
 	log.Println("Database initialized successfully!")
 
+	handlers.AllowSelfApproval = os.Getenv("ALLOW_SELF_APPROVAL") == "true"
+	if maxMinutes, err := strconv.Atoi(os.Getenv("BREAK_GLASS_MAX_MINUTES")); err == nil && maxMinutes > 0 {
+		handlers.BreakGlassMaxMinutes = maxMinutes
+	}
+	if group := os.Getenv("SECURITY_GROUP"); group != "" {
+		handlers.SecurityGroupName = group
+	}
+	// Set before any worker starts; checkpoints, action links and review reports are not
+	// signed with the fallback key
+	if key := os.Getenv("SIGNING_KEY"); key != "" {
+		handlers.SigningKey = []byte(key)
+	}
+
+	slaInterval := time.Minute
+	if interval, err := time.ParseDuration(os.Getenv("SLA_CHECK_INTERVAL")); err == nil && interval > 0 {
+		slaInterval = interval
+	}
+	handlers.StartSLAWorker(context.Background(), slaInterval)
+	handlers.StartCampaignWorker(context.Background(), 5*time.Minute)
+
+	dormancyInterval := time.Hour
+	if interval, err := time.ParseDuration(os.Getenv("DORMANCY_CHECK_INTERVAL")); err == nil && interval > 0 {
+		dormancyInterval = interval
+	}
+	handlers.StartDormancyWorker(context.Background(), dormancyInterval)
+
+	provisioningInterval := 30 * time.Second
+	if interval, err := time.ParseDuration(os.Getenv("PROVISIONING_INTERVAL")); err == nil && interval > 0 {
+		provisioningInterval = interval
+	}
+	handlers.StartProvisioningWorker(context.Background(), provisioningInterval)
+
+	reconciliationInterval := 6 * time.Hour
+	if interval, err := time.ParseDuration(os.Getenv("RECONCILIATION_INTERVAL")); err == nil && interval > 0 {
+		reconciliationInterval = interval
+	}
+	handlers.StartReconciliationWorker(context.Background(), reconciliationInterval)
+
+	webhookInterval := 10 * time.Second
+	if interval, err := time.ParseDuration(os.Getenv("WEBHOOK_INTERVAL")); err == nil && interval > 0 {
+		webhookInterval = interval
+	}
+	handlers.StartWebhookWorker(context.Background(), webhookInterval)
+
+	expiryInterval := time.Minute
+	if interval, err := time.ParseDuration(os.Getenv("EXPIRY_CHECK_INTERVAL")); err == nil && interval > 0 {
+		expiryInterval = interval
+	}
+	if hours, err := strconv.Atoi(os.Getenv("EXPIRY_NOTICE_HOURS")); err == nil && hours > 0 {
+		handlers.ExpiryNoticeWindow = time.Duration(hours) * time.Hour
+	}
+	handlers.StartExpiryWorker(context.Background(), expiryInterval)
+
+	checkpointInterval := time.Hour
+	if interval, err := time.ParseDuration(os.Getenv("AUDIT_CHECKPOINT_INTERVAL")); err == nil && interval > 0 {
+		checkpointInterval = interval
+	}
+	handlers.StartAuditCheckpointWorker(context.Background(), checkpointInterval)
+
+	if dir := os.Getenv("AUDIT_ARCHIVE_DIR"); dir != "" {
+		handlers.AuditArchiveDir = dir
+	}
+	archiveInterval := 24 * time.Hour
+	if interval, err := time.ParseDuration(os.Getenv("AUDIT_ARCHIVE_INTERVAL")); err == nil && interval > 0 {
+		archiveInterval = interval
+	}
+	handlers.StartAuditArchiveWorker(context.Background(), archiveInterval)
+
+	if window, err := time.ParseDuration(os.Getenv("AUTHZ_DENIAL_WINDOW")); err == nil && window > 0 {
+		handlers.AuthzDenialWindow = window
+	}
+	authMiddleware.OnForbidden = handlers.RecordAuthzDenial
+	handlers.StartAuthzDenialFlusher(context.Background())
+
+	var sinkConfig auditsink.ForwarderConfig
+	sinkConfig.BufferSize, _ = strconv.Atoi(os.Getenv("AUDIT_SINK_BUFFER"))
+	sinkConfig.BatchSize, _ = strconv.Atoi(os.Getenv("AUDIT_SINK_BATCH_SIZE"))
+	sinkConfig.MaxAttempts, _ = strconv.Atoi(os.Getenv("AUDIT_SINK_MAX_ATTEMPTS"))
+	handlers.StartAuditSinks(context.Background(), auditSinks(), sinkConfig)
+
+	handlers.NotificationChannels = append(notificationChannels(), handlers.InboxChannel{})
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	handlers.PublicURL = "http://localhost:" + port
+	if url := os.Getenv("PUBLIC_URL"); url != "" {
+		handlers.PublicURL = url
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("ACTION_LINK_TTL")); err == nil && ttl > 0 {
+		handlers.ActionLinkTTL = ttl
+	}
+
 	r := chi.NewRouter()
+	r.Use(authMiddleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -38,7 +139,8 @@ func main() {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 			}
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-Id")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -58,6 +160,10 @@ func main() {
 	r.Get("/check-setup", handlers.CheckSetup)
 	r.Post("/setup", handlers.Setup)
 
+	// Approve/reject links from notifications; the signed token authenticates the approver
+	r.Get("/actions/{token}", handlers.ShowActionLink)
+	r.Post("/actions/{token}", handlers.ConfirmActionLink)
+
 	// Authenticated routes
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.Auth)
@@ -68,16 +174,127 @@ func main() {
 			w.Write([]byte("Hello, " + claims.Email))
 		})
 
+		// Notification preferences and inbox (any authenticated user)
+		r.Route("/api/notifications", func(r chi.Router) {
+			r.Get("/preferences", handlers.GetNotificationPreferences)
+			r.Put("/preferences", handlers.UpdateNotificationPreferences)
+			r.Post("/test", handlers.SendTestNotification)
+
+			// In-app inbox
+			r.Get("/", handlers.ListNotifications)
+			r.Delete("/", handlers.ClearNotifications)
+			r.Get("/unread-count", handlers.GetUnreadNotificationCount)
+			r.Get("/stream", handlers.StreamNotifications)
+			r.Post("/read-all", handlers.MarkAllNotificationsRead)
+			r.Post("/{id}/read", handlers.MarkNotificationRead)
+			r.Delete("/{id}", handlers.DeleteNotification)
+		})
+
 		// Access requests (any authenticated user)
 		r.Route("/api/access", func(r chi.Router) {
+			r.Get("/check", handlers.CheckToolAccess)
 			r.Post("/request", handlers.CreateAccessRequest)
 			r.Get("/my-requests", handlers.GetMyRequests)
 			r.Get("/requests", handlers.ListAccessRequests)
 			r.Get("/requests/pending", handlers.GetPendingRequests)
 			r.Post("/requests/{id}/approve", handlers.ApproveAccessRequest)
 			r.Post("/requests/{id}/reject", handlers.RejectAccessRequest)
+			r.Post("/requests/{id}/cancel", handlers.CancelAccessRequest)
+			r.Post("/requests/{id}/extend", handlers.ExtendAccessRequest)
+			r.Post("/requests/{id}/re-request", handlers.ReRequestAccess)
+			r.Get("/requests/{id}/comments", handlers.ListRequestComments)
+			r.Post("/requests/{id}/comments", handlers.AddRequestComment)
+			r.Get("/requests/{id}/provisioning", handlers.GetRequestProvisioning)
+			r.Get("/my-bundle-requests", handlers.GetMyBundleRequests)
+			r.Get("/bundle-requests/{id}", handlers.GetBundleRequest)
+			r.Post("/bundle-requests/{id}/approve", handlers.ApproveBundleRequest)
+			r.Post("/bundle-requests/{id}/reject", handlers.RejectBundleRequest)
 			r.Post("/grant", handlers.DirectGrant)
 			r.Post("/revoke", handlers.RevokeAccess)
+			r.Get("/break-glass/reviews", handlers.ListBreakGlassReviews)
+			r.Post("/break-glass/reviews/{id}/acknowledge", handlers.AcknowledgeBreakGlassReview)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("access.break_glass"))
+				r.Post("/break-glass", handlers.BreakGlassAccess)
+			})
+		})
+
+		// Provisioning connectors
+		r.Route("/api/connectors", func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission("connectors.manage"))
+			r.Get("/", handlers.ListConnectors)
+			r.Post("/", handlers.CreateConnector)
+			r.Put("/{id}", handlers.UpdateConnector)
+			r.Delete("/{id}", handlers.DeleteConnector)
+			r.Post("/{id}/test", handlers.TestConnector)
+			r.Get("/{id}/jobs", handlers.ListConnectorJobs)
+			r.Post("/jobs/{id}/retry", handlers.RetryProvisioningJob)
+			r.Post("/{id}/reconcile", handlers.ReconcileConnector)
+			r.Get("/{id}/reconciliations", handlers.ListReconciliationRuns)
+			r.Get("/reconciliations/{id}", handlers.GetReconciliationRun)
+			r.Post("/reconciliations/findings/{id}/remediate", handlers.RemediateReconciliationFinding)
+		})
+
+		// Outbound webhooks
+		r.Route("/api/webhooks", func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission("webhooks.manage"))
+			r.Get("/", handlers.ListWebhooks)
+			r.Post("/", handlers.CreateWebhook)
+			r.Get("/{id}", handlers.GetWebhook)
+			r.Put("/{id}", handlers.UpdateWebhook)
+			r.Delete("/{id}", handlers.DeleteWebhook)
+			r.Get("/{id}/deliveries", handlers.ListWebhookDeliveries)
+			r.Post("/deliveries/{id}/redeliver", handlers.RedeliverWebhookDelivery)
+		})
+
+		// Access bundles
+		r.Route("/api/bundles", func(r chi.Router) {
+			r.Get("/", handlers.ListAccessBundles)
+			r.Get("/{id}", handlers.GetAccessBundle)
+			r.Post("/{id}/request", handlers.RequestAccessBundle)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.manage"))
+				r.Post("/", handlers.CreateAccessBundle)
+				r.Put("/{id}", handlers.UpdateAccessBundle)
+				r.Delete("/{id}", handlers.DeleteAccessBundle)
+			})
+		})
+
+		// User directory attributes
+		r.Route("/api/users", func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission("users.update"))
+			r.Put("/{id}/org", handlers.UpdateUserOrg)
+		})
+
+		// Access certification campaigns
+		r.Route("/api/reviews", func(r chi.Router) {
+			r.Get("/my-items", handlers.ListMyReviewItems)
+			r.Post("/items/{id}/decide", handlers.DecideReviewItem)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("reviews.read"))
+				r.Get("/campaigns", handlers.ListReviewCampaigns)
+				r.Get("/campaigns/{id}", handlers.GetReviewCampaign)
+				r.Get("/campaigns/{id}/items", handlers.ListCampaignItems)
+				r.Get("/campaigns/{id}/report", handlers.GetCampaignReport)
+				r.Post("/reports/verify", handlers.VerifyCampaignReport)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("reviews.manage"))
+				r.Post("/campaigns", handlers.CreateReviewCampaign)
+				r.Post("/campaigns/{id}/complete", handlers.CompleteReviewCampaign)
+				r.Post("/campaigns/{id}/cancel", handlers.CancelReviewCampaign)
+				r.Put("/items/{id}/reviewer", handlers.ReassignReviewItem)
+			})
+		})
+
+		// Approval delegation (out-of-office substitution)
+		r.Route("/api/delegations", func(r chi.Router) {
+			r.Get("/", handlers.ListDelegations)
+			r.Post("/", handlers.CreateDelegation)
+			r.Delete("/{id}", handlers.RevokeDelegation)
 		})
 
 		// Roles management
@@ -147,7 +364,11 @@ func main() {
 		r.Route("/api/tools", func(r chi.Router) {
 			r.Get("/", handlers.ListTools)
 			r.Get("/categories", handlers.GetToolCategories)
+			r.Get("/owned", handlers.ListMyOwnedTools)
 			r.Get("/{id}", handlers.GetTool)
+			r.Get("/{id}/owners", handlers.ListToolOwners)
+			r.Get("/{id}/access", handlers.GetToolAccessHolders)
+			r.Get("/{id}/access-levels", handlers.ListToolAccessLevels)
 
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.RequirePermission("tools.create"))
@@ -156,6 +377,9 @@ func main() {
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.RequirePermission("tools.update"))
 				r.Put("/{id}", handlers.UpdateTool)
+				r.Put("/{id}/access-levels", handlers.SetToolAccessLevels)
+				r.Post("/{id}/owners", handlers.AddToolOwner)
+				r.Delete("/{id}/owners/{ownerType}/{ownerId}", handlers.RemoveToolOwner)
 			})
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.RequirePermission("tools.delete"))
@@ -173,26 +397,189 @@ func main() {
 			r.Post("/access/grant", handlers.BulkGrantAccess)
 		})
 
+		// Auto-approval rules
+		r.Route("/api/auto-approval", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.read"))
+				r.Get("/rules", handlers.ListAutoApprovalRules)
+				r.Post("/dry-run", handlers.DryRunAutoApproval)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.manage"))
+				r.Post("/rules", handlers.CreateAutoApprovalRule)
+				r.Put("/rules/{id}", handlers.UpdateAutoApprovalRule)
+				r.Delete("/rules/{id}", handlers.DeleteAutoApprovalRule)
+			})
+		})
+
+		// Approval SLAs
+		r.Route("/api/sla", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.read"))
+				r.Get("/policies", handlers.ListSLAPolicies)
+				r.Get("/breaches", handlers.ListSLABreaches)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.manage"))
+				r.Post("/policies", handlers.CreateSLAPolicy)
+				r.Put("/policies/{id}", handlers.UpdateSLAPolicy)
+				r.Delete("/policies/{id}", handlers.DeleteSLAPolicy)
+			})
+		})
+
+		// Tool usage signals
+		r.Route("/api/usage", func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission("usage.report"))
+			r.Post("/report", handlers.ReportToolUsage)
+		})
+
+		// Dormant access
+		r.Route("/api/dormancy", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.read"))
+				r.Get("/policies", handlers.ListDormancyPolicies)
+				r.Get("/report", handlers.GetDormancyReport)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.manage"))
+				r.Post("/policies", handlers.CreateDormancyPolicy)
+				r.Put("/policies/{id}", handlers.UpdateDormancyPolicy)
+				r.Delete("/policies/{id}", handlers.DeleteDormancyPolicy)
+			})
+		})
+
+		// Separation of duties
+		r.Route("/api/sod", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.read"))
+				r.Get("/rules", handlers.ListSoDRules)
+				r.Get("/violations", handlers.ListSoDViolations)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("policies.manage"))
+				r.Post("/rules", handlers.CreateSoDRule)
+				r.Put("/rules/{id}", handlers.UpdateSoDRule)
+				r.Delete("/rules/{id}", handlers.DeleteSoDRule)
+				r.Post("/violations/{id}/approve", handlers.ApproveSoDException)
+				r.Post("/violations/{id}/reject", handlers.RejectSoDException)
+			})
+		})
+
 		// Audit logs
 		r.Route("/api/audit", func(r chi.Router) {
 			r.Use(authMiddleware.RequirePermission("audit.read"))
 			r.Get("/logs", handlers.ListAuditLogs)
+			r.Get("/logs/{id}", handlers.GetAuditLog)
+			r.Get("/search", handlers.SearchAuditLogs)
 			r.Get("/categories", handlers.GetAuditLogCategories)
+			r.Get("/verify", handlers.VerifyAuditChain)
+			r.Get("/checkpoints", handlers.ListAuditCheckpoints)
+			r.Get("/sinks", handlers.ListAuditSinks)
+			r.Get("/sinks/dead-letters", handlers.ListAuditSinkDeadLetters)
+			r.Get("/denials", handlers.GetAuthzDenialReport)
 
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.RequirePermission("audit.export"))
 				r.Get("/export", handlers.ExportAuditLogs)
+				r.Post("/checkpoints", handlers.CreateAuditCheckpoint)
+				r.Post("/sinks/test", handlers.TestAuditSinks)
+				r.Post("/sinks/{name}/replay", handlers.ReplayAuditSinkDeadLetters)
+			})
+
+			// Retention, archival and legal holds
+			r.Get("/retention", handlers.ListAuditRetentionPolicies)
+			r.Get("/legal-holds", handlers.ListAuditLegalHolds)
+			r.Get("/archives", handlers.ListAuditArchives)
+			r.Get("/archives/{id}/entries", handlers.ListAuditArchiveEntries)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission("audit.manage"))
+				r.Put("/retention/{category}", handlers.SetAuditRetentionPolicy)
+				r.Delete("/retention/{category}", handlers.DeleteAuditRetentionPolicy)
+				r.Post("/legal-holds", handlers.CreateAuditLegalHold)
+				r.Post("/legal-holds/{id}/release", handlers.ReleaseAuditLegalHold)
+				r.Post("/archives", handlers.RunAuditArchiver)
+				r.Post("/archives/{id}/restore", handlers.RestoreAuditArchive)
 			})
 		})
 	})
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
 	log.Printf("Server starting on port %s", port)
 	if err := http.ListenAndServe(":"+port, r); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// notificationChannels builds the notification channels configured in the environment:
+// email when SMTP_HOST is set and chat when SLACK_WEBHOOK_URL is set. Without either,
+// notifications go to the server log.
+func notificationChannels() []notify.Channel {
+	var channels []notify.Channel
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			from = "gatekeepr@localhost"
+		}
+		email, err := notify.NewSMTPChannel(notify.SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+		if err != nil {
+			log.Fatalf("Invalid SMTP configuration: %v", err)
+		}
+		channels = append(channels, email)
+	}
+
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		slack, err := notify.NewSlackChannel(url)
+		if err != nil {
+			log.Fatalf("Invalid Slack configuration: %v", err)
+		}
+		channels = append(channels, slack)
+	}
+
+	if len(channels) == 0 {
+		channels = append(channels, notify.LogChannel{})
+	}
+	return channels
+}
+
+// auditSinks builds the audit sinks configured in the environment: RFC 5424 syslog
+// when AUDIT_SYSLOG_URL is set and OCSF JSON over HTTP when AUDIT_OCSF_URL is set
+func auditSinks() []auditsink.Sink {
+	var sinks []auditsink.Sink
+
+	if url := os.Getenv("AUDIT_SYSLOG_URL"); url != "" {
+		facility, _ := strconv.Atoi(os.Getenv("AUDIT_SYSLOG_FACILITY"))
+		syslog, err := auditsink.NewSyslogSink(auditsink.SyslogConfig{
+			URL:      url,
+			Facility: facility,
+			AppName:  os.Getenv("AUDIT_SYSLOG_APP_NAME"),
+			CAFile:   os.Getenv("AUDIT_SYSLOG_CA_FILE"),
+			CertFile: os.Getenv("AUDIT_SYSLOG_CERT_FILE"),
+			KeyFile:  os.Getenv("AUDIT_SYSLOG_KEY_FILE"),
+		})
+		if err != nil {
+			log.Fatalf("Invalid syslog sink configuration: %v", err)
+		}
+		sinks = append(sinks, syslog)
+	}
+
+	if url := os.Getenv("AUDIT_OCSF_URL"); url != "" {
+		ocsf, err := auditsink.NewOCSFSink(auditsink.OCSFConfig{
+			URL:   url,
+			Token: os.Getenv("AUDIT_OCSF_TOKEN"),
+		})
+		if err != nil {
+			log.Fatalf("Invalid OCSF sink configuration: %v", err)
+		}
+		sinks = append(sinks, ocsf)
+	}
+
+	return sinks
+}
@@ -0,0 +1,117 @@
+// Command audit-sink-stub is a local collector for trying out audit sinks. It prints
+// syslog messages received over UDP and TCP and OCSF events posted over HTTP.
+//
+//	audit-sink-stub -syslog :5514 -http :9095
+//	AUDIT_SYSLOG_URL=tcp://localhost:5514 AUDIT_OCSF_URL=http://localhost:9095/ocsf ...
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func main() {
+	syslogAddr := flag.String("syslog", ":5514", "address to receive syslog on, over both UDP and TCP")
+	httpAddr := flag.String("http", ":9095", "address to receive OCSF events on")
+	failures := flag.Int("fail", 0, "answer this many OCSF posts with 503 before succeeding, to exercise retries")
+	flag.Parse()
+
+	go listenUDP(*syslogAddr)
+	go listenTCP(*syslogAddr)
+
+	var mu sync.Mutex
+	remaining := *failures
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mu.Lock()
+		fail := remaining > 0
+		if fail {
+			remaining--
+		}
+		mu.Unlock()
+		if fail {
+			http.Error(w, "Temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		var events []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+			http.Error(w, "Expected a JSON array of events", http.StatusBadRequest)
+			return
+		}
+		for _, e := range events {
+			log.Printf("ocsf %s", e)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	log.Printf("Audit sink stub receiving syslog on %s and OCSF on %s", *syslogAddr, *httpAddr)
+	log.Fatal(http.ListenAndServe(*httpAddr, nil))
+}
+
+func listenUDP(addr string) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on udp %s: %v", addr, err)
+	}
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("udp read failed: %v", err)
+			continue
+		}
+		log.Printf("syslog/udp %s", buf[:n])
+	}
+}
+
+func listenTCP(addr string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on tcp %s: %v", addr, err)
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("tcp accept failed: %v", err)
+			continue
+		}
+		go readFrames(conn)
+	}
+}
+
+// readFrames reads octet-counted messages ("LEN SP MSG"), falling back to one message
+// per line for senders that use newline framing
+func readFrames(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		head, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(head))
+		if err != nil {
+			rest, _ := r.ReadString('\n')
+			log.Printf("syslog/tcp %s", strings.TrimRight(head+rest, "\n"))
+			continue
+		}
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		log.Printf("syslog/tcp %s", msg)
+	}
+}
@@ -0,0 +1,82 @@
+// Command connector-stub is a local target system for trying out the HTTP connector.
+// It keeps accounts in memory and implements the provision, deprovision and accounts
+// endpoints the connector calls.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"gatekeepr/internal/connectors"
+)
+
+type stub struct {
+	mu       sync.Mutex
+	accounts map[string]connectors.Account
+	failures int
+}
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	failures := flag.Int("fail", 0, "answer this many calls with 503 before succeeding, to exercise retries")
+	flag.Parse()
+
+	s := &stub{accounts: map[string]connectors.Account{}, failures: *failures}
+
+	http.HandleFunc("/provision", s.handle(func(g connectors.Grant) {
+		s.accounts[g.UserEmail] = connectors.Account{UserEmail: g.UserEmail, AccessLevel: g.AccessLevel}
+	}))
+	http.HandleFunc("/deprovision", s.handle(func(g connectors.Grant) {
+		delete(s.accounts, g.UserEmail)
+	}))
+	http.HandleFunc("/accounts", s.listAccounts)
+
+	log.Printf("Connector stub listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func (s *stub) handle(apply func(connectors.Grant)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var grant connectors.Grant
+		if err := json.NewDecoder(r.Body).Decode(&grant); err != nil || grant.UserEmail == "" {
+			http.Error(w, "Invalid grant", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.failures > 0 {
+			s.failures--
+			http.Error(w, "Temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		apply(grant)
+		log.Printf("%s %s (%s)", r.URL.Path, grant.UserEmail, grant.AccessLevel)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *stub) listAccounts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	accounts := make([]connectors.Account, 0, len(s.accounts))
+	for _, a := range s.accounts {
+		accounts = append(accounts, a)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].UserEmail < accounts[j].UserEmail })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
@@ -0,0 +1,265 @@
+// Package auditchain makes the audit log tamper-evident. Each entry stores a hash of
+// its content and of the entry before it, so editing or deleting an entry breaks every
+// link after it. Signed checkpoints pin the chain head so it cannot be silently
+// rebuilt from scratch.
+package auditchain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gatekeepr/internal/models"
+)
+
+// content is the part of an entry covered by its hash. Field order is fixed by the
+// struct, which keeps the encoding stable.
+type content struct {
+	PrevHash       string  `json:"prev_hash"`
+	Action         string  `json:"action"`
+	ActionCategory string  `json:"action_category"`
+	ActorID        *int    `json:"actor_id"`
+	TargetType     *string `json:"target_type"`
+	TargetID       *int    `json:"target_id"`
+	TargetName     *string `json:"target_name"`
+	Details        *string `json:"details"`
+	OldValue       *string `json:"old_value"`
+	NewValue       *string `json:"new_value"`
+	IPAddress      *string `json:"ip_address"`
+	UserAgent      *string `json:"user_agent"`
+	Severity       string  `json:"severity"`
+	CreatedAt      string  `json:"created_at"`
+	// Added after the chain was introduced; omitted when empty so entries written
+	// before then still hash the same
+	RequestID *string `json:"request_id,omitempty"`
+}
+
+// Hash returns the chain hash of an entry that follows prevHash. The entry's own ID,
+// hash and computed fields are not covered.
+func Hash(prevHash string, e models.AuditLog) string {
+	data, _ := json.Marshal(content{
+		PrevHash:       prevHash,
+		Action:         e.Action,
+		ActionCategory: e.ActionCategory,
+		ActorID:        e.ActorID,
+		TargetType:     e.TargetType,
+		TargetID:       e.TargetID,
+		TargetName:     e.TargetName,
+		Details:        e.Details,
+		OldValue:       e.OldValue,
+		NewValue:       e.NewValue,
+		IPAddress:      e.IPAddress,
+		UserAgent:      e.UserAgent,
+		Severity:       e.Severity,
+		CreatedAt:      e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		RequestID:      e.RequestID,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckpointPayload is the data a checkpoint signature covers
+func CheckpointPayload(lastLogID int, hash string) []byte {
+	return []byte(fmt.Sprintf("%d|%s", lastLogID, hash))
+}
+
+// SignCheckpoint returns the hex-encoded HMAC-SHA256 of a checkpoint
+func SignCheckpoint(key []byte, lastLogID int, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(CheckpointPayload(lastLogID, hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCheckpoint checks a checkpoint's signature in constant time
+func VerifyCheckpoint(key []byte, cp models.AuditCheckpoint) bool {
+	expected, err := hex.DecodeString(cp.Signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(CheckpointPayload(cp.LastLogID, cp.Hash))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// Break describes the first place the chain does not hold
+type Break struct {
+	ID     int    `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Report is the outcome of walking the chain
+type Report struct {
+	Valid bool `json:"valid"`
+	// Verified counts entries whose hash matched their content and link
+	Verified int `json:"verified"`
+	// Unchained counts entries written before hashing was introduced
+	Unchained int `json:"unchained"`
+	// Archived counts entries moved to archive files; only their hashes remain, so
+	// their content is checked against the archive rather than here
+	Archived int `json:"archived"`
+	// Gaps counts places where entries are missing from the input, such as a filtered
+	// export, so the link across them could not be checked
+	Gaps int `json:"gaps"`
+	// AnchorHash is the previous hash of the first chained entry when the input starts
+	// partway through the chain
+	AnchorHash  string `json:"anchor_hash,omitempty"`
+	FirstID     int    `json:"first_id,omitempty"`
+	LastID      int    `json:"last_id,omitempty"`
+	HeadHash    string `json:"head_hash,omitempty"`
+	Checkpoints int    `json:"checkpoints_verified"`
+	FirstBroken *Break `json:"first_broken,omitempty"`
+}
+
+// Verifier walks entries in ID order. With Contiguous set every entry must link to the
+// one before it, which is the case when walking the database; without it a jump in
+// IDs is counted as a gap rather than a break.
+type Verifier struct {
+	Contiguous bool
+
+	report   Report
+	started  bool
+	prevID   int
+	prevHash string
+	hashes   map[int]string
+	// lastUnchained is the ID of the latest entry written before hashing
+	lastUnchained int
+}
+
+// NewVerifier returns a verifier that remembers entry hashes so checkpoints can be
+// checked against them afterwards
+func NewVerifier(contiguous bool) *Verifier {
+	return &Verifier{Contiguous: contiguous, hashes: map[int]string{}}
+}
+
+// Add checks the next entry. It returns false once the chain is broken; later entries
+// are not checked.
+func (v *Verifier) Add(e models.AuditLog) bool {
+	if v.report.FirstBroken != nil {
+		return false
+	}
+	if v.report.FirstID == 0 {
+		v.report.FirstID = e.ID
+	}
+	defer func() { v.report.LastID = e.ID }()
+
+	if e.Hash == nil || *e.Hash == "" {
+		if v.started {
+			return v.fail(e.ID, "entry has no hash")
+		}
+		v.report.Unchained++
+		v.lastUnchained = e.ID
+		return true
+	}
+
+	prevHash := ""
+	if e.PrevHash != nil {
+		prevHash = *e.PrevHash
+	}
+	if Hash(prevHash, e) != *e.Hash {
+		return v.fail(e.ID, "entry content does not match its hash")
+	}
+
+	switch {
+	case !v.started && !v.anchored(e.ID, prevHash):
+		return v.fail(e.ID, "chain does not start at its first entry; earlier entries were removed")
+	case !v.started:
+		v.report.AnchorHash = prevHash
+	case !v.Contiguous && e.ID != v.prevID+1:
+		v.report.Gaps++
+	case prevHash != v.prevHash:
+		return v.fail(e.ID, fmt.Sprintf("entry does not link to entry %d; an entry in between was removed or altered", v.prevID))
+	}
+
+	v.started = true
+	v.prevID = e.ID
+	v.prevHash = *e.Hash
+	v.hashes[e.ID] = *e.Hash
+	v.report.Verified++
+	v.report.HeadHash = *e.Hash
+	return true
+}
+
+// AddArchived continues the chain across an entry that was moved to an archive file,
+// of which only the ID and hashes remain. Its link to the entry before it is checked;
+// its content can only be checked against the archive. It returns false once the chain
+// is broken.
+func (v *Verifier) AddArchived(id int, prevHash string, hash string) bool {
+	if v.report.FirstBroken != nil {
+		return false
+	}
+	if v.report.FirstID == 0 {
+		v.report.FirstID = id
+	}
+	v.report.LastID = id
+
+	switch {
+	case !v.started && !v.anchored(id, prevHash):
+		return v.fail(id, "chain does not start at its first entry; earlier entries were removed")
+	case !v.started:
+		v.report.AnchorHash = prevHash
+	case !v.Contiguous && id != v.prevID+1:
+		v.report.Gaps++
+	case prevHash != v.prevHash:
+		return v.fail(id, fmt.Sprintf("archived entry does not link to entry %d; an entry in between was removed or altered", v.prevID))
+	}
+
+	v.started = true
+	v.prevID = id
+	v.prevHash = hash
+	v.hashes[id] = hash
+	v.report.Archived++
+	v.report.HeadHash = hash
+	return true
+}
+
+// anchored reports whether the first chained entry may link to prevHash. A walk of the
+// database must start at the genesis entry, whose previous hash is empty, or right
+// after the entries written before hashing; otherwise the oldest entries were deleted.
+// Input that starts partway through the chain is anchored anywhere.
+func (v *Verifier) anchored(id int, prevHash string) bool {
+	return !v.Contiguous || prevHash == "" || (v.lastUnchained != 0 && v.lastUnchained == id-1)
+}
+
+// AddCheckpoint checks a signed checkpoint against the entries seen. Walking the
+// database every checkpoint must pin an entry that was walked; otherwise checkpoints
+// for entries before the input starts are skipped.
+func (v *Verifier) AddCheckpoint(key []byte, cp models.AuditCheckpoint) bool {
+	if v.report.FirstBroken != nil {
+		return false
+	}
+	if !VerifyCheckpoint(key, cp) {
+		return v.fail(cp.LastLogID, fmt.Sprintf("checkpoint %d has an invalid signature", cp.ID))
+	}
+	if !v.Contiguous && cp.LastLogID < v.report.FirstID {
+		return true
+	}
+
+	hash, ok := v.hashes[cp.LastLogID]
+	switch {
+	case !ok && cp.LastLogID > v.report.LastID && v.Contiguous:
+		return v.fail(cp.LastLogID, fmt.Sprintf("checkpoint %d covers entries that no longer exist", cp.ID))
+	case !ok && v.Contiguous:
+		return v.fail(cp.LastLogID, fmt.Sprintf("entry pinned by checkpoint %d is missing", cp.ID))
+	case !ok:
+		return true
+	case hash != cp.Hash:
+		return v.fail(cp.LastLogID, fmt.Sprintf("entry hash differs from checkpoint %d; the chain was rewritten", cp.ID))
+	}
+	v.report.Checkpoints++
+	return true
+}
+
+// Report returns the outcome so far
+func (v *Verifier) Report() Report {
+	r := v.report
+	r.Valid = r.FirstBroken == nil
+	return r
+}
+
+func (v *Verifier) fail(id int, reason string) bool {
+	v.report.FirstBroken = &Break{ID: id, Reason: reason}
+	return false
+}
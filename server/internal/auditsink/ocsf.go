@@ -0,0 +1,296 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/models"
+)
+
+// OCSFVersion is the schema version the events are written against
+const OCSFVersion = "1.1.0"
+
+// OCSF classes gatekeepr events fall into
+const (
+	ocsfCategoryIAM       = 3
+	ocsfClassAccount      = 3001
+	ocsfClassAuth         = 3002
+	ocsfClassEntity       = 3004
+	ocsfClassUserAccess   = 3005
+	ocsfCategoryAppAct    = 6
+	ocsfClassAPIActivity  = 6003
+	ocsfActivityOther     = 99
+	ocsfSeverityInfo      = 1
+	ocsfSeverityMedium    = 3
+	ocsfSeverityCritical  = 5
+	ocsfStatusSuccess     = 1
+	ocsfStatusFailure     = 2
+	ocsfProductName       = "gatekeepr"
+	ocsfProductVendorName = "gatekeepr"
+)
+
+// OCSFConfig configures the OCSF sink. Token, when set, is sent as a bearer token;
+// Headers are added to every request, for collectors that want their own auth header.
+type OCSFConfig struct {
+	URL     string
+	Token   string
+	Headers map[string]string
+}
+
+// OCSFSink posts batches of entries as a JSON array of OCSF events to an HTTP
+// collector
+type OCSFSink struct {
+	config OCSFConfig
+	client *http.Client
+}
+
+// NewOCSFSink builds an OCSF sink for a collector URL
+func NewOCSFSink(config OCSFConfig) (*OCSFSink, error) {
+	if !strings.HasPrefix(config.URL, "http://") && !strings.HasPrefix(config.URL, "https://") {
+		return nil, errors.New("OCSF collector URL must be an http or https URL")
+	}
+	return &OCSFSink{config: config, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (s *OCSFSink) Name() string { return "ocsf" }
+
+func (s *OCSFSink) Send(ctx context.Context, entries []models.AuditLog) error {
+	events := make([]OCSFEvent, len(entries))
+	for i, e := range entries {
+		events[i] = ToOCSF(e)
+	}
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gatekeepr-audit")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OCSF collector returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// OCSFEvent is an audit entry in the Open Cybersecurity Schema Framework
+type OCSFEvent struct {
+	ActivityID   int                    `json:"activity_id"`
+	ActivityName string                 `json:"activity_name"`
+	CategoryUID  int                    `json:"category_uid"`
+	ClassUID     int                    `json:"class_uid"`
+	TypeUID      int                    `json:"type_uid"`
+	SeverityID   int                    `json:"severity_id"`
+	Severity     string                 `json:"severity"`
+	StatusID     int                    `json:"status_id"`
+	Time         int64                  `json:"time"`
+	Message      string                 `json:"message,omitempty"`
+	Metadata     ocsfMetadata           `json:"metadata"`
+	Actor        *ocsfActor             `json:"actor,omitempty"`
+	SrcEndpoint  *ocsfEndpoint          `json:"src_endpoint,omitempty"`
+	HTTPRequest  *ocsfHTTPRequest       `json:"http_request,omitempty"`
+	API          ocsfAPI                `json:"api"`
+	Resources    []ocsfResource         `json:"resources,omitempty"`
+	Unmapped     map[string]interface{} `json:"unmapped,omitempty"`
+}
+
+type ocsfMetadata struct {
+	UID            string      `json:"uid"`
+	Version        string      `json:"version"`
+	Product        ocsfProduct `json:"product"`
+	LoggedAt       int64       `json:"logged_time"`
+	CorrelationUID string      `json:"correlation_uid,omitempty"`
+}
+
+type ocsfProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+}
+
+type ocsfActor struct {
+	User ocsfUser `json:"user"`
+}
+
+type ocsfUser struct {
+	UID       string `json:"uid"`
+	EmailAddr string `json:"email_addr,omitempty"`
+}
+
+type ocsfEndpoint struct {
+	IP string `json:"ip"`
+}
+
+type ocsfHTTPRequest struct {
+	UserAgent string `json:"user_agent"`
+}
+
+type ocsfAPI struct {
+	Operation string `json:"operation"`
+}
+
+type ocsfResource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// ocsfActivities maps the last part of an action onto the activities of each class.
+// Anything not listed is activity 99, Other.
+var ocsfActivities = map[int]map[string]struct {
+	id   int
+	name string
+}{
+	ocsfClassAuth: {
+		"login":  {1, "Logon"},
+		"logout": {2, "Logoff"},
+	},
+	ocsfClassUserAccess: {
+		"approve":      {1, "Assign Privileges"},
+		"auto_approve": {1, "Assign Privileges"},
+		"direct":       {1, "Assign Privileges"},
+		"break_glass":  {1, "Assign Privileges"},
+		"revoke":       {2, "Revoke Privileges"},
+		"expire":       {2, "Revoke Privileges"},
+	},
+	ocsfClassEntity: {
+		"create": {1, "Create"},
+		"update": {3, "Update"},
+		"delete": {4, "Delete"},
+	},
+	ocsfClassAccount: {
+		"create": {1, "Create"},
+		"delete": {6, "Delete"},
+	},
+	ocsfClassAPIActivity: {
+		"create": {1, "Create"},
+		"verify": {2, "Read"},
+		"export": {2, "Read"},
+		"update": {3, "Update"},
+		"delete": {4, "Delete"},
+	},
+}
+
+// ToOCSF converts an audit entry. Authentication events become Authentication, access
+// grants and revocations User Access Management, user changes Account Change,
+// administration of roles, groups, tools and policies Entity Management, and the rest
+// API Activity, with authorization denials marked as failures. Fields OCSF has no place
+// for are kept under unmapped.
+func ToOCSF(e models.AuditLog) OCSFEvent {
+	categoryUID, classUID := ocsfCategoryIAM, ocsfClassEntity
+	switch e.ActionCategory {
+	case "auth":
+		classUID = ocsfClassAuth
+	case "access", "bulk":
+		classUID = ocsfClassUserAccess
+	case "user":
+		classUID = ocsfClassAccount
+	case "audit", "webhook", "notification", "usage", "authz":
+		categoryUID, classUID = ocsfCategoryAppAct, ocsfClassAPIActivity
+	}
+	statusID := ocsfStatusSuccess
+	if e.ActionCategory == "authz" {
+		statusID = ocsfStatusFailure
+	}
+
+	verb := e.Action[strings.LastIndex(e.Action, ".")+1:]
+	activity, ok := ocsfActivities[classUID][verb]
+	if !ok {
+		activity.id, activity.name = ocsfActivityOther, "Other"
+	}
+
+	severityID, severity := ocsfSeverityInfo, "Informational"
+	switch e.Severity {
+	case "warning":
+		severityID, severity = ocsfSeverityMedium, "Medium"
+	case "critical":
+		severityID, severity = ocsfSeverityCritical, "Critical"
+	}
+
+	ev := OCSFEvent{
+		ActivityID:   activity.id,
+		ActivityName: activity.name,
+		CategoryUID:  categoryUID,
+		ClassUID:     classUID,
+		TypeUID:      classUID*100 + activity.id,
+		SeverityID:   severityID,
+		Severity:     severity,
+		StatusID:     statusID,
+		Time:         e.CreatedAt.UnixMilli(),
+		Metadata: ocsfMetadata{
+			UID:      strconv.Itoa(e.ID),
+			Version:  OCSFVersion,
+			Product:  ocsfProduct{Name: ocsfProductName, VendorName: ocsfProductVendorName},
+			LoggedAt: e.CreatedAt.UnixMilli(),
+		},
+		API:      ocsfAPI{Operation: e.Action},
+		Unmapped: map[string]interface{}{"action_category": e.ActionCategory},
+	}
+	if e.Details != nil {
+		ev.Message = *e.Details
+	}
+	if e.RequestID != nil {
+		ev.Metadata.CorrelationUID = *e.RequestID
+	}
+	if e.ActorID != nil && *e.ActorID != 0 {
+		ev.Actor = &ocsfActor{User: ocsfUser{UID: strconv.Itoa(*e.ActorID), EmailAddr: e.ActorEmail}}
+	}
+	if e.IPAddress != nil && *e.IPAddress != "" {
+		ev.SrcEndpoint = &ocsfEndpoint{IP: hostOnly(*e.IPAddress)}
+	}
+	if e.UserAgent != nil && *e.UserAgent != "" {
+		ev.HTTPRequest = &ocsfHTTPRequest{UserAgent: *e.UserAgent}
+	}
+	if e.TargetType != nil && *e.TargetType != "" {
+		res := ocsfResource{Type: *e.TargetType}
+		if e.TargetID != nil && *e.TargetID != 0 {
+			res.UID = strconv.Itoa(*e.TargetID)
+		}
+		if e.TargetName != nil {
+			res.Name = *e.TargetName
+		}
+		ev.Resources = []ocsfResource{res}
+	}
+	if e.OldValue != nil {
+		ev.Unmapped["old_value"] = json.RawMessage(*e.OldValue)
+	}
+	if e.NewValue != nil {
+		ev.Unmapped["new_value"] = json.RawMessage(*e.NewValue)
+	}
+	if e.Hash != nil {
+		ev.Unmapped["hash"] = *e.Hash
+	}
+	return ev
+}
+
+// hostOnly strips the port from a recorded remote address
+func hostOnly(addr string) string {
+	if i := strings.LastIndex(addr, ":"); i > 0 && !strings.HasSuffix(addr, "]") {
+		return strings.Trim(addr[:i], "[]")
+	}
+	return addr
+}
@@ -0,0 +1,207 @@
+// Package auditsink forwards audit log entries to external collectors such as a
+// syslog server or a SIEM's HTTP intake. Each sink is fed by a Forwarder that buffers
+// entries in memory, retries failed sends and hands entries it cannot deliver to a
+// dead-letter store so they can be replayed later.
+package auditsink
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gatekeepr/internal/models"
+)
+
+// Sink delivers audit entries to one external collector. Send is called from a single
+// goroutine per sink; a batch that fails as a whole is retried, so collectors may see
+// an entry more than once and should deduplicate on its ID.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, entries []models.AuditLog) error
+}
+
+// DeadLetterFunc stores entries a forwarder gave up on, with the reason
+type DeadLetterFunc func(sink string, entries []models.AuditLog, attempts int, reason string)
+
+// ForwarderConfig tunes buffering and retries. Zero values take the defaults.
+type ForwarderConfig struct {
+	// BufferSize is how many entries wait in memory for the sink
+	BufferSize int
+	// BatchSize is the most entries handed to the sink in one Send
+	BatchSize int
+	// MaxAttempts is how often a batch is sent before it is dead-lettered
+	MaxAttempts int
+	// BlockTimeout is how long a writer waits for room in a full buffer before the
+	// entry is dead-lettered instead, so a slow sink never stalls the application
+	BlockTimeout time.Duration
+	// SendTimeout bounds a single Send
+	SendTimeout time.Duration
+}
+
+func (c ForwarderConfig) withDefaults() ForwarderConfig {
+	if c.BufferSize <= 0 {
+		c.BufferSize = 10000
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	if c.BlockTimeout <= 0 {
+		c.BlockTimeout = 100 * time.Millisecond
+	}
+	if c.SendTimeout <= 0 {
+		c.SendTimeout = 10 * time.Second
+	}
+	return c
+}
+
+// Status reports how a forwarder is keeping up
+type Status struct {
+	Name         string     `json:"name"`
+	Queued       int        `json:"queued"`
+	Capacity     int        `json:"capacity"`
+	Sent         int64      `json:"sent"`
+	DeadLettered int64      `json:"dead_lettered"`
+	LastSentAt   *time.Time `json:"last_sent_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastErrorAt  *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Forwarder queues entries for one sink and delivers them in the background
+type Forwarder struct {
+	sink       Sink
+	config     ForwarderConfig
+	queue      chan models.AuditLog
+	deadLetter DeadLetterFunc
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewForwarder builds a forwarder for a sink. Call Start to begin delivering.
+func NewForwarder(sink Sink, config ForwarderConfig, deadLetter DeadLetterFunc) *Forwarder {
+	config = config.withDefaults()
+	return &Forwarder{
+		sink:       sink,
+		config:     config,
+		queue:      make(chan models.AuditLog, config.BufferSize),
+		deadLetter: deadLetter,
+		status:     Status{Name: sink.Name(), Capacity: config.BufferSize},
+	}
+}
+
+// Name returns the name of the sink
+func (f *Forwarder) Name() string { return f.sink.Name() }
+
+// Sink returns the sink the forwarder delivers to
+func (f *Forwarder) Sink() Sink { return f.sink }
+
+// Start delivers queued entries until ctx is cancelled
+func (f *Forwarder) Start(ctx context.Context) {
+	go f.run(ctx)
+}
+
+// Enqueue queues an entry for delivery. When the buffer is full it waits briefly for
+// room and then dead-letters the entry rather than blocking the caller.
+func (f *Forwarder) Enqueue(e models.AuditLog) {
+	select {
+	case f.queue <- e:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(f.config.BlockTimeout)
+	defer timer.Stop()
+	select {
+	case f.queue <- e:
+	case <-timer.C:
+		f.giveUp([]models.AuditLog{e}, 0, "buffer full")
+	}
+}
+
+// Status returns the forwarder's counters and queue depth
+func (f *Forwarder) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.status
+	s.Queued = len(f.queue)
+	return s
+}
+
+func (f *Forwarder) run(ctx context.Context) {
+	for {
+		var first models.AuditLog
+		select {
+		case <-ctx.Done():
+			return
+		case first = <-f.queue:
+		}
+
+		batch := []models.AuditLog{first}
+	fill:
+		for len(batch) < f.config.BatchSize {
+			select {
+			case e := <-f.queue:
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+
+		f.deliver(ctx, batch)
+	}
+}
+
+// deliver sends a batch, retrying with exponential backoff, and dead-letters it once
+// the attempts run out
+func (f *Forwarder) deliver(ctx context.Context, batch []models.AuditLog) {
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, f.config.SendTimeout)
+		err := f.sink.Send(sendCtx, batch)
+		cancel()
+
+		now := time.Now().UTC()
+		if err == nil {
+			f.mu.Lock()
+			f.status.Sent += int64(len(batch))
+			f.status.LastSentAt = &now
+			f.mu.Unlock()
+			return
+		}
+
+		f.mu.Lock()
+		f.status.LastError = err.Error()
+		f.status.LastErrorAt = &now
+		f.mu.Unlock()
+
+		if attempt >= f.config.MaxAttempts || ctx.Err() != nil {
+			f.giveUp(batch, attempt, err.Error())
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			f.giveUp(batch, attempt, ctx.Err().Error())
+			return
+		case <-time.After(wait):
+		}
+		if wait < 30*time.Second {
+			wait *= 2
+		}
+	}
+}
+
+func (f *Forwarder) giveUp(entries []models.AuditLog, attempts int, reason string) {
+	f.mu.Lock()
+	f.status.DeadLettered += int64(len(entries))
+	f.mu.Unlock()
+
+	log.Printf("Audit sink %s dead-lettered %d entries after %d attempts: %s", f.sink.Name(), len(entries), attempts, reason)
+	if f.deadLetter != nil {
+		f.deadLetter(f.sink.Name(), entries, attempts, reason)
+	}
+}
@@ -0,0 +1,224 @@
+package auditsink
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gatekeepr/internal/models"
+)
+
+// syslogEnterpriseID qualifies the structured data element. 32473 is the private
+// enterprise number IANA reserves for documentation and examples.
+const syslogEnterpriseID = "32473"
+
+// SyslogConfig configures the syslog sink. URL is udp://, tcp:// or tls:// followed by
+// host:port. Facility defaults to 13, log audit.
+type SyslogConfig struct {
+	URL      string
+	Facility int
+	AppName  string
+	Hostname string
+	// CAFile verifies the server of a tls:// sink against a private CA
+	CAFile string
+	// CertFile and KeyFile present a client certificate to a tls:// sink
+	CertFile string
+	KeyFile  string
+}
+
+// SyslogSink sends each entry as an RFC 5424 message. UDP sends one datagram per
+// message; TCP and TLS frame messages with octet counting (RFC 6587, RFC 5425) over a
+// connection that is re-established after any write error.
+type SyslogSink struct {
+	config    SyslogConfig
+	network   string
+	address   string
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink builds a syslog sink. It does not connect until the first send.
+func NewSyslogSink(config SyslogConfig) (*SyslogSink, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || u.Host == "" {
+		return nil, errors.New("syslog URL must look like udp://host:514, tcp://host:514 or tls://host:6514")
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return nil, fmt.Errorf("syslog URL needs a port: %w", err)
+	}
+	if config.Facility == 0 {
+		config.Facility = 13
+	}
+	if config.Facility < 0 || config.Facility > 23 {
+		return nil, errors.New("syslog facility must be between 0 and 23")
+	}
+	if config.AppName == "" {
+		config.AppName = "gatekeepr"
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+
+	s := &SyslogSink{config: config, address: u.Host}
+	switch u.Scheme {
+	case "udp", "tcp":
+		s.network = u.Scheme
+	case "tls":
+		s.network = "tcp"
+		s.tlsConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+		if config.CAFile != "" {
+			pem, err := os.ReadFile(config.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read syslog CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New("syslog CA file holds no certificates")
+			}
+			s.tlsConfig.RootCAs = pool
+		}
+		if config.CertFile != "" || config.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load syslog client certificate: %w", err)
+			}
+			s.tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported syslog transport %q; use udp, tcp or tls", u.Scheme)
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) Name() string { return "syslog" }
+
+func (s *SyslogSink) Send(ctx context.Context, entries []models.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, e := range entries {
+		msg := s.format(e)
+		if s.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SyslogSink) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if s.tlsConfig != nil {
+		conn, err := (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}).DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		return nil
+	}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// format renders an RFC 5424 message. The message ID is the action, the structured
+// data carries the fields SIEMs index on and the message is the entry as JSON.
+func (s *SyslogSink) format(e models.AuditLog) string {
+	pri := s.config.Facility*8 + syslogSeverity(e.Severity)
+
+	params := []string{sdParam("id", strconv.Itoa(e.ID)), sdParam("category", e.ActionCategory), sdParam("severity", e.Severity)}
+	if e.ActorID != nil {
+		params = append(params, sdParam("actorId", strconv.Itoa(*e.ActorID)))
+	}
+	if e.ActorEmail != "" {
+		params = append(params, sdParam("actor", e.ActorEmail))
+	}
+	if e.TargetType != nil {
+		params = append(params, sdParam("targetType", *e.TargetType))
+	}
+	if e.TargetID != nil {
+		params = append(params, sdParam("targetId", strconv.Itoa(*e.TargetID)))
+	}
+	if e.IPAddress != nil {
+		params = append(params, sdParam("src", *e.IPAddress))
+	}
+	if e.RequestID != nil {
+		params = append(params, sdParam("requestId", *e.RequestID))
+	}
+	if e.Hash != nil {
+		params = append(params, sdParam("hash", *e.Hash))
+	}
+
+	body, _ := json.Marshal(e)
+	return fmt.Sprintf("<%d>1 %s %s %s - %s [gatekeepr@%s %s] \ufeff%s",
+		pri,
+		e.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(s.config.Hostname, 255),
+		headerField(s.config.AppName, 48),
+		headerField(e.Action, 32),
+		syslogEnterpriseID,
+		strings.Join(params, " "),
+		body)
+}
+
+// syslogSeverity maps audit severities onto RFC 5424 severities: critical is 2,
+// warning is 4 and everything else informational, 6
+func syslogSeverity(severity string) int {
+	switch severity {
+	case "critical":
+		return 2
+	case "warning":
+		return 4
+	default:
+		return 6
+	}
+}
+
+// headerField makes a value safe for a header field: printable ASCII without spaces,
+// cut to the field's maximum length, or the nil value "-" when empty
+func headerField(s string, max int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if s[i] > 32 && s[i] < 127 {
+			b = append(b, s[i])
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// sdParam renders a structured data parameter, escaping the characters RFC 5424
+// reserves in parameter values
+func sdParam(name string, value string) string {
+	return name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "]", `\]`).Replace(value) + `"`
+}
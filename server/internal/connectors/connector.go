@@ -0,0 +1,85 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Grant is the access a connector creates or removes in a target system
+type Grant struct {
+	RequestID   int        `json:"request_id"`
+	UserID      int        `json:"user_id"`
+	UserEmail   string     `json:"user_email"`
+	ToolID      int        `json:"tool_id"`
+	ToolName    string     `json:"tool_name"`
+	AccessLevel string     `json:"access_level"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Account is an account as reported by a target system
+type Account struct {
+	UserEmail   string `json:"user_email"`
+	AccessLevel string `json:"access_level"`
+	ExternalID  string `json:"external_id,omitempty"`
+}
+
+// Connector pushes grants to a target system. Provision and Deprovision must be
+// idempotent since failed jobs are retried.
+type Connector interface {
+	Provision(ctx context.Context, grant Grant) error
+	Deprovision(ctx context.Context, grant Grant) error
+	ListAccounts(ctx context.Context) ([]Account, error)
+}
+
+// PermanentError marks a failure that retrying will not fix, such as a rejected payload
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether err should not be retried
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// Types lists the connector types that can be configured
+var Types = []string{"http"}
+
+// New builds a connector of the given type from its stored configuration
+func New(connectorType string, config json.RawMessage) (Connector, error) {
+	switch connectorType {
+	case "http":
+		return NewHTTPConnector(config)
+	}
+	return nil, fmt.Errorf("unknown connector type %q", connectorType)
+}
+
+// RedactConfig hides secrets in a connector configuration before it is returned by the API
+func RedactConfig(connectorType string, config json.RawMessage) json.RawMessage {
+	switch connectorType {
+	case "http":
+		return redactHTTPConfig(config)
+	}
+	return config
+}
+
+// RestoreSecrets puts back secrets a client echoed in redacted form when updating a
+// connector, so configs read from the API can be edited and saved
+func RestoreSecrets(connectorType string, config json.RawMessage, previous json.RawMessage) json.RawMessage {
+	switch connectorType {
+	case "http":
+		return restoreHTTPSecrets(config, previous)
+	}
+	return config
+}
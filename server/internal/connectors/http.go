@@ -0,0 +1,148 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const redactedValue = "********"
+
+// HTTPConfig configures the generic outbound HTTP connector. The target system exposes
+// POST {base_url}/provision, POST {base_url}/deprovision and GET {base_url}/accounts.
+type HTTPConfig struct {
+	BaseURL        string            `json:"base_url"`
+	AuthToken      string            `json:"auth_token,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+}
+
+// HTTPConnector calls a target system's HTTP API with grants encoded as JSON
+type HTTPConnector struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPConnector builds an HTTP connector from its JSON configuration
+func NewHTTPConnector(raw json.RawMessage) (*HTTPConnector, error) {
+	var config HTTPConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("invalid http connector config: %w", err)
+	}
+	if !strings.HasPrefix(config.BaseURL, "http://") && !strings.HasPrefix(config.BaseURL, "https://") {
+		return nil, errors.New("base_url must be an http or https URL")
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.TimeoutSeconds <= 0 {
+		config.TimeoutSeconds = 10
+	}
+
+	return &HTTPConnector{
+		config: config,
+		client: &http.Client{Timeout: time.Duration(config.TimeoutSeconds) * time.Second},
+	}, nil
+}
+
+func (c *HTTPConnector) Provision(ctx context.Context, grant Grant) error {
+	return c.do(ctx, http.MethodPost, "/provision", grant, nil)
+}
+
+func (c *HTTPConnector) Deprovision(ctx context.Context, grant Grant) error {
+	return c.do(ctx, http.MethodPost, "/deprovision", grant, nil)
+}
+
+func (c *HTTPConnector) ListAccounts(ctx context.Context) ([]Account, error) {
+	var accounts []Account
+	if err := c.do(ctx, http.MethodGet, "/accounts", nil, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// do sends a request and decodes the response into out when it is not nil. Client
+// errors (4xx) are permanent; network failures and server errors can be retried.
+func (c *HTTPConnector) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return &PermanentError{err}
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path, reader)
+	if err != nil {
+		return &PermanentError{err}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if c.config.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.AuthToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return &PermanentError{err}
+		}
+		return err
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+func redactHTTPConfig(raw json.RawMessage) json.RawMessage {
+	var config HTTPConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return raw
+	}
+	if config.AuthToken != "" {
+		config.AuthToken = redactedValue
+	}
+	for name := range config.Headers {
+		config.Headers[name] = redactedValue
+	}
+	redacted, _ := json.Marshal(config)
+	return redacted
+}
+
+func restoreHTTPSecrets(raw json.RawMessage, previous json.RawMessage) json.RawMessage {
+	var config, old HTTPConfig
+	if json.Unmarshal(raw, &config) != nil || json.Unmarshal(previous, &old) != nil {
+		return raw
+	}
+	if config.AuthToken == redactedValue {
+		config.AuthToken = old.AuthToken
+	}
+	for name, value := range config.Headers {
+		if value == redactedValue {
+			config.Headers[name] = old.Headers[name]
+		}
+	}
+	restored, _ := json.Marshal(config)
+	return restored
+}
@@ -0,0 +1,30 @@
+-- Full-text index over audit entries. It lives outside schema.sql because FTS5 is only
+-- compiled in when the server is built with -tags sqlite_fts5.
+CREATE VIRTUAL TABLE IF NOT EXISTS audit_logs_fts USING fts5(
+    action,
+    target_name,
+    details,
+    old_value,
+    new_value,
+    content = 'audit_logs',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS audit_logs_fts_insert AFTER INSERT ON audit_logs BEGIN
+    INSERT INTO audit_logs_fts (rowid, action, target_name, details, old_value, new_value)
+    VALUES (new.id, new.action, new.target_name, new.details, new.old_value, new.new_value);
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_logs_fts_delete AFTER DELETE ON audit_logs BEGIN
+    INSERT INTO audit_logs_fts (audit_logs_fts, rowid, action, target_name, details, old_value, new_value)
+    VALUES ('delete', old.id, old.action, old.target_name, old.details, old.old_value, old.new_value);
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_logs_fts_update AFTER UPDATE ON audit_logs BEGIN
+    INSERT INTO audit_logs_fts (audit_logs_fts, rowid, action, target_name, details, old_value, new_value)
+    VALUES ('delete', old.id, old.action, old.target_name, old.details, old.old_value, old.new_value);
+    INSERT INTO audit_logs_fts (rowid, action, target_name, details, old_value, new_value)
+    VALUES (new.id, new.action, new.target_name, new.details, new.old_value, new.new_value);
+END;
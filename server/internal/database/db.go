@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	_ "embed"
 	"fmt"
+	"log"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -11,8 +12,15 @@ import (
 //go:embed schema.sql
 var schema string
 
+//go:embed audit_search.sql
+var auditSearchSchema string
+
 var DB *sql.DB
 
+// AuditFullText reports whether audit search can use the FTS5 index. Builds without
+// the sqlite_fts5 tag fall back to substring matching.
+var AuditFullText bool
+
 func InitDB(dataSourceName string) error {
 	var err error
 	DB, err = sql.Open("sqlite3", dataSourceName)
@@ -33,6 +41,14 @@ func InitDB(dataSourceName string) error {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
+	if err := migrateColumns(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if err := initAuditSearch(); err != nil {
+		return fmt.Errorf("failed to set up audit search: %w", err)
+	}
+
 	// Seed default data
 	if err := seedDefaultData(); err != nil {
 		return fmt.Errorf("failed to seed default data: %w", err)
@@ -41,6 +57,99 @@ func InitDB(dataSourceName string) error {
 	return nil
 }
 
+// columnMigrations lists columns added to tables after they were first released.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so every column
+// added to an existing table in schema.sql must also be listed here.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"users", "department", "TEXT"},
+	{"users", "manager_id", "INTEGER REFERENCES users(id)"},
+	{"users", "last_login_at", "DATETIME"},
+	{"tools", "high_risk", "BOOLEAN DEFAULT FALSE"},
+	{"access_requests", "auto_approval_rule_id", "INTEGER REFERENCES auto_approval_rules(id)"},
+	{"access_requests", "incident_reference", "TEXT"},
+	{"access_requests", "parent_request_id", "INTEGER REFERENCES access_requests(id)"},
+	{"access_requests", "reminded_at", "DATETIME"},
+	{"access_requests", "escalated_at", "DATETIME"},
+	{"access_requests", "escalation_group_id", "INTEGER REFERENCES user_groups(id)"},
+	{"access_requests", "dormant_notified_at", "DATETIME"},
+	{"access_requests", "dormant_flagged_at", "DATETIME"},
+	{"access_requests", "bundle_request_id", "INTEGER REFERENCES access_bundle_requests(id)"},
+	{"access_requests", "provisioning_status", "TEXT"},
+	{"access_requests", "expiry_notified_at", "DATETIME"},
+	{"connectors", "auto_remediate", "BOOLEAN DEFAULT FALSE"},
+	{"action_links", "failed_attempts", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_logs", "severity", "TEXT NOT NULL DEFAULT 'info'"},
+	{"audit_logs", "prev_hash", "TEXT"},
+	{"audit_logs", "hash", "TEXT"},
+	{"audit_logs", "request_id", "TEXT"},
+}
+
+// columnIndexes index migrated columns. They cannot live in schema.sql, which runs
+// before the columns exist on databases created by an earlier release.
+var columnIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs(request_id)",
+}
+
+func migrateColumns() error {
+	for _, m := range columnMigrations {
+		var exists bool
+		err := DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM pragma_table_info(?) WHERE name = ?)`,
+			m.table, m.column).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", m.table, err)
+		}
+		if exists {
+			continue
+		}
+		if _, err := DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	for _, index := range columnIndexes {
+		if _, err := DB.Exec(index); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+	return nil
+}
+
+// initAuditSearch sets up the full-text index of audit entries. The index is rebuilt
+// whenever its triggers were missing, since entries written meanwhile went unindexed.
+func initAuditSearch() error {
+	var indexed bool
+	DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'audit_logs_fts_insert')`).Scan(&indexed)
+
+	var fts5 bool
+	if err := DB.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return err
+	}
+	if !fts5 {
+		// Triggers left behind by a build with FTS5 would fail every audit write
+		for _, trigger := range []string{"audit_logs_fts_insert", "audit_logs_fts_delete", "audit_logs_fts_update"} {
+			if _, err := DB.Exec("DROP TRIGGER IF EXISTS " + trigger); err != nil {
+				return err
+			}
+		}
+		log.Printf("SQLite lacks FTS5, so audit search falls back to substring matching; build with -tags sqlite_fts5 to enable it")
+		return nil
+	}
+
+	if _, err := DB.Exec(auditSearchSchema); err != nil {
+		return err
+	}
+	if !indexed {
+		if _, err := DB.Exec("INSERT INTO audit_logs_fts (audit_logs_fts) VALUES ('rebuild')"); err != nil {
+			return err
+		}
+	}
+	AuditFullText = true
+	return nil
+}
+
 func seedDefaultData() error {
 	// Seed default roles
 	roles := []struct {
@@ -104,9 +213,23 @@ func seedDefaultData() error {
 		{"access.reject", "Reject Access", "Reject access requests", "access"},
 		{"access.grant", "Grant Access", "Directly grant access", "access"},
 		{"access.revoke", "Revoke Access", "Revoke existing access", "access"},
+		{"access.break_glass", "Break-Glass Access", "Obtain emergency access without approval", "access"},
 		// Audit permissions
 		{"audit.read", "View Audit Logs", "View audit logs", "audit"},
 		{"audit.export", "Export Audit Logs", "Export audit log data", "audit"},
+		{"audit.manage", "Manage Audit Retention", "Set audit retention, place legal holds and restore archives", "audit"},
+		// Policy permissions
+		{"policies.read", "View Policies", "View access policies and rules", "policies"},
+		{"policies.manage", "Manage Policies", "Create and modify access policies and rules", "policies"},
+		// Usage permissions
+		{"usage.report", "Report Usage", "Submit tool usage events from integrations", "usage"},
+		// Access review permissions
+		{"reviews.read", "View Access Reviews", "View certification campaigns and their reports", "reviews"},
+		{"reviews.manage", "Manage Access Reviews", "Launch, complete and cancel certification campaigns", "reviews"},
+		// Connector permissions
+		{"connectors.manage", "Manage Connectors", "Configure provisioning connectors and retry their jobs", "connectors"},
+		// Webhook permissions
+		{"webhooks.manage", "Manage Webhooks", "Configure outbound webhooks and redeliver events", "webhooks"},
 	}
 
 	for _, p := range permissions {
@@ -127,11 +250,11 @@ func seedDefaultData() error {
 		return fmt.Errorf("failed to assign permissions to super_admin: %w", err)
 	}
 
-	// Assign most permissions to admin role (except role.delete and audit.export)
+	// Assign most permissions to admin role (except role.delete, audit.export and audit.manage)
 	_, err = DB.Exec(`
 		INSERT OR IGNORE INTO role_permissions (role_id, permission_id)
 		SELECT r.id, p.id FROM roles r, permissions p 
-		WHERE r.name = 'admin' AND p.name NOT IN ('roles.delete', 'audit.export')`)
+		WHERE r.name = 'admin' AND p.name NOT IN ('roles.delete', 'audit.export', 'audit.manage')`)
 	if err != nil {
 		return fmt.Errorf("failed to assign permissions to admin: %w", err)
 	}
@@ -140,7 +263,7 @@ func seedDefaultData() error {
 	_, err = DB.Exec(`
 		INSERT OR IGNORE INTO role_permissions (role_id, permission_id)
 		SELECT r.id, p.id FROM roles r, permissions p 
-		WHERE r.name = 'manager' AND p.name IN ('users.read', 'roles.read', 'groups.read', 'tools.read', 'access.approve', 'access.reject', 'audit.read')`)
+		WHERE r.name = 'manager' AND p.name IN ('users.read', 'roles.read', 'groups.read', 'tools.read', 'access.approve', 'access.reject', 'audit.read', 'policies.read', 'reviews.read')`)
 	if err != nil {
 		return fmt.Errorf("failed to assign permissions to manager: %w", err)
 	}
@@ -6,8 +6,12 @@ CREATE TABLE IF NOT EXISTS users (
     first_name TEXT,
     last_name TEXT,
     is_active BOOLEAN DEFAULT TRUE,
+    department TEXT,
+    manager_id INTEGER,
+    last_login_at DATETIME,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (manager_id) REFERENCES users(id)
 );
 
 -- Roles with hierarchy support
@@ -42,6 +46,7 @@ CREATE TABLE IF NOT EXISTS tools (
     description TEXT,
     category TEXT,
     icon TEXT,
+    high_risk BOOLEAN DEFAULT FALSE,
     is_active BOOLEAN DEFAULT TRUE,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -119,6 +124,140 @@ CREATE TABLE IF NOT EXISTS group_tool_access (
     FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE
 );
 
+-- Tool owners (users or groups responsible for a tool)
+CREATE TABLE IF NOT EXISTS tool_owners (
+    tool_id INTEGER NOT NULL,
+    owner_type TEXT NOT NULL,
+    owner_id INTEGER NOT NULL,
+    added_by INTEGER,
+    added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tool_id, owner_type, owner_id),
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (added_by) REFERENCES users(id)
+);
+
+-- Ordered access levels a tool accepts, lowest rank first. Tools without rows here
+-- use the built-in read < write < admin levels.
+CREATE TABLE IF NOT EXISTS tool_access_levels (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tool_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    rank INTEGER NOT NULL,
+    auto_approve BOOLEAN DEFAULT FALSE,
+    approver_min_hierarchy INTEGER,
+    UNIQUE (tool_id, name),
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE
+);
+
+-- Auto-approval rules for low-risk requests; empty criteria match anything
+CREATE TABLE IF NOT EXISTS auto_approval_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT,
+    tool_id INTEGER,
+    tool_category TEXT,
+    access_level TEXT,
+    requester_role_id INTEGER,
+    requester_group_id INTEGER,
+    max_duration_minutes INTEGER,
+    start_time TEXT,
+    end_time TEXT,
+    priority INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (requester_role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (requester_group_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Decision SLAs for pending requests; a policy with no tool or category is the default
+CREATE TABLE IF NOT EXISTS sla_policies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    tool_id INTEGER,
+    tool_category TEXT,
+    sla_minutes INTEGER NOT NULL,
+    reminder_after_minutes INTEGER,
+    escalate_after_minutes INTEGER,
+    escalation_group_id INTEGER,
+    timeout_after_minutes INTEGER,
+    timeout_action TEXT NOT NULL DEFAULT 'reject',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (escalation_group_id) REFERENCES user_groups(id) ON DELETE SET NULL,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- When each user last used each tool, fed by access checks and usage reports
+CREATE TABLE IF NOT EXISTS tool_usage (
+    user_id INTEGER NOT NULL,
+    tool_id INTEGER NOT NULL,
+    last_used_at DATETIME NOT NULL,
+    source TEXT NOT NULL,
+    PRIMARY KEY (user_id, tool_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE
+);
+
+-- Flag or revoke tool grants left unused; a policy with no tool or category is the default
+CREATE TABLE IF NOT EXISTS dormancy_policies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    tool_id INTEGER,
+    tool_category TEXT,
+    inactive_days INTEGER NOT NULL,
+    action TEXT NOT NULL DEFAULT 'flag',
+    notice_days INTEGER NOT NULL DEFAULT 7,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Named sets of tools, roles and groups that can be requested together
+CREATE TABLE IF NOT EXISTS access_bundles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    display_name TEXT NOT NULL,
+    description TEXT,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS access_bundle_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    bundle_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    access_level TEXT DEFAULT 'read',
+    UNIQUE (bundle_id, target_type, target_id),
+    FOREIGN KEY (bundle_id) REFERENCES access_bundles(id) ON DELETE CASCADE
+);
+
+-- A user's request for a bundle; each item becomes an access request pointing back here
+CREATE TABLE IF NOT EXISTS access_bundle_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    bundle_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    reason TEXT,
+    duration_minutes INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (bundle_id) REFERENCES access_bundles(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
 -- Access requests with enhanced workflow
 CREATE TABLE IF NOT EXISTS access_requests (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -137,11 +276,210 @@ CREATE TABLE IF NOT EXISTS access_requests (
     rejected_at DATETIME,
     rejection_reason TEXT,
     expires_at DATETIME,
+    auto_approval_rule_id INTEGER,
+    incident_reference TEXT,
+    parent_request_id INTEGER,
+    reminded_at DATETIME,
+    escalated_at DATETIME,
+    escalation_group_id INTEGER,
+    dormant_notified_at DATETIME,
+    dormant_flagged_at DATETIME,
+    bundle_request_id INTEGER,
+    provisioning_status TEXT,
+    expiry_notified_at DATETIME,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id),
     FOREIGN KEY (approver_id) REFERENCES users(id),
     FOREIGN KEY (approved_by) REFERENCES users(id),
-    FOREIGN KEY (rejected_by) REFERENCES users(id)
+    FOREIGN KEY (rejected_by) REFERENCES users(id),
+    FOREIGN KEY (auto_approval_rule_id) REFERENCES auto_approval_rules(id),
+    FOREIGN KEY (parent_request_id) REFERENCES access_requests(id),
+    FOREIGN KEY (escalation_group_id) REFERENCES user_groups(id),
+    FOREIGN KEY (bundle_request_id) REFERENCES access_bundle_requests(id)
+);
+
+-- Connectors push grants for a tool to the target system
+CREATE TABLE IF NOT EXISTS connectors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    type TEXT NOT NULL,
+    tool_id INTEGER UNIQUE NOT NULL,
+    config TEXT NOT NULL,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    auto_remediate BOOLEAN DEFAULT FALSE,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Queued provision/deprovision calls, run in order per connector and retried with backoff
+CREATE TABLE IF NOT EXISTS provisioning_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    connector_id INTEGER NOT NULL,
+    access_request_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at DATETIME NOT NULL,
+    completed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (connector_id) REFERENCES connectors(id) ON DELETE CASCADE,
+    FOREIGN KEY (access_request_id) REFERENCES access_requests(id)
+);
+
+-- Comparisons of a connector's accounts in the target system with the grants behind them
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    connector_id INTEGER NOT NULL,
+    triggered_by INTEGER,
+    remediate BOOLEAN DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'RUNNING',
+    account_count INTEGER NOT NULL DEFAULT 0,
+    grant_count INTEGER NOT NULL DEFAULT 0,
+    orphaned_count INTEGER NOT NULL DEFAULT 0,
+    missing_count INTEGER NOT NULL DEFAULT 0,
+    mismatch_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at DATETIME NOT NULL,
+    completed_at DATETIME,
+    FOREIGN KEY (connector_id) REFERENCES connectors(id) ON DELETE CASCADE,
+    FOREIGN KEY (triggered_by) REFERENCES users(id)
+);
+
+-- Drift found by a reconciliation run and how it was fixed
+CREATE TABLE IF NOT EXISTS reconciliation_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    user_id INTEGER,
+    user_email TEXT NOT NULL,
+    access_request_id INTEGER,
+    expected_level TEXT,
+    actual_level TEXT,
+    external_id TEXT,
+    remediation TEXT,
+    remediation_error TEXT,
+    remediated_at DATETIME,
+    FOREIGN KEY (run_id) REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (access_request_id) REFERENCES access_requests(id)
+);
+
+-- Outbound webhook subscriptions. events is a JSON array of action patterns.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL,
+    max_attempts INTEGER NOT NULL DEFAULT 8,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Queued webhook calls and the response each one got
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL,
+    audit_log_id INTEGER,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER,
+    response_body TEXT,
+    last_error TEXT,
+    next_attempt_at DATETIME NOT NULL,
+    delivered_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    FOREIGN KEY (audit_log_id) REFERENCES audit_logs(id)
+);
+
+-- Channels users turned off per notification event. Missing rows mean enabled.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, event, channel)
+);
+
+-- In-app inbox. request_id links notifications about an access request to it.
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    request_id INTEGER,
+    read_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (request_id) REFERENCES access_requests(id) ON DELETE SET NULL
+);
+
+-- Single-use approve/reject links sent to approvers. The id is the nonce carried in
+-- the signed link; used_at is set when the link is redeemed.
+CREATE TABLE IF NOT EXISTS action_links (
+    id TEXT PRIMARY KEY,
+    request_id INTEGER NOT NULL,
+    approver_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES access_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (approver_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Discussion thread on an access request
+CREATE TABLE IF NOT EXISTS access_request_comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (request_id) REFERENCES access_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Approvers delegating their approvals to someone else for a date range
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    delegator_id INTEGER NOT NULL,
+    delegate_id INTEGER NOT NULL,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    reason TEXT,
+    revoked_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (delegator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (delegate_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Post-hoc reviews opened for break-glass grants
+CREATE TABLE IF NOT EXISTS break_glass_reviews (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    access_request_id INTEGER UNIQUE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    reviewer_id INTEGER,
+    reviewed_at DATETIME,
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (access_request_id) REFERENCES access_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users(id)
 );
 
 -- Enhanced audit logs
@@ -158,10 +496,172 @@ CREATE TABLE IF NOT EXISTS audit_logs (
     new_value TEXT,
     ip_address TEXT,
     user_agent TEXT,
+    severity TEXT NOT NULL DEFAULT 'info',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    prev_hash TEXT,
+    hash TEXT,
+    request_id TEXT,
     FOREIGN KEY (actor_id) REFERENCES users(id)
 );
 
+-- Signed snapshots of the audit chain head. A chain rebuilt after tampering no longer
+-- matches the hashes recorded here.
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    last_log_id INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    entry_count INTEGER NOT NULL,
+    signature TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- How long audit entries stay in the database, per action category. The '*' policy
+-- covers categories without their own; with neither, entries are kept forever.
+CREATE TABLE IF NOT EXISTS audit_retention_policies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    action_category TEXT UNIQUE NOT NULL,
+    retention_days INTEGER NOT NULL,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Legal holds keep matching audit entries in the database past their retention.
+-- Criteria left empty match any entry; dates are inclusive YYYY-MM-DD days.
+CREATE TABLE IF NOT EXISTS audit_legal_holds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    reason TEXT,
+    action_category TEXT,
+    actor_id INTEGER,
+    target_type TEXT,
+    target_id INTEGER,
+    date_from TEXT,
+    date_to TEXT,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    released_by INTEGER,
+    released_at DATETIME,
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (released_by) REFERENCES users(id)
+);
+
+-- Compressed files audit entries were moved to once their retention ran out
+CREATE TABLE IF NOT EXISTS audit_archives (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    file_name TEXT NOT NULL,
+    sha256 TEXT NOT NULL,
+    entry_count INTEGER NOT NULL,
+    first_log_id INTEGER NOT NULL,
+    last_log_id INTEGER NOT NULL,
+    first_created_at DATETIME NOT NULL,
+    last_created_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- What stays behind of an archived audit entry: its hashes, so the chain through it
+-- can still be verified, and the archive holding its content
+CREATE TABLE IF NOT EXISTS audit_archived_entries (
+    id INTEGER PRIMARY KEY,
+    archive_id INTEGER NOT NULL,
+    prev_hash TEXT,
+    hash TEXT,
+    FOREIGN KEY (archive_id) REFERENCES audit_archives(id)
+);
+
+-- Audit entries an external sink (syslog, SIEM) could not take, kept for replay
+CREATE TABLE IF NOT EXISTS audit_sink_dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sink TEXT NOT NULL,
+    audit_log_id INTEGER NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (audit_log_id) REFERENCES audit_logs(id)
+);
+
+-- Separation of duties rules: a user may not hold both sides at once
+CREATE TABLE IF NOT EXISTS sod_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT,
+    left_type TEXT NOT NULL,
+    left_id INTEGER NOT NULL,
+    left_access_level TEXT,
+    right_type TEXT NOT NULL,
+    right_id INTEGER NOT NULL,
+    right_access_level TEXT,
+    enforcement TEXT NOT NULL DEFAULT 'block',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Detected separation of duties conflicts and their exception decisions
+CREATE TABLE IF NOT EXISTS sod_violations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    entitlement_type TEXT NOT NULL,
+    entitlement_id INTEGER NOT NULL,
+    access_level TEXT,
+    status TEXT NOT NULL DEFAULT 'BLOCKED',
+    requested_by INTEGER,
+    decided_by INTEGER,
+    decided_at DATETIME,
+    decision_reason TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (rule_id) REFERENCES sod_rules(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (requested_by) REFERENCES users(id),
+    FOREIGN KEY (decided_by) REFERENCES users(id)
+);
+
+-- Access certification campaigns; items are a snapshot of the access in scope at launch
+CREATE TABLE IF NOT EXISTS review_campaigns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    description TEXT,
+    scope_type TEXT NOT NULL,
+    scope_id INTEGER,
+    scope_value TEXT,
+    status TEXT NOT NULL DEFAULT 'ACTIVE',
+    due_at DATETIME NOT NULL,
+    revoke_on_no_response BOOLEAN DEFAULT FALSE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME,
+    report TEXT,
+    report_signature TEXT,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- One entitlement held by one user, to be kept or revoked by its reviewer
+CREATE TABLE IF NOT EXISTS review_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    entitlement_type TEXT NOT NULL,
+    entitlement_id INTEGER NOT NULL,
+    target_type TEXT,
+    target_id INTEGER,
+    access_level TEXT,
+    reviewer_id INTEGER NOT NULL,
+    decision TEXT,
+    comment TEXT,
+    decided_by INTEGER,
+    decided_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (campaign_id) REFERENCES review_campaigns(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (reviewer_id) REFERENCES users(id),
+    FOREIGN KEY (decided_by) REFERENCES users(id)
+);
+
 -- Resources table (existing, kept for backward compatibility)
 CREATE TABLE IF NOT EXISTS resources (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -179,6 +679,24 @@ CREATE INDEX IF NOT EXISTS idx_role_permissions_role_id ON role_permissions(role
 CREATE INDEX IF NOT EXISTS idx_group_permissions_group_id ON group_permissions(group_id);
 CREATE INDEX IF NOT EXISTS idx_access_requests_user_id ON access_requests(user_id);
 CREATE INDEX IF NOT EXISTS idx_access_requests_status ON access_requests(status);
+CREATE INDEX IF NOT EXISTS idx_access_bundle_requests_user_id ON access_bundle_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_access_request_comments_request_id ON access_request_comments(request_id);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate_id ON approval_delegations(delegate_id);
+CREATE INDEX IF NOT EXISTS idx_provisioning_jobs_status ON provisioning_jobs(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_provisioning_jobs_request_id ON provisioning_jobs(access_request_id);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_connector_id ON reconciliation_runs(connector_id);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_findings_run_id ON reconciliation_findings(run_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_action_links_request_id ON action_links(request_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, read_at);
+CREATE INDEX IF NOT EXISTS idx_audit_sink_dead_letters_sink ON audit_sink_dead_letters(sink);
+CREATE INDEX IF NOT EXISTS idx_audit_archived_entries_archive_id ON audit_archived_entries(archive_id);
+CREATE INDEX IF NOT EXISTS idx_tool_owners_owner ON tool_owners(owner_type, owner_id);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_action_category ON audit_logs(action_category);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_sod_violations_user_id ON sod_violations(user_id);
+CREATE INDEX IF NOT EXISTS idx_sod_violations_status ON sod_violations(status);
+CREATE INDEX IF NOT EXISTS idx_review_items_campaign_id ON review_items(campaign_id);
+CREATE INDEX IF NOT EXISTS idx_review_items_reviewer_id ON review_items(reviewer_id);
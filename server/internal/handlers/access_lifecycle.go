@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+	"gatekeepr/internal/notify"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CancelAccessRequest lets a requester withdraw their own pending request
+func CancelAccessRequest(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var requesterID int
+	if err := database.DB.QueryRow("SELECT user_id FROM access_requests WHERE id = ?", requestID).Scan(&requesterID); err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if requesterID != GetActorID(r) {
+		http.Error(w, "You can only cancel your own requests", http.StatusForbidden)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	before, err := snapshotEntity(tx, "access_requests", requestID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := transitionRequest(tx, requestID, StatusCancelled, ""); err != nil {
+		tx.Rollback()
+		writeTransitionError(w, err, "Failed to cancel request")
+		return
+	}
+
+	after, err := snapshotEntity(tx, "access_requests", requestID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "access.request.cancel", "access_request", requestID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Request cancelled successfully"})
+}
+
+// ExtendAccessRequest asks for more time on an approved, time-boxed grant. The
+// extension is a new pending request that must be approved like any other; once
+// approved it replaces the original grant.
+func ExtendAccessRequest(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.ExtendAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DurationMinutes <= 0 {
+		http.Error(w, "duration_minutes is required", http.StatusBadRequest)
+		return
+	}
+
+	userID := GetActorID(r)
+
+	var grant models.AccessRequest
+	err := database.DB.QueryRow(`
+		SELECT user_id, target_type, target_id, access_level, status, expires_at
+		FROM access_requests WHERE id = ?`, requestID).Scan(
+		&grant.UserID, &grant.TargetType, &grant.TargetID, &grant.AccessLevel, &grant.Status, &grant.ExpiresAt)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if grant.UserID != userID {
+		http.Error(w, "You can only extend your own access", http.StatusForbidden)
+		return
+	}
+	if !canTransition(grant.Status, StatusExtended) {
+		http.Error(w, (&transitionError{From: grant.Status, To: StatusExtended}).Error(), http.StatusConflict)
+		return
+	}
+	if grant.ExpiresAt == nil {
+		http.Error(w, "Only time-boxed grants can be extended", http.StatusBadRequest)
+		return
+	}
+
+	var pending int
+	database.DB.QueryRow(`
+		SELECT COUNT(*) FROM access_requests
+		WHERE parent_request_id = ? AND request_type = 'extension' AND status = 'PENDING'`,
+		requestID).Scan(&pending)
+	if pending > 0 {
+		http.Error(w, "An extension for this grant is already pending", http.StatusConflict)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, reason, duration_minutes, parent_request_id)
+		VALUES (?, 'extension', ?, ?, ?, ?, ?, ?)`,
+		userID, grant.TargetType, grant.TargetID, grant.AccessLevel, req.Reason, req.DurationMinutes, requestID)
+	if err != nil {
+		http.Error(w, "Failed to request extension", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "access.request.extend", "access_request", int(id), "", nil, map[string]interface{}{
+		"parent_request_id": requestID,
+		"duration_minutes":  req.DurationMinutes,
+		"reason":            req.Reason,
+	})
+
+	notifyRequestApprovers(int(id))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Extension requested successfully"})
+}
+
+// ReRequestAccess files a new request based on one that was rejected, cancelled,
+// revoked or expired, optionally overriding its level, reason or duration
+func ReRequestAccess(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.ReRequestAccessRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var previous models.AccessRequest
+	err := database.DB.QueryRow(`
+		SELECT user_id, target_type, target_id, access_level, status, reason, duration_minutes
+		FROM access_requests WHERE id = ?`, requestID).Scan(
+		&previous.UserID, &previous.TargetType, &previous.TargetID, &previous.AccessLevel,
+		&previous.Status, &previous.Reason, &previous.DurationMinutes)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if previous.UserID != GetActorID(r) {
+		http.Error(w, "You can only re-request your own requests", http.StatusForbidden)
+		return
+	}
+	if previous.Status == StatusPending || previous.Status == StatusApproved {
+		http.Error(w, "Request is still "+previous.Status, http.StatusConflict)
+		return
+	}
+
+	dto := models.CreateAccessRequestDTO{
+		TargetType:      previous.TargetType,
+		TargetID:        previous.TargetID,
+		AccessLevel:     previous.AccessLevel,
+		Reason:          previous.Reason,
+		DurationMinutes: previous.DurationMinutes,
+	}
+	if req.AccessLevel != nil && *req.AccessLevel != "" {
+		dto.AccessLevel = *req.AccessLevel
+	}
+	if req.Reason != nil {
+		dto.Reason = req.Reason
+	}
+	if req.DurationMinutes != nil {
+		dto.DurationMinutes = req.DurationMinutes
+	}
+
+	submitAccessRequest(w, r, dto, &requestID)
+}
+
+// ListRequestComments returns the comment thread of an access request
+func ListRequestComments(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if !canParticipateInRequest(w, r, requestID) {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.request_id, c.user_id, c.body, c.created_at, u.email
+		FROM access_request_comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.request_id = ?
+		ORDER BY c.created_at ASC, c.id ASC`, requestID)
+	if err != nil {
+		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var comments []models.AccessRequestComment
+	for rows.Next() {
+		var c models.AccessRequestComment
+		if err := rows.Scan(&c.ID, &c.RequestID, &c.UserID, &c.Body, &c.CreatedAt, &c.UserEmail); err != nil {
+			http.Error(w, "Failed to scan comment", http.StatusInternalServerError)
+			return
+		}
+		comments = append(comments, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// AddRequestComment posts a comment on an access request
+func AddRequestComment(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if !canParticipateInRequest(w, r, requestID) {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Body == "" {
+		http.Error(w, "body is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO access_request_comments (request_id, user_id, body)
+		VALUES (?, ?, ?)`, requestID, GetActorID(r), req.Body)
+	if err != nil {
+		http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "access.request.comment", "access_request", requestID, "", nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Comment added successfully"})
+}
+
+// canParticipateInRequest checks that the current user is the requester or may decide
+// the request, writing an error response when they are not
+func canParticipateInRequest(w http.ResponseWriter, r *http.Request, requestID int) bool {
+	var requesterID, targetID int
+	var targetType string
+	err := database.DB.QueryRow(`
+		SELECT user_id, target_type, target_id FROM access_requests WHERE id = ?`,
+		requestID).Scan(&requesterID, &targetType, &targetID)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return false
+	}
+
+	if requesterID != GetActorID(r) && !canDecideRequest(r, requestID, targetType, targetID) {
+		RecordAuthzDenial(r, "capability:request_participant")
+		http.Error(w, "You are not a participant in this request", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ExpiryNoticeWindow is how long before a grant expires its holder is warned
+var ExpiryNoticeWindow = 24 * time.Hour
+
+// StartExpiryWorker warns holders of grants about to expire, expires lapsed grants and
+// closes extensions left without a grant every interval until ctx is cancelled
+func StartExpiryWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "expiry worker", interval, func(now time.Time) error {
+		if err := warnExpiringGrants(now); err != nil {
+			return err
+		}
+		if err := expireGrants(now); err != nil {
+			return err
+		}
+		return expireOrphanedExtensions()
+	})
+}
+
+// warnExpiringGrants notifies holders of grants expiring within ExpiryNoticeWindow,
+// once per grant
+func warnExpiringGrants(now time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT id FROM access_requests
+		WHERE status = 'APPROVED' AND expires_at IS NOT NULL AND expires_at > ? AND expires_at <= ?
+		  AND expiry_notified_at IS NULL`, now, now.Add(ExpiryNoticeWindow))
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if _, err := database.DB.Exec("UPDATE access_requests SET expiry_notified_at = ? WHERE id = ?", now, id); err != nil {
+			return err
+		}
+		notifyRequester(id, notify.EventRequestExpiring)
+	}
+	return nil
+}
+
+// expireGrants moves approved grants past their expiry time to EXPIRED, which also
+// queues their deprovisioning
+func expireGrants(now time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT id FROM access_requests
+		WHERE status = 'APPROVED' AND expires_at IS NOT NULL AND expires_at <= ?`, now)
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		tx, err := database.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if err := transitionRequest(tx, id, StatusExpired, ""); err != nil {
+			tx.Rollback()
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		LogSystemAudit("access.expire", "access_request", id, "", "Grant reached its expiry time",
+			map[string]string{"status": StatusApproved}, map[string]string{"status": StatusExpired})
+
+		notifyRequester(id, notify.EventRequestExpired)
+	}
+	return nil
+}
+
+// expireOrphanedExtensions expires pending extensions whose grant has expired or been
+// revoked. Such an extension can never be approved, since only an approved grant can
+// move to EXTENDED, and would otherwise wait for a decision forever.
+func expireOrphanedExtensions() error {
+	rows, err := database.DB.Query(`
+		SELECT e.id FROM access_requests e
+		JOIN access_requests p ON e.parent_request_id = p.id
+		WHERE e.request_type = 'extension' AND e.status = 'PENDING' AND p.status != 'APPROVED'`)
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	const reason = "The grant it extends is no longer active"
+	for _, id := range ids {
+		tx, err := database.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if err := transitionRequest(tx, id, StatusExpired, "rejection_reason = ?", reason); err != nil {
+			tx.Rollback()
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		LogSystemAudit("access.request.expire", "access_request", id, "", reason,
+			map[string]string{"status": StatusPending}, map[string]string{"status": StatusExpired})
+
+		notifyRequester(id, notify.EventRequestExpired)
+	}
+	return nil
+}
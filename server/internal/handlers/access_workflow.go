@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"gatekeepr/internal/database"
 	"gatekeepr/internal/models"
+	"gatekeepr/internal/notify"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -25,11 +29,59 @@ func CreateAccessRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.AccessLevel == "" {
-		req.AccessLevel = "read"
+	submitAccessRequest(w, r, req, nil)
+}
+
+// errDuplicateRequest is returned when the user already has a pending request for the target
+var errDuplicateRequest = errors.New("duplicate pending request")
+
+// autoApproval explains why a request skipped human review: a matching auto-approval
+// rule, or an access level that needs no approval
+type autoApproval struct {
+	Rule   *models.AutoApprovalRule
+	Reason string
+}
+
+// submitAccessRequest files a request for the current user, auto-approving it when a
+// rule matches. parentID links a re-request to the request it was created from.
+func submitAccessRequest(w http.ResponseWriter, r *http.Request, req models.CreateAccessRequestDTO, parentID *int) {
+	id, approval, err := fileAccessRequest(r, GetActorID(r), req, parentID, nil)
+	var levelErr *accessLevelError
+	switch {
+	case errors.As(err, &levelErr):
+		http.Error(w, levelErr.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errDuplicateRequest):
+		http.Error(w, "You already have a pending request for this resource", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		return
 	}
 
-	userID := GetActorID(r)
+	response := map[string]interface{}{"id": id, "status": "PENDING", "message": "Access request created successfully"}
+	if approval != nil {
+		response["status"] = "APPROVED"
+		response["message"] = "Access request auto-approved " + approval.Reason
+		if approval.Rule != nil {
+			response["auto_approval_rule_id"] = approval.Rule.ID
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// fileAccessRequest validates the access level and inserts a request for a user,
+// auto-approving it when a rule matches or the level needs no approval.
+// bundleRequestID links the request to the bundle request it was fanned out from.
+func fileAccessRequest(r *http.Request, userID int, req models.CreateAccessRequestDTO, parentID *int, bundleRequestID *int) (int, *autoApproval, error) {
+	level, err := resolveAccessLevel(req.TargetType, req.TargetID, req.AccessLevel)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.AccessLevel = level
 
 	// Check if user already has a pending request for this target
 	var existingCount int
@@ -39,26 +91,79 @@ func CreateAccessRequest(w http.ResponseWriter, r *http.Request) {
 		userID, req.TargetType, req.TargetID).Scan(&existingCount)
 
 	if existingCount > 0 {
-		http.Error(w, "You already have a pending request for this resource", http.StatusConflict)
-		return
+		return 0, nil, errDuplicateRequest
+	}
+
+	// Low-risk requests matching an auto-approval rule or asking for a level that
+	// needs no approval skip human review, unless granting them would violate a
+	// separation of duties rule
+	var approval *autoApproval
+	rule, _, err := evaluateAutoApprovalRules(autoApprovalCandidate{
+		UserID:          userID,
+		TargetType:      req.TargetType,
+		TargetID:        req.TargetID,
+		AccessLevel:     req.AccessLevel,
+		DurationMinutes: req.DurationMinutes,
+		At:              time.Now(),
+	})
+	if err == nil && rule != nil {
+		approval = &autoApproval{Rule: rule, Reason: "by rule " + rule.Name}
+	} else if def := findToolAccessLevel(req.TargetType, req.TargetID, req.AccessLevel); def != nil && def.AutoApprove {
+		approval = &autoApproval{Reason: "as " + def.Name + " access needs no approval"}
+	}
+	if approval != nil {
+		conflicts, err := findSoDConflicts(userID, []Entitlement{{Type: req.TargetType, ID: req.TargetID, AccessLevel: req.AccessLevel}})
+		if err != nil || len(conflicts) > 0 {
+			approval = nil
+		}
 	}
 
-	result, err := database.DB.Exec(`
-		INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, reason, duration_minutes)
-		VALUES (?, 'tool_access', ?, ?, ?, ?, ?)`,
-		userID, req.TargetType, req.TargetID, req.AccessLevel, req.Reason, req.DurationMinutes)
+	var result sql.Result
+	if approval != nil {
+		var expiresAt *time.Time
+		if req.DurationMinutes != nil && *req.DurationMinutes > 0 {
+			t := time.Now().Add(time.Duration(*req.DurationMinutes) * time.Minute)
+			expiresAt = &t
+		}
+		var ruleID *int
+		if approval.Rule != nil {
+			ruleID = &approval.Rule.ID
+		}
+
+		result, err = database.DB.Exec(`
+			INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, reason, duration_minutes,
+				status, approved_at, expires_at, auto_approval_rule_id, parent_request_id, bundle_request_id)
+			VALUES (?, 'tool_access', ?, ?, ?, ?, ?, 'APPROVED', CURRENT_TIMESTAMP, ?, ?, ?, ?)`,
+			userID, req.TargetType, req.TargetID, req.AccessLevel, req.Reason, req.DurationMinutes, expiresAt, ruleID,
+			parentID, bundleRequestID)
+	} else {
+		result, err = database.DB.Exec(`
+			INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, reason, duration_minutes,
+				parent_request_id, bundle_request_id)
+			VALUES (?, 'tool_access', ?, ?, ?, ?, ?, ?, ?)`,
+			userID, req.TargetType, req.TargetID, req.AccessLevel, req.Reason, req.DurationMinutes, parentID, bundleRequestID)
+	}
 	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return
+		return 0, nil, err
 	}
 
 	id, _ := result.LastInsertId()
 
 	LogAudit(r, "access.request.create", "access_request", int(id), "", nil, &req)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Access request created successfully"})
+	if approval != nil {
+		LogRequestSystemAudit(r, "access.request.auto_approve", "access_request", int(id), "",
+			"Auto-approved "+approval.Reason, nil, approval.Rule)
+
+		if err := enqueueProvisioning(database.DB, int(id), ProvisionAction); err != nil {
+			log.Printf("Failed to queue provisioning for request %d: %v", id, err)
+		}
+		notifyRequester(int(id), notify.EventRequestApproved)
+	} else {
+		notifyRequestApprovers(int(id))
+	}
+
+	return int(id), approval, nil
 }
 
 // ListAccessRequests returns access requests with filters
@@ -66,14 +171,15 @@ func ListAccessRequests(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
 	userID := r.URL.Query().Get("user_id")
 	targetType := r.URL.Query().Get("target_type")
+	requestType := r.URL.Query().Get("request_type")
 
 	query := `
 		SELECT ar.id, ar.user_id, ar.request_type, ar.target_type, ar.target_id, 
 			   ar.access_level, ar.status, ar.reason, ar.duration_minutes,
 			   ar.approver_id, ar.approved_by, ar.approved_at, 
 			   ar.rejected_by, ar.rejected_at, ar.rejection_reason,
-			   ar.expires_at, ar.created_at,
-			   u.email as user_email
+			   ar.expires_at, ar.auto_approval_rule_id, ar.incident_reference, ar.parent_request_id, ar.bundle_request_id, ar.provisioning_status,
+			   ar.created_at, u.email as user_email
 		FROM access_requests ar
 		JOIN users u ON ar.user_id = u.id
 		WHERE 1=1`
@@ -91,6 +197,10 @@ func ListAccessRequests(w http.ResponseWriter, r *http.Request) {
 		query += " AND ar.target_type = ?"
 		args = append(args, targetType)
 	}
+	if requestType != "" {
+		query += " AND ar.request_type = ?"
+		args = append(args, requestType)
+	}
 
 	query += " ORDER BY ar.created_at DESC LIMIT 100"
 
@@ -108,7 +218,8 @@ func ListAccessRequests(w http.ResponseWriter, r *http.Request) {
 			&req.AccessLevel, &req.Status, &req.Reason, &req.DurationMinutes,
 			&req.ApproverID, &req.ApprovedBy, &req.ApprovedAt,
 			&req.RejectedBy, &req.RejectedAt, &req.RejectionReason,
-			&req.ExpiresAt, &req.CreatedAt, &req.UserEmail); err != nil {
+			&req.ExpiresAt, &req.AutoApprovalRuleID, &req.IncidentReference, &req.ParentRequestID, &req.BundleRequestID,
+			&req.ProvisioningStatus, &req.CreatedAt, &req.UserEmail); err != nil {
 			http.Error(w, "Failed to scan request", http.StatusInternalServerError)
 			return
 		}
@@ -121,20 +232,43 @@ func ListAccessRequests(w http.ResponseWriter, r *http.Request) {
 
 // GetPendingRequests returns requests pending approval
 func GetPendingRequests(w http.ResponseWriter, r *http.Request) {
-	// Only show pending requests to users who can approve
-	if !CanApproveRequests(r) {
-		http.Error(w, "You do not have permission to view pending requests", http.StatusForbidden)
-		return
+	actorID := GetActorID(r)
+
+	// Approvers see every pending request; tool owners only see requests for their tools
+	// and members of an escalation group see the requests escalated to it. Delegates also
+	// see what the approvers they stand in for would see.
+	deciders := append([]int{actorID}, activeDelegators(actorID)...)
+	seeAll := false
+	for _, id := range deciders {
+		if userCanApproveRequests(id) {
+			seeAll = true
+			break
+		}
 	}
 
-	rows, err := database.DB.Query(`
+	query := `
 		SELECT ar.id, ar.user_id, ar.request_type, ar.target_type, ar.target_id, 
 			   ar.access_level, ar.status, ar.reason, ar.duration_minutes,
-			   ar.created_at, u.email as user_email
+			   ar.created_at, u.email as user_email,
+			   (ar.target_type = 'tool' AND ar.target_id IN (` + ownedToolsSubquery + `)) as is_tool_owner,
+			   ar.escalated_at, ar.escalation_group_id, ar.bundle_request_id
 		FROM access_requests ar
 		JOIN users u ON ar.user_id = u.id
-		WHERE ar.status = 'PENDING'
-		ORDER BY ar.created_at ASC`)
+		WHERE ar.status = 'PENDING'`
+	args := []interface{}{actorID, actorID}
+
+	if !seeAll || r.URL.Query().Get("owned") == "true" {
+		clauses := []string{}
+		for _, id := range deciders {
+			clauses = append(clauses, `(ar.target_type = 'tool' AND ar.target_id IN (`+ownedToolsSubquery+`))
+			OR ar.escalation_group_id IN (SELECT group_id FROM user_group_members WHERE user_id = ?)`)
+			args = append(args, id, id, id)
+		}
+		query += " AND (" + joinStrings(clauses, " OR ") + ")"
+	}
+	query += " ORDER BY ar.created_at ASC"
+
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to fetch requests", http.StatusInternalServerError)
 		return
@@ -142,17 +276,21 @@ func GetPendingRequests(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	type PendingRequest struct {
-		ID              int       `json:"id"`
-		UserID          int       `json:"user_id"`
-		RequestType     string    `json:"request_type"`
-		TargetType      string    `json:"target_type"`
-		TargetID        int       `json:"target_id"`
-		AccessLevel     string    `json:"access_level"`
-		Status          string    `json:"status"`
-		Reason          *string   `json:"reason,omitempty"`
-		DurationMinutes *int      `json:"duration_minutes,omitempty"`
-		CreatedAt       time.Time `json:"created_at"`
-		UserEmail       string    `json:"user_email"`
+		ID                int        `json:"id"`
+		UserID            int        `json:"user_id"`
+		RequestType       string     `json:"request_type"`
+		TargetType        string     `json:"target_type"`
+		TargetID          int        `json:"target_id"`
+		AccessLevel       string     `json:"access_level"`
+		Status            string     `json:"status"`
+		Reason            *string    `json:"reason,omitempty"`
+		DurationMinutes   *int       `json:"duration_minutes,omitempty"`
+		CreatedAt         time.Time  `json:"created_at"`
+		UserEmail         string     `json:"user_email"`
+		IsToolOwner       bool       `json:"is_tool_owner"`
+		EscalatedAt       *time.Time `json:"escalated_at,omitempty"`
+		EscalationGroupID *int       `json:"escalation_group_id,omitempty"`
+		BundleRequestID   *int       `json:"bundle_request_id,omitempty"`
 	}
 
 	var requests []PendingRequest
@@ -160,7 +298,7 @@ func GetPendingRequests(w http.ResponseWriter, r *http.Request) {
 		var req PendingRequest
 		if err := rows.Scan(&req.ID, &req.UserID, &req.RequestType, &req.TargetType, &req.TargetID,
 			&req.AccessLevel, &req.Status, &req.Reason, &req.DurationMinutes,
-			&req.CreatedAt, &req.UserEmail); err != nil {
+			&req.CreatedAt, &req.UserEmail, &req.IsToolOwner, &req.EscalatedAt, &req.EscalationGroupID, &req.BundleRequestID); err != nil {
 			http.Error(w, "Failed to scan request", http.StatusInternalServerError)
 			return
 		}
@@ -173,36 +311,119 @@ func GetPendingRequests(w http.ResponseWriter, r *http.Request) {
 
 // ApproveAccessRequest approves a pending request
 func ApproveAccessRequest(w http.ResponseWriter, r *http.Request) {
-	if !CanApproveRequests(r) {
-		http.Error(w, "You do not have permission to approve requests", http.StatusForbidden)
-		return
-	}
-
 	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
 	var req models.ApproveAccessRequest
 	json.NewDecoder(r.Body).Decode(&req)
 
+	approveAccessRequest(w, r, requestID, req, "")
+}
+
+// approveAccessRequest approves a request as the current user after checking their
+// rights. via, when set, notes in the audit entry how the decision was made.
+func approveAccessRequest(w http.ResponseWriter, r *http.Request, requestID int, req models.ApproveAccessRequest, via string) {
 	approverID := GetActorID(r)
 
-	// Calculate expiration
+	var requesterID, targetID int
+	var targetType, accessLevel, requestType string
+	var requestedMinutes, parentID *int
+	err := database.DB.QueryRow(`
+		SELECT user_id, target_type, target_id, access_level, request_type, duration_minutes, parent_request_id
+		FROM access_requests WHERE id = ?`,
+		requestID).Scan(&requesterID, &targetType, &targetID, &accessLevel, &requestType, &requestedMinutes, &parentID)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
+	onBehalfOf, ok := resolveRequestDecider(approverID, requestID, targetType, targetID)
+	if !ok {
+		RecordAuthzDenial(r, "capability:can_approve_requests")
+		http.Error(w, "You do not have permission to approve requests", http.StatusForbidden)
+		return
+	}
+
+	if (requesterID == approverID || requesterID == onBehalfOf) && !AllowSelfApproval {
+		http.Error(w, "You cannot approve your own request", http.StatusForbidden)
+		return
+	}
+	if err := checkLevelApprover(approverID, targetType, targetID, accessLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	proposed := []Entitlement{{Type: targetType, ID: targetID, AccessLevel: accessLevel}}
+	if !enforceSoD(w, r, requesterID, "access.request.approve", proposed) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	before, err := snapshotEntity(tx, "access_requests", requestID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load request", http.StatusInternalServerError)
+		return
+	}
+
+	// Calculate expiration. Extensions run from the end of the grant they extend
+	// and default to the duration the requester asked for.
+	start := time.Now()
+	minutes := req.DurationMinutes
+	if requestType == "extension" && parentID != nil {
+		var parentExpiry *time.Time
+		tx.QueryRow("SELECT expires_at FROM access_requests WHERE id = ?", *parentID).Scan(&parentExpiry)
+		if parentExpiry != nil && parentExpiry.After(start) {
+			start = *parentExpiry
+		}
+		if minutes == nil {
+			minutes = requestedMinutes
+		}
+
+		if err := transitionRequest(tx, *parentID, StatusExtended, ""); err != nil {
+			tx.Rollback()
+			writeTransitionError(w, err, "Failed to extend request")
+			return
+		}
+	}
+
 	var expiresAt *time.Time
-	if req.DurationMinutes != nil && *req.DurationMinutes > 0 {
-		t := time.Now().Add(time.Duration(*req.DurationMinutes) * time.Minute)
+	if minutes != nil && *minutes > 0 {
+		t := start.Add(time.Duration(*minutes) * time.Minute)
 		expiresAt = &t
 	}
 
-	_, err := database.DB.Exec(`
-		UPDATE access_requests 
-		SET status = 'APPROVED', approved_by = ?, approved_at = CURRENT_TIMESTAMP, expires_at = ?
-		WHERE id = ? AND status = 'PENDING'`,
-		approverID, expiresAt, requestID)
+	err = transitionRequest(tx, requestID, StatusApproved,
+		"approved_by = ?, approved_at = CURRENT_TIMESTAMP, expires_at = ?", approverID, expiresAt)
 	if err != nil {
-		http.Error(w, "Failed to approve request", http.StatusInternalServerError)
+		tx.Rollback()
+		writeTransitionError(w, err, "Failed to approve request")
 		return
 	}
 
-	LogAudit(r, "access.request.approve", "access_request", requestID, "", nil, &req)
+	after, err := snapshotEntity(tx, "access_requests", requestID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if note := decisionNote("Approved", approverID, onBehalfOf, via); note != "" {
+		LogAuditWithDetails(r, "access.request.approve", "access_request", requestID, "", note, before, after)
+	} else {
+		LogAudit(r, "access.request.approve", "access_request", requestID, "", before, after)
+	}
+
+	notifyRequester(requestID, notify.EventRequestApproved)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Request approved successfully"})
@@ -210,11 +431,6 @@ func ApproveAccessRequest(w http.ResponseWriter, r *http.Request) {
 
 // RejectAccessRequest rejects a pending request
 func RejectAccessRequest(w http.ResponseWriter, r *http.Request) {
-	if !CanApproveRequests(r) {
-		http.Error(w, "You do not have permission to reject requests", http.StatusForbidden)
-		return
-	}
-
 	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
 	var req models.RejectAccessRequest
@@ -223,19 +439,71 @@ func RejectAccessRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rejectAccessRequest(w, r, requestID, req, "")
+}
+
+// rejectAccessRequest rejects a request as the current user after checking their
+// rights. via, when set, notes in the audit entry how the decision was made.
+func rejectAccessRequest(w http.ResponseWriter, r *http.Request, requestID int, req models.RejectAccessRequest, via string) {
+	var targetType string
+	var targetID int
+	err := database.DB.QueryRow(`
+		SELECT target_type, target_id FROM access_requests WHERE id = ?`,
+		requestID).Scan(&targetType, &targetID)
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+
 	rejectorID := GetActorID(r)
 
-	_, err := database.DB.Exec(`
-		UPDATE access_requests 
-		SET status = 'REJECTED', rejected_by = ?, rejected_at = CURRENT_TIMESTAMP, rejection_reason = ?
-		WHERE id = ? AND status = 'PENDING'`,
-		rejectorID, req.Reason, requestID)
+	onBehalfOf, ok := resolveRequestDecider(rejectorID, requestID, targetType, targetID)
+	if !ok {
+		RecordAuthzDenial(r, "capability:can_approve_requests")
+		http.Error(w, "You do not have permission to reject requests", http.StatusForbidden)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	before, err := snapshotEntity(tx, "access_requests", requestID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load request", http.StatusInternalServerError)
+		return
+	}
+
+	err = transitionRequest(tx, requestID, StatusRejected,
+		"rejected_by = ?, rejected_at = CURRENT_TIMESTAMP, rejection_reason = ?", rejectorID, req.Reason)
 	if err != nil {
-		http.Error(w, "Failed to reject request", http.StatusInternalServerError)
+		tx.Rollback()
+		writeTransitionError(w, err, "Failed to reject request")
+		return
+	}
+
+	after, err := snapshotEntity(tx, "access_requests", requestID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "access.request.reject", "access_request", requestID, "", nil, &req)
+	if note := decisionNote("Rejected", rejectorID, onBehalfOf, via); note != "" {
+		LogAuditWithDetails(r, "access.request.reject", "access_request", requestID, "", note, before, after)
+	} else {
+		LogAudit(r, "access.request.reject", "access_request", requestID, "", before, after)
+	}
+
+	notifyRequester(requestID, notify.EventRequestRejected)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Request rejected successfully"})
@@ -244,6 +512,7 @@ func RejectAccessRequest(w http.ResponseWriter, r *http.Request) {
 // DirectGrant grants access directly without a request (for authorized users)
 func DirectGrant(w http.ResponseWriter, r *http.Request) {
 	if !CanGrantAccess(r) {
+		RecordAuthzDenial(r, "capability:can_grant_access")
 		http.Error(w, "You do not have permission to grant access directly", http.StatusForbidden)
 		return
 	}
@@ -259,31 +528,64 @@ func DirectGrant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.AccessLevel == "" {
-		req.AccessLevel = "read"
+	level, err := resolveAccessLevel(req.TargetType, req.TargetID, req.AccessLevel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	req.AccessLevel = level
 
 	granterID := GetActorID(r)
 
+	if err := checkLevelApprover(granterID, req.TargetType, req.TargetID, req.AccessLevel); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	proposed := []Entitlement{{Type: req.TargetType, ID: req.TargetID, AccessLevel: req.AccessLevel}}
+	if !enforceSoD(w, r, req.UserID, "access.grant.direct", proposed) {
+		return
+	}
+
 	var expiresAt *time.Time
 	if req.DurationMinutes != nil && *req.DurationMinutes > 0 {
 		t := time.Now().Add(time.Duration(*req.DurationMinutes) * time.Minute)
 		expiresAt = &t
 	}
 
-	result, err := database.DB.Exec(`
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
 		INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, status, approved_by, approved_at, expires_at)
 		VALUES (?, 'tool_access', ?, ?, ?, 'APPROVED', ?, CURRENT_TIMESTAMP, ?)`,
 		req.UserID, req.TargetType, req.TargetID, req.AccessLevel, granterID, expiresAt)
 	if err != nil {
+		tx.Rollback()
 		http.Error(w, "Failed to grant access", http.StatusInternalServerError)
 		return
 	}
 
 	id, _ := result.LastInsertId()
 
+	if err := enqueueProvisioning(tx, int(id), ProvisionAction); err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to queue provisioning", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	LogAudit(r, "access.grant.direct", "access_request", int(id), "", nil, &req)
 
+	notifyRequester(int(id), notify.EventRequestApproved)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Access granted successfully"})
 }
@@ -291,6 +593,7 @@ func DirectGrant(w http.ResponseWriter, r *http.Request) {
 // RevokeAccess revokes existing access
 func RevokeAccess(w http.ResponseWriter, r *http.Request) {
 	if !CanGrantAccess(r) {
+		RecordAuthzDenial(r, "capability:can_grant_access")
 		http.Error(w, "You do not have permission to revoke access", http.StatusForbidden)
 		return
 	}
@@ -301,17 +604,58 @@ func RevokeAccess(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := database.DB.Exec(`
-		UPDATE access_requests 
-		SET status = 'REVOKED'
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	var grantIDs []int
+	rows, err := tx.Query(`
+		SELECT id FROM access_requests
 		WHERE user_id = ? AND target_type = ? AND target_id = ? AND status = 'APPROVED'`,
 		req.UserID, req.TargetType, req.TargetID)
 	if err != nil {
+		tx.Rollback()
 		http.Error(w, "Failed to revoke access", http.StatusInternalServerError)
 		return
 	}
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			grantIDs = append(grantIDs, id)
+		}
+	}
+	rows.Close()
+
+	befores := make([]interface{}, len(grantIDs))
+	afters := make([]interface{}, len(grantIDs))
+	for i, id := range grantIDs {
+		if befores[i], err = snapshotEntity(tx, "access_requests", id); err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to load request", http.StatusInternalServerError)
+			return
+		}
+		if err := transitionRequest(tx, id, StatusRevoked, ""); err != nil {
+			tx.Rollback()
+			writeTransitionError(w, err, "Failed to revoke access")
+			return
+		}
+		if afters[i], err = snapshotEntity(tx, "access_requests", id); err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to load request", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
 
-	LogAudit(r, "access.revoke", "access_request", 0, "", nil, &req)
+	for i, id := range grantIDs {
+		LogAudit(r, "access.revoke", "access_request", id, "", befores[i], afters[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Access revoked successfully"})
@@ -324,7 +668,8 @@ func GetMyRequests(w http.ResponseWriter, r *http.Request) {
 	rows, err := database.DB.Query(`
 		SELECT id, request_type, target_type, target_id, access_level, 
 			   status, reason, duration_minutes, approved_at, 
-			   rejected_at, rejection_reason, expires_at, created_at
+			   rejected_at, rejection_reason, expires_at, auto_approval_rule_id, incident_reference, parent_request_id,
+			   bundle_request_id, provisioning_status, created_at
 		FROM access_requests
 		WHERE user_id = ?
 		ORDER BY created_at DESC`, userID)
@@ -341,7 +686,8 @@ func GetMyRequests(w http.ResponseWriter, r *http.Request) {
 		if err := rows.Scan(&req.ID, &req.RequestType, &req.TargetType, &req.TargetID,
 			&req.AccessLevel, &req.Status, &req.Reason, &req.DurationMinutes,
 			&req.ApprovedAt, &req.RejectedAt, &req.RejectionReason,
-			&req.ExpiresAt, &req.CreatedAt); err != nil {
+			&req.ExpiresAt, &req.AutoApprovalRuleID, &req.IncidentReference, &req.ParentRequestID, &req.BundleRequestID,
+			&req.ProvisioningStatus, &req.CreatedAt); err != nil {
 			http.Error(w, "Failed to scan request", http.StatusInternalServerError)
 			return
 		}
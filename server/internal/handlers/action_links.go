@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/auth"
+	"gatekeepr/internal/database"
+	authMiddleware "gatekeepr/internal/middleware"
+	"gatekeepr/internal/models"
+	"gatekeepr/internal/notify"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PublicURL is the externally reachable base URL of the API, used to build the action
+// links sent in notifications
+var PublicURL = "http://localhost:8080"
+
+// ActionLinkTTL is how long an approve or reject link in a notification stays valid
+var ActionLinkTTL = 30 * time.Minute
+
+// Actions an action link can take on a request
+const (
+	LinkApprove = "approve"
+	LinkReject  = "reject"
+)
+
+var errLinkInvalid = errors.New("this link is invalid")
+var errLinkExpired = errors.New("this link has expired")
+var errLinkUsed = errors.New("this link has already been used")
+var errLinkLocked = errors.New("too many incorrect passwords; this link can no longer be used")
+
+// actionLinkMaxPasswordAttempts is how many wrong passwords an approver may enter across
+// their links for one request before all of them stop working
+const actionLinkMaxPasswordAttempts = 5
+
+// actionClaims is the signed part of an action link
+type actionClaims struct {
+	ID         string `json:"id"`
+	RequestID  int    `json:"request_id"`
+	ApproverID int    `json:"approver_id"`
+	Action     string `json:"action"`
+	Channel    string `json:"channel"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// actionPage is what the confirmation and result pages show
+type actionPage struct {
+	Title       string
+	Message     string
+	Error       string
+	Action      string
+	Requester   string
+	AccessLevel string
+	TargetName  string
+	Reason      string
+	Approver    string
+	HighRisk    bool
+	ShowForm    bool
+}
+
+var actionPageTemplate = template.Must(template.New("action").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}} - gatekeepr</title></head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+{{if .Requester}}
+<p>{{.Requester}} requested {{.AccessLevel}} access to {{.TargetName}}.</p>
+{{if .Reason}}<p>Reason: {{.Reason}}</p>{{end}}
+{{end}}
+{{if .ShowForm}}
+<form method="post">
+{{if eq .Action "reject"}}<p><label>Reason <input type="text" name="reason"></label></p>{{end}}
+{{if .HighRisk}}<p>This tool is high risk. Enter the password for {{.Approver}} to continue.</p>
+<p><label>Password <input type="password" name="password" autocomplete="current-password"></label></p>{{end}}
+<p><button type="submit">{{if eq .Action "approve"}}Approve{{else}}Reject{{end}} request</button></p>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// ShowActionLink renders the confirmation page for an action link. Nothing is decided
+// on GET, so mail scanners that prefetch links cannot approve requests.
+func ShowActionLink(w http.ResponseWriter, r *http.Request) {
+	claims, err := verifyActionLink(chi.URLParam(r, "token"))
+	if err != nil {
+		renderActionPage(w, http.StatusGone, actionPage{Title: "Link unavailable", Error: err.Error()})
+		return
+	}
+
+	page, err := actionLinkPage(claims)
+	if err != nil {
+		renderActionPage(w, http.StatusNotFound, actionPage{Title: "Request not found", Error: err.Error()})
+		return
+	}
+	page.ShowForm = true
+	renderActionPage(w, http.StatusOK, page)
+}
+
+// ConfirmActionLink carries out the decision of an action link. The approver's rights
+// are checked again at this point, high-risk tools require their password, and the
+// link cannot be used again whatever the outcome.
+func ConfirmActionLink(w http.ResponseWriter, r *http.Request) {
+	claims, err := verifyActionLink(chi.URLParam(r, "token"))
+	if err != nil {
+		renderActionPage(w, http.StatusGone, actionPage{Title: "Link unavailable", Error: err.Error()})
+		return
+	}
+
+	page, err := actionLinkPage(claims)
+	if err != nil {
+		renderActionPage(w, http.StatusNotFound, actionPage{Title: "Request not found", Error: err.Error()})
+		return
+	}
+
+	var email, passwordHash string
+	err = database.DB.QueryRow("SELECT email, password_hash FROM users WHERE id = ? AND is_active = 1", claims.ApproverID).
+		Scan(&email, &passwordHash)
+	if err != nil {
+		renderActionPage(w, http.StatusForbidden, actionPage{Title: "Not allowed", Error: "Your account is not active"})
+		return
+	}
+
+	// Act as the approver from here on so permission checks and the audit log see them
+	r = r.WithContext(context.WithValue(r.Context(), authMiddleware.UserContextKey,
+		&auth.Claims{UserID: claims.ApproverID, Email: email}))
+
+	var targetType string
+	var targetID int
+	database.DB.QueryRow("SELECT target_type, target_id FROM access_requests WHERE id = ?", claims.RequestID).
+		Scan(&targetType, &targetID)
+	if _, ok := resolveRequestDecider(claims.ApproverID, claims.RequestID, targetType, targetID); !ok {
+		RecordAuthzDenial(r, "capability:can_approve_requests")
+		renderActionPage(w, http.StatusForbidden, actionPage{Title: "Not allowed",
+			Error: "You can no longer decide this request"})
+		return
+	}
+
+	if page.HighRisk {
+		if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(r.FormValue("password"))) != nil {
+			locked, err := recordActionLinkPasswordFailure(claims)
+			if err != nil {
+				renderActionPage(w, http.StatusInternalServerError, actionPage{Title: "Something went wrong",
+					Error: "Failed to check the password"})
+				return
+			}
+			if locked {
+				LogAudit(r, "action_link.lock", "access_request", claims.RequestID, "", nil,
+					map[string]interface{}{"channel": claims.Channel, "failed_attempts": actionLinkMaxPasswordAttempts})
+				renderActionPage(w, http.StatusGone, actionPage{Title: "Link unavailable", Error: errLinkLocked.Error()})
+				return
+			}
+			page.ShowForm = true
+			page.Error = "Incorrect password"
+			renderActionPage(w, http.StatusUnauthorized, page)
+			return
+		}
+	}
+
+	result, err := database.DB.Exec("UPDATE action_links SET used_at = ? WHERE id = ? AND used_at IS NULL",
+		time.Now(), claims.ID)
+	if err != nil {
+		renderActionPage(w, http.StatusInternalServerError, actionPage{Title: "Something went wrong",
+			Error: "Failed to use the link"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		renderActionPage(w, http.StatusGone, actionPage{Title: "Link unavailable", Error: errLinkUsed.Error()})
+		return
+	}
+
+	via := claims.Channel + " action link"
+	capture := newResponseCapture()
+	if claims.Action == LinkApprove {
+		approveAccessRequest(capture, r, claims.RequestID, models.ApproveAccessRequest{}, via)
+	} else {
+		rejectAccessRequest(capture, r, claims.RequestID, models.RejectAccessRequest{Reason: r.FormValue("reason")}, via)
+	}
+
+	page.Title = "Request " + map[string]string{LinkApprove: "approved", LinkReject: "rejected"}[claims.Action]
+	if capture.status >= 300 {
+		page.Title = "Could not " + claims.Action + " request"
+		page.Error = capture.message()
+	} else {
+		page.Message = capture.message()
+	}
+	renderActionPage(w, capture.status, page)
+}
+
+// recordActionLinkPasswordFailure counts a wrong password against a link. Once the
+// approver's links for the request have seen actionLinkMaxPasswordAttempts failures
+// between them, all of them are used up so the password cannot be guessed further.
+func recordActionLinkPasswordFailure(claims *actionClaims) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE action_links SET failed_attempts = failed_attempts + 1 WHERE id = ?", claims.ID); err != nil {
+		return false, err
+	}
+	var failures int
+	err = tx.QueryRow("SELECT COALESCE(SUM(failed_attempts), 0) FROM action_links WHERE request_id = ? AND approver_id = ?",
+		claims.RequestID, claims.ApproverID).Scan(&failures)
+	if err != nil {
+		return false, err
+	}
+	locked := failures >= actionLinkMaxPasswordAttempts
+	if locked {
+		_, err := tx.Exec("UPDATE action_links SET used_at = ? WHERE request_id = ? AND approver_id = ? AND used_at IS NULL",
+			time.Now(), claims.RequestID, claims.ApproverID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return locked, tx.Commit()
+}
+
+// issueActionLinks creates approve and reject links for one approver on one channel.
+// Links are signed, so none are issued without SIGNING_KEY.
+func issueActionLinks(requestID int, approverID int, channel string) (string, string, time.Time, error) {
+	expiresAt := time.Now().Add(ActionLinkTTL)
+	if !signingKeySet() {
+		return "", "", expiresAt, errNoSigningKey
+	}
+
+	var urls []string
+	for _, action := range []string{LinkApprove, LinkReject} {
+		id, err := generateWebhookSecret()
+		if err != nil {
+			return "", "", expiresAt, err
+		}
+		claims := actionClaims{
+			ID:         id,
+			RequestID:  requestID,
+			ApproverID: approverID,
+			Action:     action,
+			Channel:    channel,
+			ExpiresAt:  expiresAt.Unix(),
+		}
+		_, err = database.DB.Exec(`
+			INSERT INTO action_links (id, request_id, approver_id, action, channel, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?)`, id, requestID, approverID, action, channel, expiresAt)
+		if err != nil {
+			return "", "", expiresAt, err
+		}
+
+		payload, _ := json.Marshal(claims)
+		token := base64.RawURLEncoding.EncodeToString(payload) + "." + signPayload(payload)
+		urls = append(urls, strings.TrimRight(PublicURL, "/")+"/actions/"+token)
+	}
+	return urls[0], urls[1], expiresAt, nil
+}
+
+// verifyActionLink checks a token's signature and expiry, that it matches the link
+// issued under its ID and that it has not been used
+func verifyActionLink(token string) (*actionClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errLinkInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || !verifySignature(payload, signature) {
+		return nil, errLinkInvalid
+	}
+
+	var claims actionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errLinkInvalid
+	}
+	if claims.Action != LinkApprove && claims.Action != LinkReject {
+		return nil, errLinkInvalid
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, errLinkExpired
+	}
+
+	var link actionClaims
+	var expiresAt time.Time
+	var usedAt *time.Time
+	err = database.DB.QueryRow(`
+		SELECT id, request_id, approver_id, action, channel, expires_at, used_at
+		FROM action_links WHERE id = ?`, claims.ID).
+		Scan(&link.ID, &link.RequestID, &link.ApproverID, &link.Action, &link.Channel, &expiresAt, &usedAt)
+	if err != nil {
+		return nil, errLinkInvalid
+	}
+	link.ExpiresAt = expiresAt.Unix()
+	if link != claims {
+		return nil, errLinkInvalid
+	}
+	if usedAt != nil {
+		return nil, errLinkUsed
+	}
+	return &claims, nil
+}
+
+// actionLinkPage describes the request behind a link
+func actionLinkPage(claims *actionClaims) (actionPage, error) {
+	notice, err := loadRequestNotice(claims.RequestID)
+	if err != nil {
+		return actionPage{}, errors.New("the request no longer exists")
+	}
+
+	page := actionPage{
+		Title:       "Confirm: " + claims.Action + " access request",
+		Action:      claims.Action,
+		Requester:   notice.Requester,
+		AccessLevel: notice.AccessLevel,
+		TargetName:  notice.TargetName,
+		Reason:      notice.Reason,
+	}
+	database.DB.QueryRow("SELECT email FROM users WHERE id = ?", claims.ApproverID).Scan(&page.Approver)
+	if notice.TargetType == "tool" {
+		database.DB.QueryRow(`
+			SELECT t.high_risk FROM tools t
+			JOIN access_requests ar ON ar.target_id = t.id
+			WHERE ar.id = ?`, claims.RequestID).Scan(&page.HighRisk)
+	}
+	return page, nil
+}
+
+func renderActionPage(w http.ResponseWriter, status int, page actionPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	actionPageTemplate.Execute(w, page)
+}
+
+// requestLinkNotice renders a new-request notification for one approver, with action
+// links only on channels that reach the approver alone. Anyone reading the server log
+// or a shared chat channel could otherwise act as the approver, and the inbox needs no
+// links because its reader is already signed in.
+func requestLinkNotice(notice notify.RequestNotice, rcpt notify.Recipient, ch notify.Channel) (string, string, error) {
+	if notify.IsPrivate(ch) {
+		approveURL, rejectURL, expiresAt, err := issueActionLinks(notice.RequestID, rcpt.UserID, ch.Name())
+		if err == nil {
+			notice.ApproveURL, notice.RejectURL, notice.LinksExpireAt = approveURL, rejectURL, &expiresAt
+		}
+	}
+	return notify.Render(notify.EventRequestCreated, notice)
+}
+
+// responseCapture records what a handler wrote so it can be shown on a page instead
+type responseCapture struct {
+	header http.Header
+	status int
+	body   strings.Builder
+}
+
+func newResponseCapture() *responseCapture {
+	return &responseCapture{header: http.Header{}, status: http.StatusOK}
+}
+
+func (c *responseCapture) Header() http.Header { return c.header }
+
+func (c *responseCapture) Write(b []byte) (int, error) { return c.body.Write(b) }
+
+func (c *responseCapture) WriteHeader(status int) { c.status = status }
+
+// message returns the "message" field of a JSON response, or the plain text of an error
+func (c *responseCapture) message() string {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(c.body.String()), &resp) == nil && resp.Message != "" {
+		return resp.Message
+	}
+	return strings.TrimSpace(c.body.String())
+}
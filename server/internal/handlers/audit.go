@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync"
+	"time"
 
+	"gatekeepr/internal/auditchain"
 	"gatekeepr/internal/auth"
 	"gatekeepr/internal/database"
 	authMiddleware "gatekeepr/internal/middleware"
@@ -30,60 +36,19 @@ func ListAuditLogs(w http.ResponseWriter, r *http.Request) {
 	baseQuery := `
 		SELECT al.id, al.action, al.action_category, al.actor_id, 
 			   al.target_type, al.target_id, al.target_name, al.details,
-			   al.old_value, al.new_value, al.ip_address, al.user_agent, al.created_at,
+			   al.old_value, al.new_value, al.ip_address, al.user_agent, al.severity, al.created_at,
+			   al.request_id,
 			   COALESCE(u.email, 'System') as actor_email
 		FROM audit_logs al
 		LEFT JOIN users u ON al.actor_id = u.id
 		WHERE 1=1`
 	countQuery := `SELECT COUNT(*) FROM audit_logs al WHERE 1=1`
 
-	args := []interface{}{}
-	countArgs := []interface{}{}
-
 	// Apply filters
-	if actorID := query.Get("actor_id"); actorID != "" {
-		baseQuery += " AND al.actor_id = ?"
-		countQuery += " AND al.actor_id = ?"
-		args = append(args, actorID)
-		countArgs = append(countArgs, actorID)
-	}
-	if action := query.Get("action"); action != "" {
-		baseQuery += " AND al.action LIKE ?"
-		countQuery += " AND al.action LIKE ?"
-		pattern := "%" + action + "%"
-		args = append(args, pattern)
-		countArgs = append(countArgs, pattern)
-	}
-	if actionCategory := query.Get("action_category"); actionCategory != "" {
-		baseQuery += " AND al.action_category = ?"
-		countQuery += " AND al.action_category = ?"
-		args = append(args, actionCategory)
-		countArgs = append(countArgs, actionCategory)
-	}
-	if targetType := query.Get("target_type"); targetType != "" {
-		baseQuery += " AND al.target_type = ?"
-		countQuery += " AND al.target_type = ?"
-		args = append(args, targetType)
-		countArgs = append(countArgs, targetType)
-	}
-	if targetID := query.Get("target_id"); targetID != "" {
-		baseQuery += " AND al.target_id = ?"
-		countQuery += " AND al.target_id = ?"
-		args = append(args, targetID)
-		countArgs = append(countArgs, targetID)
-	}
-	if dateFrom := query.Get("date_from"); dateFrom != "" {
-		baseQuery += " AND DATE(al.created_at) >= ?"
-		countQuery += " AND DATE(al.created_at) >= ?"
-		args = append(args, dateFrom)
-		countArgs = append(countArgs, dateFrom)
-	}
-	if dateTo := query.Get("date_to"); dateTo != "" {
-		baseQuery += " AND DATE(al.created_at) <= ?"
-		countQuery += " AND DATE(al.created_at) <= ?"
-		args = append(args, dateTo)
-		countArgs = append(countArgs, dateTo)
-	}
+	where, args := auditLogFilter(query)
+	baseQuery += where
+	countQuery += where
+	countArgs := append([]interface{}{}, args...)
 
 	// Get total count
 	var total int
@@ -114,7 +79,8 @@ func ListAuditLogs(w http.ResponseWriter, r *http.Request) {
 		var log models.AuditLog
 		if err := rows.Scan(&log.ID, &log.Action, &log.ActionCategory, &log.ActorID,
 			&log.TargetType, &log.TargetID, &log.TargetName, &log.Details,
-			&log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
+			&log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.Severity, &log.CreatedAt,
+			&log.RequestID,
 			&log.ActorEmail); err != nil {
 			http.Error(w, "Failed to scan log", http.StatusInternalServerError)
 			return
@@ -136,6 +102,51 @@ func ListAuditLogs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// auditLogFilter turns the filters of the audit log list into SQL conditions on al.
+// The listing and the export share it so an export holds exactly what was listed.
+func auditLogFilter(query url.Values) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+
+	if actorID := query.Get("actor_id"); actorID != "" {
+		where += " AND al.actor_id = ?"
+		args = append(args, actorID)
+	}
+	if action := query.Get("action"); action != "" {
+		where += " AND al.action LIKE ?"
+		args = append(args, "%"+action+"%")
+	}
+	if actionCategory := query.Get("action_category"); actionCategory != "" {
+		where += " AND al.action_category = ?"
+		args = append(args, actionCategory)
+	}
+	if severity := query.Get("severity"); severity != "" {
+		where += " AND al.severity = ?"
+		args = append(args, severity)
+	}
+	if targetType := query.Get("target_type"); targetType != "" {
+		where += " AND al.target_type = ?"
+		args = append(args, targetType)
+	}
+	if targetID := query.Get("target_id"); targetID != "" {
+		where += " AND al.target_id = ?"
+		args = append(args, targetID)
+	}
+	if requestID := query.Get("request_id"); requestID != "" {
+		where += " AND al.request_id = ?"
+		args = append(args, requestID)
+	}
+	if dateFrom := query.Get("date_from"); dateFrom != "" {
+		where += " AND DATE(al.created_at) >= ?"
+		args = append(args, dateFrom)
+	}
+	if dateTo := query.Get("date_to"); dateTo != "" {
+		where += " AND DATE(al.created_at) <= ?"
+		args = append(args, dateTo)
+	}
+	return where, args
+}
+
 // GetAuditLogCategories returns all unique action categories
 func GetAuditLogCategories(w http.ResponseWriter, r *http.Request) {
 	rows, err := database.DB.Query(`SELECT DISTINCT action_category FROM audit_logs WHERE action_category IS NOT NULL ORDER BY action_category`)
@@ -159,95 +170,202 @@ func GetAuditLogCategories(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(categories)
 }
 
-// ExportAuditLogs exports audit logs as JSON
-func ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
-	// Re-use the list logic but without pagination
-	query := r.URL.Query()
+// auditRecord is a single audit log entry before it is written
+type auditRecord struct {
+	Action     string
+	ActorID    *int
+	TargetType string
+	TargetID   int
+	TargetName string
+	Details    *string
+	OldValue   interface{}
+	NewValue   interface{}
+	IPAddress  *string
+	UserAgent  *string
+	Severity   string
+	RequestID  *string
+}
 
-	baseQuery := `
-		SELECT al.id, al.action, al.action_category, al.actor_id, 
-			   al.target_type, al.target_id, al.target_name, al.details,
-			   al.ip_address, al.user_agent, al.created_at,
-			   COALESCE(u.email, 'System') as actor_email
-		FROM audit_logs al
-		LEFT JOIN users u ON al.actor_id = u.id
-		WHERE 1=1`
+// LogAudit is a helper function to create audit log entries
+func LogAudit(r *http.Request, action string, targetType string, targetID int, targetName string, oldValue interface{}, newValue interface{}) {
+	writeAuditLog(requestAuditRecord(r, action, targetType, targetID, targetName, oldValue, newValue))
+}
 
-	args := []interface{}{}
+// LogCriticalAudit records an entry flagged as critical so it stands out in audit
+// views, such as a break-glass grant
+func LogCriticalAudit(r *http.Request, action string, targetType string, targetID int, targetName string, oldValue interface{}, newValue interface{}) {
+	rec := requestAuditRecord(r, action, targetType, targetID, targetName, oldValue, newValue)
+	rec.Severity = "critical"
+	writeAuditLog(rec)
+}
 
-	if dateFrom := query.Get("date_from"); dateFrom != "" {
-		baseQuery += " AND DATE(al.created_at) >= ?"
-		args = append(args, dateFrom)
+// LogAuditWithDetails records an action along with a human-readable explanation
+func LogAuditWithDetails(r *http.Request, action string, targetType string, targetID int, targetName string, details string, oldValue interface{}, newValue interface{}) {
+	rec := requestAuditRecord(r, action, targetType, targetID, targetName, oldValue, newValue)
+	rec.Details = &details
+	writeAuditLog(rec)
+}
+
+// LogSystemAudit records an action taken by gatekeepr itself rather than by a user,
+// such as an auto-approval or a background job. The actor is stored as NULL.
+func LogSystemAudit(action string, targetType string, targetID int, targetName string, details string, oldValue interface{}, newValue interface{}) {
+	rec := auditRecord{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		OldValue:   oldValue,
+		NewValue:   newValue,
 	}
-	if dateTo := query.Get("date_to"); dateTo != "" {
-		baseQuery += " AND DATE(al.created_at) <= ?"
-		args = append(args, dateTo)
+	if details != "" {
+		rec.Details = &details
 	}
-	if actionCategory := query.Get("action_category"); actionCategory != "" {
-		baseQuery += " AND al.action_category = ?"
-		args = append(args, actionCategory)
+	writeAuditLog(rec)
+}
+
+// LogRequestSystemAudit records an action gatekeepr takes on its own while serving a
+// request, such as an auto-approval. The actor is stored as NULL but the entry keeps the
+// request ID so it can be traced back to the call that caused it.
+func LogRequestSystemAudit(r *http.Request, action string, targetType string, targetID int, targetName string, details string, oldValue interface{}, newValue interface{}) {
+	rec := auditRecord{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		RequestID:  requestID(r),
+	}
+	if details != "" {
+		rec.Details = &details
 	}
+	writeAuditLog(rec)
+}
 
-	baseQuery += " ORDER BY al.created_at DESC LIMIT 10000"
+func requestAuditRecord(r *http.Request, action string, targetType string, targetID int, targetName string, oldValue interface{}, newValue interface{}) auditRecord {
+	actorID := GetActorID(r)
+	ipAddress := r.RemoteAddr
+	userAgent := r.UserAgent()
 
-	rows, err := database.DB.Query(baseQuery, args...)
-	if err != nil {
-		http.Error(w, "Failed to fetch audit logs", http.StatusInternalServerError)
-		return
+	return auditRecord{
+		Action:     action,
+		ActorID:    &actorID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		IPAddress:  &ipAddress,
+		UserAgent:  &userAgent,
+		RequestID:  requestID(r),
 	}
-	defer rows.Close()
+}
 
-	var logs []models.AuditLog
-	for rows.Next() {
-		var log models.AuditLog
-		if err := rows.Scan(&log.ID, &log.Action, &log.ActionCategory, &log.ActorID,
-			&log.TargetType, &log.TargetID, &log.TargetName, &log.Details,
-			&log.IPAddress, &log.UserAgent, &log.CreatedAt,
-			&log.ActorEmail); err != nil {
-			continue
-		}
-		logs = append(logs, log)
+// requestID returns the ID the RequestID middleware gave the request, or nil outside one
+func requestID(r *http.Request) *string {
+	id := authMiddleware.GetRequestID(r)
+	if id == "" {
+		return nil
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Disposition", "attachment; filename=audit_logs_export.json")
-	json.NewEncoder(w).Encode(logs)
+	return &id
 }
 
-// LogAudit is a helper function to create audit log entries
-func LogAudit(r *http.Request, action string, targetType string, targetID int, targetName string, oldValue interface{}, newValue interface{}) {
-	actorID := GetActorID(r)
-
+func writeAuditLog(rec auditRecord) {
 	var oldJSON, newJSON *string
-	if oldValue != nil {
-		if data, err := json.Marshal(oldValue); err == nil {
+	if rec.OldValue != nil {
+		if data, err := json.Marshal(rec.OldValue); err == nil {
 			s := string(data)
 			oldJSON = &s
 		}
 	}
-	if newValue != nil {
-		if data, err := json.Marshal(newValue); err == nil {
+	if rec.NewValue != nil {
+		if data, err := json.Marshal(rec.NewValue); err == nil {
 			s := string(data)
 			newJSON = &s
 		}
 	}
 
 	// Extract action category from action (e.g., "role.create" -> "role")
-	actionCategory := targetType
-	for i, c := range action {
+	actionCategory := rec.TargetType
+	for i, c := range rec.Action {
 		if c == '.' {
-			actionCategory = action[:i]
+			actionCategory = rec.Action[:i]
 			break
 		}
 	}
 
-	ipAddress := r.RemoteAddr
-	userAgent := r.UserAgent()
+	if rec.Severity == "" {
+		rec.Severity = "info"
+	}
+
+	entry := models.AuditLog{
+		Action:         rec.Action,
+		ActionCategory: actionCategory,
+		ActorID:        rec.ActorID,
+		TargetType:     &rec.TargetType,
+		TargetID:       &rec.TargetID,
+		TargetName:     &rec.TargetName,
+		Details:        rec.Details,
+		OldValue:       oldJSON,
+		NewValue:       newJSON,
+		IPAddress:      rec.IPAddress,
+		UserAgent:      rec.UserAgent,
+		Severity:       rec.Severity,
+		CreatedAt:      time.Now().UTC(),
+		RequestID:      rec.RequestID,
+	}
+
+	if err := appendAuditEntry(&entry); err != nil {
+		log.Printf("Failed to write audit log %s: %v", rec.Action, err)
+		return
+	}
+	enqueueWebhookEvent(entry.ID, rec, oldJSON, newJSON)
+	forwardAuditEntry(entry)
+}
+
+// auditChainMu serializes audit writes so each entry links to the one written before it
+var auditChainMu sync.Mutex
 
-	database.DB.Exec(`
-		INSERT INTO audit_logs (action, action_category, actor_id, target_type, target_id, target_name, old_value, new_value, ip_address, user_agent)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		action, actionCategory, actorID, targetType, targetID, targetName, oldJSON, newJSON, ipAddress, userAgent)
+// appendAuditEntry writes an entry at the end of the hash chain and fills in its ID
+// and hashes
+func appendAuditEntry(e *models.AuditLog) error {
+	auditChainMu.Lock()
+	defer auditChainMu.Unlock()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var prevHash *string
+	err = tx.QueryRow("SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	prev := ""
+	if prevHash != nil {
+		prev = *prevHash
+	}
+	hash := auditchain.Hash(prev, *e)
+
+	result, err := tx.Exec(`
+		INSERT INTO audit_logs (action, action_category, actor_id, target_type, target_id, target_name, details, old_value, new_value, ip_address, user_agent, severity, created_at, prev_hash, hash, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Action, e.ActionCategory, e.ActorID, e.TargetType, e.TargetID, e.TargetName, e.Details,
+		e.OldValue, e.NewValue, e.IPAddress, e.UserAgent, e.Severity, e.CreatedAt, prev, hash, e.RequestID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	id, _ := result.LastInsertId()
+	e.ID = int(id)
+	e.PrevHash = &prev
+	e.Hash = &hash
+	return nil
 }
 
 // GetActorID extracts the current user ID from the request context
@@ -279,8 +397,12 @@ func CanGrantAccess(r *http.Request) bool {
 
 // CanApproveRequests checks if the current user can approve access requests
 func CanApproveRequests(r *http.Request) bool {
-	actorID := GetActorID(r)
-	if actorID == 0 {
+	return userCanApproveRequests(GetActorID(r))
+}
+
+// userCanApproveRequests checks if a user holds a role that approves access requests
+func userCanApproveRequests(userID int) bool {
+	if userID == 0 {
 		return false
 	}
 
@@ -290,7 +412,7 @@ func CanApproveRequests(r *http.Request) bool {
 			SELECT 1 FROM user_roles ur
 			JOIN roles r ON ur.role_id = r.id
 			WHERE ur.user_id = ? AND r.can_approve_requests = 1
-		)`, actorID).Scan(&canApprove)
+		)`, userID).Scan(&canApprove)
 
 	return canApprove
 }
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gatekeepr/internal/auditchain"
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+)
+
+// VerifyAuditChain walks the whole audit log and its checkpoints and reports the first
+// broken link, if any
+func VerifyAuditChain(w http.ResponseWriter, r *http.Request) {
+	report, err := verifyAuditChain(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to verify audit log", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "audit.verify", "audit_log", report.LastID, "", nil, report)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ListAuditCheckpoints returns the signed checkpoints, newest first
+func ListAuditCheckpoints(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT id, last_log_id, hash, entry_count, signature, created_at
+		FROM audit_checkpoints ORDER BY id DESC`)
+	if err != nil {
+		http.Error(w, "Failed to fetch checkpoints", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	checkpoints := []models.AuditCheckpoint{}
+	for rows.Next() {
+		cp, err := scanAuditCheckpoint(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan checkpoint", http.StatusInternalServerError)
+			return
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(checkpoints)
+}
+
+// CreateAuditCheckpoint signs the current chain head straight away, for instance right
+// before an export
+func CreateAuditCheckpoint(w http.ResponseWriter, r *http.Request) {
+	cp, err := createAuditCheckpoint()
+	if errors.Is(err, errNoSigningKey) {
+		http.Error(w, "Set SIGNING_KEY to sign audit checkpoints", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create checkpoint", http.StatusInternalServerError)
+		return
+	}
+	if cp == nil {
+		http.Error(w, "No new audit entries since the last checkpoint", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cp)
+}
+
+// StartAuditCheckpointWorker signs the chain head periodically. It does not start
+// without a signing key of its own.
+func StartAuditCheckpointWorker(ctx context.Context, interval time.Duration) {
+	if !signingKeySet() {
+		log.Printf("WARNING: SIGNING_KEY is not set, so audit checkpoints are not being created; without them a rewritten audit chain cannot be detected")
+		return
+	}
+	startPeriodicJob(ctx, "audit checkpoint", interval, func(now time.Time) error {
+		_, err := createAuditCheckpoint()
+		return err
+	})
+}
+
+// createAuditCheckpoint records a signed checkpoint of the newest chained entry. It
+// returns nil when nothing was written since the last checkpoint.
+func createAuditCheckpoint() (*models.AuditCheckpoint, error) {
+	if !signingKeySet() {
+		return nil, errNoSigningKey
+	}
+
+	auditChainMu.Lock()
+	defer auditChainMu.Unlock()
+
+	var cp models.AuditCheckpoint
+	err := database.DB.QueryRow(`
+		SELECT id, hash, (SELECT COUNT(*) FROM audit_logs WHERE hash IS NOT NULL)
+		FROM audit_logs WHERE hash IS NOT NULL
+		ORDER BY id DESC LIMIT 1`).Scan(&cp.LastLogID, &cp.Hash, &cp.EntryCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int
+	database.DB.QueryRow("SELECT COALESCE(MAX(last_log_id), 0) FROM audit_checkpoints").Scan(&latest)
+	if latest >= cp.LastLogID {
+		return nil, nil
+	}
+
+	cp.Signature = auditchain.SignCheckpoint(SigningKey, cp.LastLogID, cp.Hash)
+	cp.CreatedAt = time.Now().UTC()
+	result, err := database.DB.Exec(`
+		INSERT INTO audit_checkpoints (last_log_id, hash, entry_count, signature, created_at)
+		VALUES (?, ?, ?, ?, ?)`, cp.LastLogID, cp.Hash, cp.EntryCount, cp.Signature, cp.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := result.LastInsertId()
+	cp.ID = int(id)
+	return &cp, nil
+}
+
+// verifyAuditChain checks every entry in order, then every checkpoint. Archived
+// entries are walked through by the hashes they left behind.
+func verifyAuditChain(ctx context.Context) (auditchain.Report, error) {
+	verifier := auditchain.NewVerifier(true)
+
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT id, action, action_category, actor_id, target_type, target_id, target_name, details,
+			   old_value, new_value, ip_address, user_agent, severity, created_at, prev_hash, hash, request_id
+		FROM audit_logs ORDER BY id`)
+	if err != nil {
+		return auditchain.Report{}, err
+	}
+	defer rows.Close()
+
+	archivedRows, err := database.DB.QueryContext(ctx, "SELECT id, prev_hash, hash FROM audit_archived_entries ORDER BY id")
+	if err != nil {
+		return auditchain.Report{}, err
+	}
+	defer archivedRows.Close()
+
+	// Both lists are in ID order; merge them so the chain is walked in one pass
+	var archived *models.AuditLog
+	nextArchived := func() error {
+		archived = nil
+		if !archivedRows.Next() {
+			return archivedRows.Err()
+		}
+		var a models.AuditLog
+		if err := archivedRows.Scan(&a.ID, &a.PrevHash, &a.Hash); err != nil {
+			return err
+		}
+		archived = &a
+		return nil
+	}
+	addArchived := func(a *models.AuditLog) bool {
+		if a.Hash == nil {
+			return verifier.Add(*a)
+		}
+		prev := ""
+		if a.PrevHash != nil {
+			prev = *a.PrevHash
+		}
+		return verifier.AddArchived(a.ID, prev, *a.Hash)
+	}
+	if err := nextArchived(); err != nil {
+		return auditchain.Report{}, err
+	}
+
+	for rows.Next() {
+		var e models.AuditLog
+		if err := rows.Scan(&e.ID, &e.Action, &e.ActionCategory, &e.ActorID, &e.TargetType, &e.TargetID,
+			&e.TargetName, &e.Details, &e.OldValue, &e.NewValue, &e.IPAddress, &e.UserAgent, &e.Severity,
+			&e.CreatedAt, &e.PrevHash, &e.Hash, &e.RequestID); err != nil {
+			return auditchain.Report{}, err
+		}
+		for archived != nil && archived.ID < e.ID {
+			if !addArchived(archived) {
+				return verifier.Report(), nil
+			}
+			if err := nextArchived(); err != nil {
+				return auditchain.Report{}, err
+			}
+		}
+		if !verifier.Add(e) {
+			return verifier.Report(), nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return auditchain.Report{}, err
+	}
+	for archived != nil {
+		if !addArchived(archived) {
+			return verifier.Report(), nil
+		}
+		if err := nextArchived(); err != nil {
+			return auditchain.Report{}, err
+		}
+	}
+	rows.Close()
+	archivedRows.Close()
+
+	cpRows, err := database.DB.QueryContext(ctx, `
+		SELECT id, last_log_id, hash, entry_count, signature, created_at
+		FROM audit_checkpoints ORDER BY id`)
+	if err != nil {
+		return auditchain.Report{}, err
+	}
+	defer cpRows.Close()
+
+	for cpRows.Next() {
+		cp, err := scanAuditCheckpoint(cpRows)
+		if err != nil {
+			return auditchain.Report{}, err
+		}
+		if !verifier.AddCheckpoint(SigningKey, cp) {
+			break
+		}
+	}
+	return verifier.Report(), nil
+}
+
+func scanAuditCheckpoint(s rowScanner) (models.AuditCheckpoint, error) {
+	var cp models.AuditCheckpoint
+	err := s.Scan(&cp.ID, &cp.LastLogID, &cp.Hash, &cp.EntryCount, &cp.Signature, &cp.CreatedAt)
+	return cp, err
+}
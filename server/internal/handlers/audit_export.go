@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+)
+
+// auditExportBatch is how many entries an export reads per query. Reading in short
+// batches keeps the export from holding a read lock that would block new audit entries.
+const auditExportBatch = 1000
+
+// auditExportWriter writes exported entries in one format
+type auditExportWriter interface {
+	begin() error
+	write(e models.AuditLog) error
+	end() error
+}
+
+// auditExportFormats maps the format parameter to its content type, file extension
+// and writer
+var auditExportFormats = map[string]struct {
+	contentType string
+	extension   string
+	writer      func(w io.Writer) auditExportWriter
+}{
+	"json":   {"application/json", "json", func(w io.Writer) auditExportWriter { return &jsonArrayExport{w: w} }},
+	"ndjson": {"application/x-ndjson", "ndjson", func(w io.Writer) auditExportWriter { return &ndjsonExport{enc: json.NewEncoder(w)} }},
+	"csv":    {"text/csv", "csv", func(w io.Writer) auditExportWriter { return &csvExport{w: csv.NewWriter(w)} }},
+	"cef":    {"text/plain", "cef", func(w io.Writer) auditExportWriter { return &cefExport{w: w} }},
+}
+
+// ExportAuditLogs streams every audit entry matching the list filters, oldest first,
+// as JSON (the default), NDJSON, CSV or ArcSight CEF chosen with ?format=. Entries
+// carry their chain hashes so JSON exports can be checked offline with audit-verify.
+// The export itself is recorded in the audit log with its filters.
+func ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	f, ok := auditExportFormats[format]
+	if !ok {
+		http.Error(w, "format must be one of json, ndjson, csv or cef", http.StatusBadRequest)
+		return
+	}
+
+	where, args := auditLogFilter(query)
+
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=audit_logs_export."+f.extension)
+	flusher, _ := w.(http.Flusher)
+
+	out := f.writer(w)
+	exported := 0
+	err := out.begin()
+	for lastID := 0; err == nil; {
+		var batch []models.AuditLog
+		batch, err = loadAuditExportBatch(where, args, lastID)
+		if err != nil || len(batch) == 0 {
+			break
+		}
+		for _, e := range batch {
+			if err = out.write(e); err != nil {
+				break
+			}
+		}
+		exported += len(batch)
+		lastID = batch[len(batch)-1].ID
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if r.Context().Err() != nil {
+			err = r.Context().Err()
+		}
+	}
+	if err == nil {
+		err = out.end()
+	}
+
+	filters := map[string]string{}
+	for key := range query {
+		filters[key] = query.Get(key)
+	}
+	details := fmt.Sprintf("Exported %d entries as %s", exported, format)
+	if err != nil {
+		details = fmt.Sprintf("Export as %s stopped after %d entries: %v", format, exported, err)
+	}
+	LogAuditWithDetails(r, "audit.export", "audit_log", 0, format, details, nil, map[string]interface{}{
+		"format":   format,
+		"filters":  filters,
+		"exported": exported,
+	})
+}
+
+func loadAuditExportBatch(where string, args []interface{}, afterID int) ([]models.AuditLog, error) {
+	batchArgs := append([]interface{}{afterID}, args...)
+	batchArgs = append(batchArgs, auditExportBatch)
+
+	rows, err := database.DB.Query(`
+		SELECT al.id, al.action, al.action_category, al.actor_id,
+			   al.target_type, al.target_id, al.target_name, al.details,
+			   al.old_value, al.new_value, al.ip_address, al.user_agent, al.severity, al.created_at,
+			   al.prev_hash, al.hash, al.request_id,
+			   COALESCE(u.email, 'System') as actor_email
+		FROM audit_logs al
+		LEFT JOIN users u ON al.actor_id = u.id
+		WHERE al.id > ?`+where+`
+		ORDER BY al.id
+		LIMIT ?`, batchArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []models.AuditLog
+	for rows.Next() {
+		var e models.AuditLog
+		if err := rows.Scan(&e.ID, &e.Action, &e.ActionCategory, &e.ActorID,
+			&e.TargetType, &e.TargetID, &e.TargetName, &e.Details,
+			&e.OldValue, &e.NewValue, &e.IPAddress, &e.UserAgent, &e.Severity, &e.CreatedAt,
+			&e.PrevHash, &e.Hash, &e.RequestID,
+			&e.ActorEmail); err != nil {
+			return nil, err
+		}
+		batch = append(batch, e)
+	}
+	return batch, rows.Err()
+}
+
+// jsonArrayExport writes one JSON array, element by element
+type jsonArrayExport struct {
+	w     io.Writer
+	count int
+}
+
+func (x *jsonArrayExport) begin() error {
+	_, err := io.WriteString(x.w, "[")
+	return err
+}
+
+func (x *jsonArrayExport) write(e models.AuditLog) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if x.count > 0 {
+		if _, err := io.WriteString(x.w, ","); err != nil {
+			return err
+		}
+	}
+	x.count++
+	_, err = x.w.Write(data)
+	return err
+}
+
+func (x *jsonArrayExport) end() error {
+	_, err := io.WriteString(x.w, "]\n")
+	return err
+}
+
+// ndjsonExport writes one JSON object per line
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func (x *ndjsonExport) begin() error { return nil }
+
+func (x *ndjsonExport) write(e models.AuditLog) error { return x.enc.Encode(e) }
+
+func (x *ndjsonExport) end() error { return nil }
+
+// csvExport writes a header row and one row per entry
+type csvExport struct {
+	w *csv.Writer
+}
+
+func (x *csvExport) begin() error {
+	return x.w.Write([]string{
+		"id", "created_at", "action", "action_category", "severity", "actor_id", "actor_email",
+		"target_type", "target_id", "target_name", "details", "old_value", "new_value",
+		"ip_address", "user_agent", "prev_hash", "hash", "request_id",
+	})
+}
+
+func (x *csvExport) write(e models.AuditLog) error {
+	err := x.w.Write([]string{
+		strconv.Itoa(e.ID), e.CreatedAt.UTC().Format(time.RFC3339Nano), e.Action, e.ActionCategory, e.Severity,
+		intString(e.ActorID), e.ActorEmail,
+		stringValue(e.TargetType), intString(e.TargetID), stringValue(e.TargetName), stringValue(e.Details),
+		stringValue(e.OldValue), stringValue(e.NewValue),
+		stringValue(e.IPAddress), stringValue(e.UserAgent), stringValue(e.PrevHash), stringValue(e.Hash),
+		stringValue(e.RequestID),
+	})
+	if err != nil {
+		return err
+	}
+	x.w.Flush()
+	return x.w.Error()
+}
+
+func (x *csvExport) end() error {
+	x.w.Flush()
+	return x.w.Error()
+}
+
+// cefExport writes ArcSight Common Event Format lines
+type cefExport struct {
+	w io.Writer
+}
+
+// cefSeverity maps audit severities onto CEF's 0-10 scale
+var cefSeverity = map[string]int{"info": 3, "warning": 6, "critical": 10}
+
+func (x *cefExport) begin() error { return nil }
+
+func (x *cefExport) write(e models.AuditLog) error {
+	severity, ok := cefSeverity[e.Severity]
+	if !ok {
+		severity = 5
+	}
+
+	ext := []string{
+		"rt=" + strconv.FormatInt(e.CreatedAt.UnixMilli(), 10),
+		"externalId=" + strconv.Itoa(e.ID),
+		"cat=" + cefExtension(e.ActionCategory),
+		"act=" + cefExtension(e.Action),
+	}
+	if e.ActorID != nil {
+		ext = append(ext, "suid="+strconv.Itoa(*e.ActorID))
+	}
+	ext = append(ext, "suser="+cefExtension(e.ActorEmail))
+	if e.IPAddress != nil {
+		ext = append(ext, "src="+cefExtension(remoteHost(*e.IPAddress)))
+	}
+	if e.UserAgent != nil {
+		ext = append(ext, "requestClientApplication="+cefExtension(*e.UserAgent))
+	}
+	if e.TargetType != nil {
+		ext = append(ext, "cs1Label=targetType", "cs1="+cefExtension(*e.TargetType))
+	}
+	if e.TargetName != nil && *e.TargetName != "" {
+		ext = append(ext, "cs2Label=targetName", "cs2="+cefExtension(*e.TargetName))
+	}
+	if e.OldValue != nil {
+		ext = append(ext, "cs3Label=oldValue", "cs3="+cefExtension(*e.OldValue))
+	}
+	if e.NewValue != nil {
+		ext = append(ext, "cs4Label=newValue", "cs4="+cefExtension(*e.NewValue))
+	}
+	if e.Hash != nil {
+		ext = append(ext, "cs5Label=hash", "cs5="+cefExtension(*e.Hash))
+	}
+	if e.RequestID != nil {
+		ext = append(ext, "cs6Label=requestId", "cs6="+cefExtension(*e.RequestID))
+	}
+	if e.TargetID != nil {
+		ext = append(ext, "cn1Label=targetId", "cn1="+strconv.Itoa(*e.TargetID))
+	}
+	if e.Details != nil {
+		ext = append(ext, "msg="+cefExtension(*e.Details))
+	}
+
+	_, err := fmt.Fprintf(x.w, "CEF:0|gatekeepr|gatekeepr|1.0|%s|%s|%d|%s\n",
+		cefHeader(e.Action), cefHeader(e.Action), severity, strings.Join(ext, " "))
+	return err
+}
+
+func (x *cefExport) end() error { return nil }
+
+// cefHeader escapes a CEF header field
+func cefHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+// cefExtension escapes a CEF extension value
+func cefExtension(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r", `\r`, "\n", `\n`).Replace(s)
+}
+
+// remoteHost strips the port from a recorded remote address
+func remoteHost(addr string) string {
+	if i := strings.LastIndex(addr, ":"); i > 0 && !strings.HasSuffix(addr, "]") {
+		return strings.Trim(addr[:i], "[]")
+	}
+	return addr
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func intString(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
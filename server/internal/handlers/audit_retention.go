@@ -0,0 +1,733 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/auditchain"
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AuditArchiveDir is where the archiver writes archive files
+var AuditArchiveDir = "./audit-archive"
+
+// auditArchiveBatch is the most entries written to one archive file
+const auditArchiveBatch = 10000
+
+// legalHoldMatch matches an audit entry al against a legal hold h
+const legalHoldMatch = `(h.action_category IS NULL OR h.action_category = al.action_category)
+	AND (h.actor_id IS NULL OR h.actor_id = al.actor_id)
+	AND (h.target_type IS NULL OR h.target_type = al.target_type)
+	AND (h.target_id IS NULL OR h.target_id = al.target_id)
+	AND (h.date_from IS NULL OR DATE(al.created_at) >= h.date_from)
+	AND (h.date_to IS NULL OR DATE(al.created_at) <= h.date_to)`
+
+// archivable excludes entries under an active legal hold, entries still waiting in a
+// sink's dead letters and the chain head, which the next entry links to
+const archivable = `
+	NOT EXISTS (SELECT 1 FROM audit_legal_holds h WHERE h.released_at IS NULL AND ` + legalHoldMatch + `)
+	AND NOT EXISTS (SELECT 1 FROM audit_sink_dead_letters d WHERE d.audit_log_id = al.id)
+	AND al.id < (SELECT MAX(id) FROM audit_logs)`
+
+const auditArchiveColumns = `id, file_name, sha256, entry_count,
+	(SELECT COUNT(*) FROM audit_archived_entries e WHERE e.archive_id = audit_archives.id),
+	first_log_id, last_log_id, first_created_at, last_created_at, created_at`
+
+// errAuditArchiveCorrupt reports an archive file that no longer matches what was
+// written
+var errAuditArchiveCorrupt = errors.New("archive file failed its integrity check")
+
+// ListAuditRetentionPolicies returns the retention of every action category that has
+// one
+func ListAuditRetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT id, action_category, retention_days, created_by, created_at, updated_at
+		FROM audit_retention_policies ORDER BY action_category`)
+	if err != nil {
+		http.Error(w, "Failed to fetch retention policies", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	policies := []models.AuditRetentionPolicy{}
+	for rows.Next() {
+		var p models.AuditRetentionPolicy
+		if err := rows.Scan(&p.ID, &p.ActionCategory, &p.RetentionDays, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			http.Error(w, "Failed to scan retention policy", http.StatusInternalServerError)
+			return
+		}
+		policies = append(policies, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// SetAuditRetentionPolicy sets how many days entries of an action category are kept
+// in the database. Category "*" sets the default for categories without a policy.
+func SetAuditRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	category := chi.URLParam(r, "category")
+
+	var req models.SetAuditRetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.RetentionDays < 1 {
+		http.Error(w, "retention_days must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	var old *models.SetAuditRetentionRequest
+	var days int
+	if err := database.DB.QueryRow("SELECT retention_days FROM audit_retention_policies WHERE action_category = ?",
+		category).Scan(&days); err == nil {
+		old = &models.SetAuditRetentionRequest{RetentionDays: days}
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO audit_retention_policies (action_category, retention_days, created_by)
+		VALUES (?, ?, ?)
+		ON CONFLICT(action_category) DO UPDATE SET retention_days = excluded.retention_days, updated_at = CURRENT_TIMESTAMP`,
+		category, req.RetentionDays, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to set retention policy", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "audit.retention.update", "audit_retention_policy", 0, category, old, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Retention policy saved"})
+}
+
+// DeleteAuditRetentionPolicy removes a category's retention; its entries then fall
+// under the default policy, or are kept forever without one
+func DeleteAuditRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	category := chi.URLParam(r, "category")
+
+	var days int
+	if err := database.DB.QueryRow("SELECT retention_days FROM audit_retention_policies WHERE action_category = ?",
+		category).Scan(&days); err != nil {
+		http.Error(w, "Retention policy not found", http.StatusNotFound)
+		return
+	}
+
+	if _, err := database.DB.Exec("DELETE FROM audit_retention_policies WHERE action_category = ?", category); err != nil {
+		http.Error(w, "Failed to delete retention policy", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "audit.retention.delete", "audit_retention_policy", 0, category,
+		models.SetAuditRetentionRequest{RetentionDays: days}, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Retention policy deleted"})
+}
+
+// ListAuditLegalHolds returns legal holds, newest first, with the number of entries
+// each one currently keeps in the database. ?active=true leaves out released holds.
+func ListAuditLegalHolds(w http.ResponseWriter, r *http.Request) {
+	query := `SELECT h.id, h.name, h.reason, h.action_category, h.actor_id, h.target_type, h.target_id,
+			h.date_from, h.date_to,
+			(SELECT COUNT(*) FROM audit_logs al WHERE ` + legalHoldMatch + `),
+			h.created_by, h.created_at, h.released_by, h.released_at
+		FROM audit_legal_holds h`
+	if r.URL.Query().Get("active") == "true" {
+		query += " WHERE h.released_at IS NULL"
+	}
+	query += " ORDER BY h.id DESC"
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		http.Error(w, "Failed to fetch legal holds", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	holds := []models.AuditLegalHold{}
+	for rows.Next() {
+		var h models.AuditLegalHold
+		if err := rows.Scan(&h.ID, &h.Name, &h.Reason, &h.ActionCategory, &h.ActorID, &h.TargetType, &h.TargetID,
+			&h.DateFrom, &h.DateTo, &h.HeldEntries, &h.CreatedBy, &h.CreatedAt, &h.ReleasedBy, &h.ReleasedAt); err != nil {
+			http.Error(w, "Failed to scan legal hold", http.StatusInternalServerError)
+			return
+		}
+		holds = append(holds, h)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+// CreateAuditLegalHold stops matching entries from being archived and deleted until
+// the hold is released. Criteria left out match any entry.
+func CreateAuditLegalHold(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAuditLegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	for _, d := range []*string{req.DateFrom, req.DateTo} {
+		if d == nil {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", *d); err != nil {
+			http.Error(w, "date_from and date_to must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO audit_legal_holds (name, reason, action_category, actor_id, target_type, target_id,
+			date_from, date_to, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Reason, req.ActionCategory, req.ActorID, req.TargetType, req.TargetID,
+		req.DateFrom, req.DateTo, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create legal hold", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "audit.legal_hold.create", "audit_legal_hold", int(id), req.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Legal hold placed"})
+}
+
+// ReleaseAuditLegalHold lifts a legal hold. Its entries become subject to retention
+// again on the archiver's next run.
+func ReleaseAuditLegalHold(w http.ResponseWriter, r *http.Request) {
+	holdID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var name string
+	var releasedAt *time.Time
+	err := database.DB.QueryRow("SELECT name, released_at FROM audit_legal_holds WHERE id = ?", holdID).
+		Scan(&name, &releasedAt)
+	if err != nil {
+		http.Error(w, "Legal hold not found", http.StatusNotFound)
+		return
+	}
+	if releasedAt != nil {
+		http.Error(w, "Legal hold is already released", http.StatusConflict)
+		return
+	}
+
+	before, after, err := execWithSnapshots("audit_legal_holds", holdID, `
+		UPDATE audit_legal_holds SET released_at = ?, released_by = ?
+		WHERE id = ? AND released_at IS NULL`, time.Now().UTC(), GetActorID(r), holdID)
+	if err != nil {
+		http.Error(w, "Failed to release legal hold", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "audit.legal_hold.release", "audit_legal_hold", holdID, name, before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Legal hold released"})
+}
+
+// ListAuditArchives returns the archive files, newest first. archived_count is how
+// many of an archive's entries are still out of the database.
+func ListAuditArchives(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query("SELECT " + auditArchiveColumns + " FROM audit_archives ORDER BY id DESC")
+	if err != nil {
+		http.Error(w, "Failed to fetch archives", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	archives := []models.AuditArchive{}
+	for rows.Next() {
+		a, err := scanAuditArchive(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan archive", http.StatusInternalServerError)
+			return
+		}
+		archives = append(archives, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(archives)
+}
+
+// RunAuditArchiver archives every entry past its retention straight away rather than
+// waiting for the next scheduled run
+func RunAuditArchiver(w http.ResponseWriter, r *http.Request) {
+	archives, entries, err := archiveAuditLogs(time.Now())
+	if err != nil {
+		http.Error(w, "Failed to archive audit logs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"archives": archives, "entries": entries})
+}
+
+// ListAuditArchiveEntries reads entries back from an archive file, with the same
+// filters as the audit log list plus from_id and to_id
+func ListAuditArchiveEntries(w http.ResponseWriter, r *http.Request) {
+	archiveID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	archive, err := scanAuditArchive(database.DB.QueryRow("SELECT "+auditArchiveColumns+" FROM audit_archives WHERE id = ?", archiveID))
+	if err != nil {
+		http.Error(w, "Archive not found", http.StatusNotFound)
+		return
+	}
+	entries, err := readAuditArchive(archive)
+	if errors.Is(err, errAuditArchiveCorrupt) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read archive", http.StatusInternalServerError)
+		return
+	}
+
+	matched := []models.AuditLog{}
+	for _, e := range entries {
+		if matchesAuditFilter(e, query) {
+			matched = append(matched, e)
+		}
+	}
+	total := len(matched)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PaginatedResponse{
+		Data:       matched[offset:end],
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (total + limit - 1) / limit,
+	})
+}
+
+// RestoreAuditArchive moves archived entries back into the database with their
+// original IDs and hashes, so the chain verifies as before. Restored entries past
+// their retention are archived again on the next run unless a legal hold covers them.
+func RestoreAuditArchive(w http.ResponseWriter, r *http.Request) {
+	archiveID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.RestoreAuditArchiveRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	archive, err := scanAuditArchive(database.DB.QueryRow("SELECT "+auditArchiveColumns+" FROM audit_archives WHERE id = ?", archiveID))
+	if err != nil {
+		http.Error(w, "Archive not found", http.StatusNotFound)
+		return
+	}
+	entries, err := readAuditArchive(archive)
+	if errors.Is(err, errAuditArchiveCorrupt) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read archive", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to restore archive", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	restored := 0
+	for _, e := range entries {
+		if (req.FromID != nil && e.ID < *req.FromID) || (req.ToID != nil && e.ID > *req.ToID) {
+			continue
+		}
+		// Only entries still out of the database are restored
+		result, err := tx.Exec("DELETE FROM audit_archived_entries WHERE id = ? AND archive_id = ?", e.ID, archiveID)
+		if err != nil {
+			http.Error(w, "Failed to restore archive", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO audit_logs (id, action, action_category, actor_id, target_type, target_id, target_name, details, old_value, new_value, ip_address, user_agent, severity, created_at, prev_hash, hash, request_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID, e.Action, e.ActionCategory, e.ActorID, e.TargetType, e.TargetID, e.TargetName, e.Details,
+			e.OldValue, e.NewValue, e.IPAddress, e.UserAgent, e.Severity, e.CreatedAt, e.PrevHash, e.Hash, e.RequestID)
+		if err != nil {
+			http.Error(w, "Failed to restore archive", http.StatusInternalServerError)
+			return
+		}
+		restored++
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to restore archive", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "audit.archive.restore", "audit_archive", archiveID, archive.FileName, nil, map[string]interface{}{
+		"from_id":  req.FromID,
+		"to_id":    req.ToID,
+		"restored": restored,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"restored": restored, "message": "Archived entries restored"})
+}
+
+// StartAuditArchiveWorker archives entries past their retention every interval
+func StartAuditArchiveWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "audit archiver", interval, func(now time.Time) error {
+		_, _, err := archiveAuditLogs(now)
+		return err
+	})
+}
+
+// archiveAuditLogs moves every entry past its category's retention into archive
+// files, oldest first. Each file is written, read back and checked before the entries
+// leave the database; their hashes stay behind so the chain still verifies.
+func archiveAuditLogs(now time.Time) (int, int, error) {
+	where, args, err := auditRetentionFilter(now)
+	if err != nil || where == "" {
+		return 0, 0, err
+	}
+
+	archives, archived := 0, 0
+	for lastID := 0; ; {
+		batch, err := loadAuditExportBatch(where, args, lastID)
+		if err != nil {
+			return archives, archived, err
+		}
+		if len(batch) == 0 {
+			return archives, archived, nil
+		}
+		for len(batch) < auditArchiveBatch {
+			more, err := loadAuditExportBatch(where, args, batch[len(batch)-1].ID)
+			if err != nil {
+				return archives, archived, err
+			}
+			if len(more) == 0 {
+				break
+			}
+			batch = append(batch, more...)
+		}
+		lastID = batch[len(batch)-1].ID
+
+		archive, n, err := writeAuditArchive(batch, now)
+		if err != nil {
+			return archives, archived, err
+		}
+		archives++
+		archived += n
+
+		LogSystemAudit("audit.archive", "audit_archive", archive.ID, archive.FileName,
+			fmt.Sprintf("Archived %d entries (%d to %d)", n, archive.FirstLogID, archive.LastLogID),
+			nil, map[string]interface{}{
+				"file_name":   archive.FileName,
+				"sha256":      archive.SHA256,
+				"entry_count": archive.EntryCount,
+				"archived":    n,
+			})
+	}
+}
+
+// auditRetentionFilter turns the retention policies into SQL conditions on al that
+// match entries due for archiving. It returns no conditions when there are no
+// policies, in which case nothing is archived.
+func auditRetentionFilter(now time.Time) (string, []interface{}, error) {
+	rows, err := database.DB.Query("SELECT action_category, retention_days FROM audit_retention_policies")
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	var clauses []string
+	var args []interface{}
+	var explicit []interface{}
+	defaultDays := 0
+	for rows.Next() {
+		var category string
+		var days int
+		if err := rows.Scan(&category, &days); err != nil {
+			return "", nil, err
+		}
+		if category == "*" {
+			defaultDays = days
+			continue
+		}
+		clauses = append(clauses, "(al.action_category = ? AND al.created_at < ?)")
+		args = append(args, category, now.UTC().AddDate(0, 0, -days))
+		explicit = append(explicit, category)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if defaultDays > 0 {
+		clause := "al.created_at < ?"
+		if len(explicit) > 0 {
+			clause = "(al.action_category NOT IN (?" + strings.Repeat(", ?", len(explicit)-1) + ") AND " + clause + ")"
+		}
+		clauses = append(clauses, clause)
+		args = append(args, explicit...)
+		args = append(args, now.UTC().AddDate(0, 0, -defaultDays))
+	}
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+
+	return " AND (" + strings.Join(clauses, " OR ") + ") AND" + archivable, args, nil
+}
+
+// writeAuditArchive writes entries to a gzipped NDJSON file, checks the file, then
+// removes from the database the entries that are still archivable, leaving their
+// hashes behind. It returns the archive and how many entries left the database.
+func writeAuditArchive(entries []models.AuditLog, now time.Time) (models.AuditArchive, int, error) {
+	first, last := entries[0], entries[len(entries)-1]
+	archive := models.AuditArchive{
+		FileName:       fmt.Sprintf("audit-%d-%d-%s.ndjson.gz", first.ID, last.ID, now.UTC().Format("20060102T150405Z")),
+		EntryCount:     len(entries),
+		FirstLogID:     first.ID,
+		LastLogID:      last.ID,
+		FirstCreatedAt: first.CreatedAt,
+		LastCreatedAt:  last.CreatedAt,
+	}
+	for _, e := range entries {
+		if e.CreatedAt.Before(archive.FirstCreatedAt) {
+			archive.FirstCreatedAt = e.CreatedAt
+		}
+		if e.CreatedAt.After(archive.LastCreatedAt) {
+			archive.LastCreatedAt = e.CreatedAt
+		}
+	}
+
+	if err := os.MkdirAll(AuditArchiveDir, 0o750); err != nil {
+		return archive, 0, err
+	}
+	path := filepath.Join(AuditArchiveDir, archive.FileName)
+	sum, err := writeAuditArchiveFile(path, entries)
+	if err != nil {
+		return archive, 0, err
+	}
+	archive.SHA256 = sum
+
+	// Read the file back before anything is deleted
+	written, err := readAuditArchive(archive)
+	if err != nil {
+		return archive, 0, err
+	}
+	if len(written) != len(entries) {
+		return archive, 0, fmt.Errorf("%w: wrote %d entries, read back %d", errAuditArchiveCorrupt, len(entries), len(written))
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return archive, 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO audit_archives (file_name, sha256, entry_count, first_log_id, last_log_id,
+			first_created_at, last_created_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		archive.FileName, archive.SHA256, archive.EntryCount, archive.FirstLogID, archive.LastLogID,
+		archive.FirstCreatedAt, archive.LastCreatedAt, now.UTC())
+	if err != nil {
+		return archive, 0, err
+	}
+	id, _ := result.LastInsertId()
+	archive.ID = int(id)
+	archive.CreatedAt = now.UTC()
+
+	archived := 0
+	for _, e := range entries {
+		// A legal hold placed since the entries were read still keeps them
+		var ok bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM audit_logs al WHERE al.id = ? AND"+archivable+")",
+			e.ID).Scan(&ok); err != nil {
+			return archive, 0, err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE webhook_deliveries SET audit_log_id = NULL WHERE audit_log_id = ?", e.ID); err != nil {
+			return archive, 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM audit_logs WHERE id = ?", e.ID); err != nil {
+			return archive, 0, err
+		}
+		if _, err := tx.Exec("INSERT INTO audit_archived_entries (id, archive_id, prev_hash, hash) VALUES (?, ?, ?, ?)",
+			e.ID, archive.ID, e.PrevHash, e.Hash); err != nil {
+			return archive, 0, err
+		}
+		archived++
+	}
+	archive.ArchivedCount = archived
+	return archive, archived, tx.Commit()
+}
+
+// writeAuditArchiveFile writes entries as gzipped NDJSON, synced to disk, and returns
+// the file's SHA-256. The file only appears under its name once it is complete.
+func writeAuditArchiveFile(path string, entries []models.AuditLog) (string, error) {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	hasher := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(f, hasher))
+	enc := json.NewEncoder(gz)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// readAuditArchive reads an archive file back, checking the file against its recorded
+// SHA-256 and every chained entry against its own hash
+func readAuditArchive(archive models.AuditArchive) ([]models.AuditLog, error) {
+	data, err := os.ReadFile(filepath.Join(AuditArchiveDir, archive.FileName))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != archive.SHA256 {
+		return nil, fmt.Errorf("%w: %s has a different SHA-256", errAuditArchiveCorrupt, archive.FileName)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAuditArchiveCorrupt, err)
+	}
+	dec := json.NewDecoder(gz)
+
+	var entries []models.AuditLog
+	for {
+		var e models.AuditLog
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errAuditArchiveCorrupt, err)
+		}
+		if e.Hash != nil && *e.Hash != "" {
+			prev := ""
+			if e.PrevHash != nil {
+				prev = *e.PrevHash
+			}
+			if auditchain.Hash(prev, e) != *e.Hash {
+				return nil, fmt.Errorf("%w: entry %d does not match its hash", errAuditArchiveCorrupt, e.ID)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// matchesAuditFilter applies the audit log list filters to an archived entry
+func matchesAuditFilter(e models.AuditLog, query url.Values) bool {
+	matchInt := func(key string, v *int) bool {
+		want := query.Get(key)
+		return want == "" || (v != nil && strconv.Itoa(*v) == want)
+	}
+	matchString := func(key string, v *string) bool {
+		want := query.Get(key)
+		return want == "" || (v != nil && *v == want)
+	}
+
+	day := e.CreatedAt.UTC().Format("2006-01-02")
+	fromID, _ := strconv.Atoi(query.Get("from_id"))
+	toID, _ := strconv.Atoi(query.Get("to_id"))
+
+	switch {
+	case !matchInt("actor_id", e.ActorID), !matchInt("target_id", e.TargetID),
+		!matchString("target_type", e.TargetType), !matchString("request_id", e.RequestID):
+		return false
+	case query.Get("action") != "" && !strings.Contains(e.Action, query.Get("action")):
+		return false
+	case query.Get("action_category") != "" && e.ActionCategory != query.Get("action_category"):
+		return false
+	case query.Get("severity") != "" && e.Severity != query.Get("severity"):
+		return false
+	case query.Get("date_from") != "" && day < query.Get("date_from"):
+		return false
+	case query.Get("date_to") != "" && day > query.Get("date_to"):
+		return false
+	case fromID > 0 && e.ID < fromID, toID > 0 && e.ID > toID:
+		return false
+	}
+	return true
+}
+
+func scanAuditArchive(s rowScanner) (models.AuditArchive, error) {
+	var a models.AuditArchive
+	err := s.Scan(&a.ID, &a.FileName, &a.SHA256, &a.EntryCount, &a.ArchivedCount, &a.FirstLogID, &a.LastLogID,
+		&a.FirstCreatedAt, &a.LastCreatedAt, &a.CreatedAt)
+	return a, err
+}
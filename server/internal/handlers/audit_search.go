@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+)
+
+// auditSearchTerm is one term of a search: free text, or a field:value filter. A
+// leading - excludes what the term matches.
+type auditSearchTerm struct {
+	field   string
+	value   string
+	quoted  bool
+	negated bool
+}
+
+// auditSearchFields turn a field:value filter into an SQL condition on al
+var auditSearchFields = map[string]func(value string) (string, []interface{}, error){
+	"actor": func(v string) (string, []interface{}, error) {
+		if id, err := strconv.Atoi(v); err == nil {
+			return "al.actor_id = ?", []interface{}{id}, nil
+		}
+		if strings.EqualFold(v, "system") {
+			return "al.actor_id IS NULL", nil, nil
+		}
+		// A bare name matches the local part of the email
+		return "al.actor_id IN (SELECT id FROM users WHERE email = ? OR email LIKE ?)", []interface{}{v, v + "@%"}, nil
+	},
+	"action": func(v string) (string, []interface{}, error) {
+		// "role" matches role.create, role.update and so on
+		return "(al.action = ? OR al.action LIKE ?)", []interface{}{v, v + ".%"}, nil
+	},
+	"category": func(v string) (string, []interface{}, error) {
+		return "al.action_category = ?", []interface{}{v}, nil
+	},
+	"severity": func(v string) (string, []interface{}, error) {
+		return "al.severity = ?", []interface{}{v}, nil
+	},
+	"target_type": func(v string) (string, []interface{}, error) {
+		return "al.target_type = ?", []interface{}{v}, nil
+	},
+	"target_id": func(v string) (string, []interface{}, error) {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return "", nil, errors.New("target_id must be a number")
+		}
+		return "al.target_id = ?", []interface{}{id}, nil
+	},
+	"target": func(v string) (string, []interface{}, error) {
+		return "al.target_name = ?", []interface{}{v}, nil
+	},
+	"tool": func(v string) (string, []interface{}, error) {
+		// Changes to the tool itself and to requests for access to it
+		return `((al.target_type = 'tool' AND (al.target_name = ? OR al.target_id IN (SELECT id FROM tools WHERE name = ?)))
+			OR (al.target_type = 'access_request' AND al.target_id IN (
+				SELECT ar.id FROM access_requests ar JOIN tools t ON ar.target_id = t.id
+				WHERE ar.target_type = 'tool' AND t.name = ?)))`, []interface{}{v, v, v}, nil
+	},
+	"request": func(v string) (string, []interface{}, error) {
+		return "al.request_id = ?", []interface{}{v}, nil
+	},
+	"ip": func(v string) (string, []interface{}, error) {
+		// Addresses are recorded with the client's port
+		return "(al.ip_address = ? OR al.ip_address LIKE ?)", []interface{}{v, v + ":%"}, nil
+	},
+	"after": func(v string) (string, []interface{}, error) {
+		t, err := parseAuditSearchTime(v)
+		if err != nil {
+			return "", nil, err
+		}
+		return "al.created_at >= ?", []interface{}{t}, nil
+	},
+	"before": func(v string) (string, []interface{}, error) {
+		t, err := parseAuditSearchTime(v)
+		if err != nil {
+			return "", nil, err
+		}
+		return "al.created_at < ?", []interface{}{t}, nil
+	},
+}
+
+// parseAuditSearchTime reads a date, taken as midnight UTC, or an RFC 3339 time
+func parseAuditSearchTime(v string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02) or time (2006-01-02T15:04:05Z)", v)
+	}
+	return t.UTC(), nil
+}
+
+// parseAuditSearch splits a query such as `actor:alice -severity:info "break glass"`
+// into terms. Values with spaces are quoted.
+func parseAuditSearch(q string) ([]auditSearchTerm, error) {
+	var terms []auditSearchTerm
+	for i := 0; i < len(q); {
+		if q[i] == ' ' || q[i] == '\t' || q[i] == '\n' {
+			i++
+			continue
+		}
+
+		var t auditSearchTerm
+		if q[i] == '-' {
+			t.negated = true
+			i++
+		}
+		j := i
+		for j < len(q) && (q[j] == '_' || (q[j] >= 'a' && q[j] <= 'z') || (q[j] >= 'A' && q[j] <= 'Z')) {
+			j++
+		}
+		if j > i && j < len(q) && q[j] == ':' {
+			t.field = strings.ToLower(q[i:j])
+			i = j + 1
+		}
+
+		if i < len(q) && q[i] == '"' {
+			end := strings.IndexByte(q[i+1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated quote")
+			}
+			t.value = q[i+1 : i+1+end]
+			t.quoted = true
+			i += end + 2
+		} else {
+			j = i
+			for j < len(q) && q[j] != ' ' && q[j] != '\t' && q[j] != '\n' {
+				j++
+			}
+			t.value = q[i:j]
+			i = j
+		}
+
+		if t.value == "" {
+			if t.field != "" {
+				return nil, fmt.Errorf("%s: needs a value", t.field)
+			}
+			continue
+		}
+		if t.field != "" && auditSearchFields[t.field] == nil {
+			fields := make([]string, 0, len(auditSearchFields))
+			for name := range auditSearchFields {
+				fields = append(fields, name)
+			}
+			sort.Strings(fields)
+			return nil, fmt.Errorf("unknown search field %q; use one of %s, or quote the text", t.field, strings.Join(fields, ", "))
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// auditSearchFilter turns search terms into SQL conditions on al. Free text goes to the
+// full-text index when there is one and is matched as substrings otherwise.
+func auditSearchFilter(terms []auditSearchTerm) (string, []interface{}, error) {
+	where := ""
+	args := []interface{}{}
+
+	// NULL columns must not make an excluded condition drop the entry
+	add := func(clause string, clauseArgs []interface{}, negated bool) {
+		if negated {
+			where += " AND NOT COALESCE((" + clause + "), FALSE)"
+		} else {
+			where += " AND (" + clause + ")"
+		}
+		args = append(args, clauseArgs...)
+	}
+
+	var match []string
+	for _, t := range terms {
+		if t.field != "" {
+			clause, clauseArgs, err := auditSearchFields[t.field](t.value)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", t.field, err)
+			}
+			add(clause, clauseArgs, t.negated)
+			continue
+		}
+
+		if !database.AuditFullText {
+			pattern := "%" + t.value + "%"
+			add(`al.action LIKE ? OR al.target_name LIKE ? OR al.details LIKE ?
+				OR al.old_value LIKE ? OR al.new_value LIKE ?`,
+				[]interface{}{pattern, pattern, pattern, pattern, pattern}, t.negated)
+			continue
+		}
+
+		phrase := ftsPhrase(t)
+		if phrase == "" {
+			continue
+		}
+		if t.negated {
+			add("al.id NOT IN (SELECT rowid FROM audit_logs_fts WHERE audit_logs_fts MATCH ?)", []interface{}{phrase}, false)
+			continue
+		}
+		match = append(match, phrase)
+	}
+	if len(match) > 0 {
+		add("al.id IN (SELECT rowid FROM audit_logs_fts WHERE audit_logs_fts MATCH ?)", []interface{}{strings.Join(match, " ")}, false)
+	}
+	return where, args, nil
+}
+
+// ftsPhrase quotes a term for FTS5 so its punctuation is not read as query syntax. An
+// unquoted term ending in * matches as a prefix.
+func ftsPhrase(t auditSearchTerm) string {
+	value, prefix := t.value, false
+	if !t.quoted && strings.HasSuffix(value, "*") {
+		value, prefix = strings.TrimRight(value, "*"), true
+	}
+	if value == "" {
+		return ""
+	}
+	phrase := `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	if prefix {
+		phrase += "*"
+	}
+	return phrase
+}
+
+// SearchAuditLogs searches audit entries with ?q=, which mixes free text over the
+// action, target name, details and before/after values with field filters such as
+// actor:alice, tool:grafana or after:2026-01-01. The list filters of ListAuditLogs apply
+// too. Pages are keyed on the entry ID, so entries written while paging neither shift
+// nor repeat results.
+func SearchAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	ascending := query.Get("order") == "asc"
+
+	terms, err := parseAuditSearch(query.Get("q"))
+	if err != nil {
+		http.Error(w, "Invalid search: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	where, args, err := auditSearchFilter(terms)
+	if err != nil {
+		http.Error(w, "Invalid search: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filterWhere, filterArgs := auditLogFilter(query)
+	where += filterWhere
+	args = append(args, filterArgs...)
+
+	response := models.AuditSearchResponse{Data: []models.AuditLog{}, FullText: database.AuditFullText}
+
+	pageWhere := where
+	pageArgs := append([]interface{}{}, args...)
+	if cursor := query.Get("cursor"); cursor != "" {
+		afterID, err := strconv.Atoi(cursor)
+		if err != nil || afterID < 1 {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if ascending {
+			pageWhere += " AND al.id > ?"
+		} else {
+			pageWhere += " AND al.id < ?"
+		}
+		pageArgs = append(pageArgs, afterID)
+	} else {
+		facets, err := auditSearchFacets(where, args)
+		if err != nil {
+			http.Error(w, "Failed to search audit logs", http.StatusInternalServerError)
+			return
+		}
+		response.Facets = facets
+	}
+
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+	// One extra row tells whether another page follows
+	rows, err := database.DB.Query(`
+		SELECT al.id, al.action, al.action_category, al.actor_id,
+			   al.target_type, al.target_id, al.target_name, al.details,
+			   al.old_value, al.new_value, al.ip_address, al.user_agent, al.severity, al.created_at,
+			   al.request_id,
+			   COALESCE(u.email, 'System') as actor_email
+		FROM audit_logs al
+		LEFT JOIN users u ON al.actor_id = u.id
+		WHERE 1=1`+pageWhere+`
+		ORDER BY al.id `+order+`
+		LIMIT ?`, append(pageArgs, limit+1)...)
+	if err != nil {
+		http.Error(w, "Failed to search audit logs", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditLog
+		if err := rows.Scan(&e.ID, &e.Action, &e.ActionCategory, &e.ActorID,
+			&e.TargetType, &e.TargetID, &e.TargetName, &e.Details,
+			&e.OldValue, &e.NewValue, &e.IPAddress, &e.UserAgent, &e.Severity, &e.CreatedAt,
+			&e.RequestID,
+			&e.ActorEmail); err != nil {
+			http.Error(w, "Failed to scan log", http.StatusInternalServerError)
+			return
+		}
+		response.Data = append(response.Data, e)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to search audit logs", http.StatusInternalServerError)
+		return
+	}
+
+	if len(response.Data) > limit {
+		response.Data = response.Data[:limit]
+		next := strconv.Itoa(response.Data[limit-1].ID)
+		response.NextCursor = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// auditSearchActorFacets caps the actor breakdown at the most active actors
+const auditSearchActorFacets = 20
+
+// auditSearchFacets counts every entry matching a search by category, severity and actor
+func auditSearchFacets(where string, args []interface{}) (*models.AuditSearchFacets, error) {
+	facets := &models.AuditSearchFacets{
+		Categories: []models.AuditFacet{},
+		Severities: []models.AuditFacet{},
+		Actors:     []models.AuditActorFacet{},
+	}
+
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM audit_logs al WHERE 1=1"+where, args...).Scan(&facets.Total); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if facets.Categories, err = auditFacetCounts("al.action_category", where, args); err != nil {
+		return nil, err
+	}
+	if facets.Severities, err = auditFacetCounts("al.severity", where, args); err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT al.actor_id, COALESCE(u.email, 'System'), COUNT(*)
+		FROM audit_logs al
+		LEFT JOIN users u ON al.actor_id = u.id
+		WHERE 1=1`+where+`
+		GROUP BY al.actor_id
+		ORDER BY COUNT(*) DESC, al.actor_id
+		LIMIT ?`, append(append([]interface{}{}, args...), auditSearchActorFacets)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f models.AuditActorFacet
+		if err := rows.Scan(&f.ActorID, &f.Email, &f.Count); err != nil {
+			return nil, err
+		}
+		facets.Actors = append(facets.Actors, f)
+	}
+	return facets, rows.Err()
+}
+
+func auditFacetCounts(column string, where string, args []interface{}) ([]models.AuditFacet, error) {
+	rows, err := database.DB.Query(`
+		SELECT `+column+`, COUNT(*)
+		FROM audit_logs al
+		WHERE 1=1`+where+`
+		GROUP BY `+column+`
+		ORDER BY COUNT(*) DESC, `+column, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.AuditFacet{}
+	for rows.Next() {
+		var f models.AuditFacet
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, f)
+	}
+	return counts, rows.Err()
+}
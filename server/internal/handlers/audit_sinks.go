@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/auditsink"
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AuditSinks forward every audit entry to the external collectors configured at
+// startup. Set by StartAuditSinks.
+var AuditSinks []*auditsink.Forwarder
+
+// StartAuditSinks starts a forwarder for each sink. Entries a sink cannot take are
+// kept in audit_sink_dead_letters until they are replayed.
+func StartAuditSinks(ctx context.Context, sinks []auditsink.Sink, config auditsink.ForwarderConfig) {
+	for _, sink := range sinks {
+		f := auditsink.NewForwarder(sink, config, storeAuditDeadLetters)
+		f.Start(ctx)
+		AuditSinks = append(AuditSinks, f)
+		log.Printf("Forwarding audit entries to %s sink", sink.Name())
+	}
+}
+
+// forwardAuditEntry hands a written entry to every sink
+func forwardAuditEntry(e models.AuditLog) {
+	if len(AuditSinks) == 0 {
+		return
+	}
+	if e.ActorID != nil {
+		database.DB.QueryRow("SELECT email FROM users WHERE id = ?", *e.ActorID).Scan(&e.ActorEmail)
+	}
+	for _, f := range AuditSinks {
+		f.Enqueue(e)
+	}
+}
+
+func storeAuditDeadLetters(sink string, entries []models.AuditLog, attempts int, reason string) {
+	for _, e := range entries {
+		_, err := database.DB.Exec(`
+			INSERT INTO audit_sink_dead_letters (sink, audit_log_id, attempts, error)
+			VALUES (?, ?, ?, ?)`, sink, e.ID, attempts, reason)
+		if err != nil {
+			log.Printf("Failed to store dead letter of audit entry %d for %s: %v", e.ID, sink, err)
+		}
+	}
+}
+
+// ListAuditSinks reports each sink's queue depth, delivery counters and last error,
+// with the number of dead letters waiting for replay
+func ListAuditSinks(w http.ResponseWriter, r *http.Request) {
+	type sinkStatus struct {
+		auditsink.Status
+		DeadLetters int `json:"dead_letters"`
+	}
+
+	sinks := []sinkStatus{}
+	for _, f := range AuditSinks {
+		s := sinkStatus{Status: f.Status()}
+		database.DB.QueryRow("SELECT COUNT(*) FROM audit_sink_dead_letters WHERE sink = ?", f.Name()).Scan(&s.DeadLetters)
+		sinks = append(sinks, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sinks)
+}
+
+// TestAuditSinks sends a test event straight to every sink, bypassing the queue, and
+// reports what each one answered
+func TestAuditSinks(w http.ResponseWriter, r *http.Request) {
+	actorID := GetActorID(r)
+	details := "Test event; collectors can discard it"
+	targetType := "audit_sink"
+	event := models.AuditLog{
+		Action:         "audit.sink.test",
+		ActionCategory: "audit",
+		ActorID:        &actorID,
+		TargetType:     &targetType,
+		Details:        &details,
+		Severity:       "info",
+		CreatedAt:      time.Now().UTC(),
+	}
+	database.DB.QueryRow("SELECT email FROM users WHERE id = ?", actorID).Scan(&event.ActorEmail)
+
+	results := map[string]string{}
+	for _, f := range AuditSinks {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		err := f.Sink().Send(ctx, []models.AuditLog{event})
+		cancel()
+		if err != nil {
+			results[f.Name()] = err.Error()
+			continue
+		}
+		results[f.Name()] = "sent"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// ListAuditSinkDeadLetters returns dead-lettered entries, oldest first, optionally
+// filtered by ?sink=
+func ListAuditSinkDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	where := ""
+	args := []interface{}{}
+	if sink := query.Get("sink"); sink != "" {
+		where = " WHERE d.sink = ?"
+		args = append(args, sink)
+	}
+
+	var total int
+	database.DB.QueryRow("SELECT COUNT(*) FROM audit_sink_dead_letters d"+where, args...).Scan(&total)
+
+	rows, err := database.DB.Query(`
+		SELECT d.id, d.sink, d.audit_log_id, al.action, d.attempts, d.error, d.created_at
+		FROM audit_sink_dead_letters d
+		JOIN audit_logs al ON al.id = d.audit_log_id`+where+`
+		ORDER BY d.id
+		LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		http.Error(w, "Failed to fetch dead letters", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	letters := []models.AuditSinkDeadLetter{}
+	for rows.Next() {
+		var d models.AuditSinkDeadLetter
+		if err := rows.Scan(&d.ID, &d.Sink, &d.AuditLogID, &d.Action, &d.Attempts, &d.Error, &d.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan dead letter", http.StatusInternalServerError)
+			return
+		}
+		letters = append(letters, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PaginatedResponse{
+		Data:       letters,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (total + limit - 1) / limit,
+	})
+}
+
+// ReplayAuditSinkDeadLetters queues a sink's dead letters for delivery again, oldest
+// first. Entries that fail again are dead-lettered anew.
+func ReplayAuditSinkDeadLetters(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var forwarder *auditsink.Forwarder
+	for _, f := range AuditSinks {
+		if f.Name() == name {
+			forwarder = f
+		}
+	}
+	if forwarder == nil {
+		http.Error(w, "Audit sink not found", http.StatusNotFound)
+		return
+	}
+
+	// Only letters present now are replayed, so entries that fail again during the
+	// replay are not picked up a second time
+	var maxID int
+	database.DB.QueryRow("SELECT COALESCE(MAX(id), 0) FROM audit_sink_dead_letters WHERE sink = ?", name).Scan(&maxID)
+
+	replayed := 0
+	for lastID := 0; lastID < maxID; {
+		var batch []models.AuditLog
+		rows, err := database.DB.Query(`
+			SELECT d.id, al.id, al.action, al.action_category, al.actor_id,
+				   al.target_type, al.target_id, al.target_name, al.details,
+				   al.old_value, al.new_value, al.ip_address, al.user_agent, al.severity, al.created_at,
+				   al.prev_hash, al.hash, al.request_id,
+				   COALESCE(u.email, '') as actor_email
+			FROM audit_sink_dead_letters d
+			JOIN audit_logs al ON al.id = d.audit_log_id
+			LEFT JOIN users u ON al.actor_id = u.id
+			WHERE d.sink = ? AND d.id > ? AND d.id <= ?
+			ORDER BY d.id
+			LIMIT ?`, name, lastID, maxID, auditExportBatch)
+		if err != nil {
+			http.Error(w, "Failed to fetch dead letters", http.StatusInternalServerError)
+			return
+		}
+		batchEnd := lastID
+		for rows.Next() {
+			var e models.AuditLog
+			if err := rows.Scan(&batchEnd, &e.ID, &e.Action, &e.ActionCategory, &e.ActorID,
+				&e.TargetType, &e.TargetID, &e.TargetName, &e.Details,
+				&e.OldValue, &e.NewValue, &e.IPAddress, &e.UserAgent, &e.Severity, &e.CreatedAt,
+				&e.PrevHash, &e.Hash, &e.RequestID,
+				&e.ActorEmail); err != nil {
+				rows.Close()
+				http.Error(w, "Failed to scan dead letter", http.StatusInternalServerError)
+				return
+			}
+			batch = append(batch, e)
+		}
+		rows.Close()
+		if len(batch) == 0 {
+			break
+		}
+
+		_, err = database.DB.Exec("DELETE FROM audit_sink_dead_letters WHERE sink = ? AND id > ? AND id <= ?",
+			name, lastID, batchEnd)
+		if err != nil {
+			http.Error(w, "Failed to replay dead letters", http.StatusInternalServerError)
+			return
+		}
+		for _, e := range batch {
+			forwarder.Enqueue(e)
+		}
+		replayed += len(batch)
+		lastID = batchEnd
+	}
+
+	LogAudit(r, "audit.sink.replay", "audit_sink", 0, name, nil, map[string]int{"replayed": replayed})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"replayed": replayed, "message": "Dead letters queued for delivery"})
+}
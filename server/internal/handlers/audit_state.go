@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"gatekeepr/internal/connectors"
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// snapshotRelation is a set that belongs to an entity, such as a role's permissions,
+// captured along with its row
+type snapshotRelation struct {
+	field string
+	query string
+}
+
+var snapshotRelations = map[string][]snapshotRelation{
+	"roles": {
+		{"permissions", `SELECT p.name FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id
+			WHERE rp.role_id = ? ORDER BY p.name`},
+	},
+	"user_groups": {
+		{"permissions", `SELECT p.name FROM group_permissions gp JOIN permissions p ON p.id = gp.permission_id
+			WHERE gp.group_id = ? ORDER BY p.name`},
+		{"members", `SELECT u.email FROM user_group_members m JOIN users u ON u.id = m.user_id
+			WHERE m.group_id = ? ORDER BY u.email`},
+	},
+	"tools": {
+		{"owners", `SELECT owner_type || ':' || owner_id FROM tool_owners WHERE tool_id = ? ORDER BY 1`},
+	},
+	"users": {
+		{"roles", `SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = ? ORDER BY r.name`},
+		{"groups", `SELECT g.name FROM user_group_members m JOIN user_groups g ON g.id = m.group_id
+			WHERE m.user_id = ? ORDER BY g.name`},
+		{"grants", `SELECT target_type || ':' || target_id || ':' || access_level FROM access_requests
+			WHERE user_id = ? AND status = 'APPROVED' ORDER BY 1`},
+	},
+	"access_bundles": {
+		{"items", `SELECT target_type || ':' || target_id || ':' || access_level FROM access_bundle_items
+			WHERE bundle_id = ? ORDER BY 1`},
+	},
+}
+
+// redactedColumns never appear in snapshots
+var redactedColumns = map[string]bool{"password_hash": true, "secret": true}
+
+// snapshotEntity captures a row and its related sets as a column-to-value map for the
+// audit log. It returns nil when the row does not exist.
+func snapshotEntity(q queryer, table string, id int) (interface{}, error) {
+	rows, err := q.Query("SELECT * FROM "+table+" WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if !rows.Next() {
+		rows.Close()
+		return nil, rows.Err()
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	snapshot := map[string]interface{}{}
+	for i, col := range columns {
+		if redactedColumns[col] {
+			continue
+		}
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		snapshot[col] = values[i]
+	}
+
+	// Connector configs carry credentials; keep their shape but hide the secrets
+	if table == "connectors" {
+		if config, ok := snapshot["config"].(string); ok {
+			connectorType, _ := snapshot["type"].(string)
+			snapshot["config"] = json.RawMessage(connectors.RedactConfig(connectorType, json.RawMessage(config)))
+		}
+	}
+
+	for _, rel := range snapshotRelations[table] {
+		set, err := querySnapshotSet(q, rel.query, id)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[rel.field] = set
+	}
+	return snapshot, nil
+}
+
+// snapshotEntities captures each of several rows of one table, in the order given
+func snapshotEntities(q queryer, table string, ids []int) ([]interface{}, error) {
+	snapshots := make([]interface{}, len(ids))
+	for i, id := range ids {
+		snapshot, err := snapshotEntity(q, table, id)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i] = snapshot
+	}
+	return snapshots, nil
+}
+
+func querySnapshotSet(q queryer, query string, id int) ([]string, error) {
+	rows, err := q.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := []string{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		set = append(set, v)
+	}
+	return set, rows.Err()
+}
+
+// execWithSnapshots runs a statement that changes one entity in a transaction and
+// captures the entity before and after it. After is nil when the statement deleted it.
+func execWithSnapshots(table string, id int, query string, args ...interface{}) (interface{}, interface{}, error) {
+	return changeWithSnapshots(table, id, func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, args...)
+		return err
+	})
+}
+
+// changeWithSnapshots runs change in a transaction and captures the entity before and
+// after it, so the audit log shows exactly what the change did
+func changeWithSnapshots(table string, id int, change func(tx *sql.Tx) error) (interface{}, interface{}, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotEntity(tx, table, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := change(tx); err != nil {
+		return nil, nil, err
+	}
+	after, err := snapshotEntity(tx, table, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, tx.Commit()
+}
+
+// GetAuditLog returns one audit entry with a field-level diff of its before and after
+// state
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	logID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var entry models.AuditLogDetail
+	err := database.DB.QueryRow(`
+		SELECT al.id, al.action, al.action_category, al.actor_id,
+			   al.target_type, al.target_id, al.target_name, al.details,
+			   al.old_value, al.new_value, al.ip_address, al.user_agent, al.severity, al.created_at,
+			   al.prev_hash, al.hash, al.request_id,
+			   COALESCE(u.email, 'System') as actor_email
+		FROM audit_logs al
+		LEFT JOIN users u ON al.actor_id = u.id
+		WHERE al.id = ?`, logID).
+		Scan(&entry.ID, &entry.Action, &entry.ActionCategory, &entry.ActorID,
+			&entry.TargetType, &entry.TargetID, &entry.TargetName, &entry.Details,
+			&entry.OldValue, &entry.NewValue, &entry.IPAddress, &entry.UserAgent, &entry.Severity, &entry.CreatedAt,
+			&entry.PrevHash, &entry.Hash, &entry.RequestID,
+			&entry.ActorEmail)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Audit log not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+
+	entry.Changes = diffAuditValues(entry.OldValue, entry.NewValue)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// diffAuditValues compares the stored old and new JSON values field by field. Nested
+// objects are walked with dotted paths; lists of plain values report the items added
+// and removed.
+func diffAuditValues(oldJSON *string, newJSON *string) []models.AuditFieldChange {
+	var before, after interface{}
+	if oldJSON != nil {
+		json.Unmarshal([]byte(*oldJSON), &before)
+	}
+	if newJSON != nil {
+		json.Unmarshal([]byte(*newJSON), &after)
+	}
+
+	changes := []models.AuditFieldChange{}
+	diffValues("", before, after, &changes)
+	return changes
+}
+
+func diffValues(path string, before interface{}, after interface{}, changes *[]models.AuditFieldChange) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if (beforeIsMap || before == nil) && (afterIsMap || after == nil) && (beforeIsMap || afterIsMap) {
+		keys := map[string]bool{}
+		for k := range beforeMap {
+			keys[k] = true
+		}
+		for k := range afterMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			diffValues(field, beforeMap[k], afterMap[k], changes)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+
+	change := models.AuditFieldChange{Field: path, Old: before, New: after}
+	switch {
+	case before == nil:
+		change.Change = "added"
+	case after == nil:
+		change.Change = "removed"
+	default:
+		change.Change = "changed"
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if (beforeIsList || before == nil) && (afterIsList || after == nil) {
+		change.Added = listDifference(afterList, beforeList)
+		change.Removed = listDifference(beforeList, afterList)
+	}
+	*changes = append(*changes, change)
+}
+
+// listDifference returns the items of a that are not in b
+func listDifference(a []interface{}, b []interface{}) []interface{} {
+	seen := map[string]bool{}
+	for _, v := range b {
+		seen[fmt.Sprint(v)] = true
+	}
+	var diff []interface{}
+	for _, v := range a {
+		if !seen[fmt.Sprint(v)] {
+			diff = append(diff, v)
+		}
+	}
+	return diff
+}
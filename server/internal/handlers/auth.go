@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"gatekeepr/internal/auth"
 	"gatekeepr/internal/database"
@@ -41,6 +42,8 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	database.DB.Exec("UPDATE users SET last_login_at = ? WHERE id = ?", time.Now(), user.ID)
+
 	// Get user's roles from user_roles table
 	roles := getUserRoles(user.ID)
 
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+)
+
+// AuthzDenialWindow is how long repeated denials of one user for one requirement are
+// folded into a single audit entry, so a client retrying in a loop cannot flood the log
+var AuthzDenialWindow = time.Minute
+
+type authzDenialKey struct {
+	userID      int
+	requirement string
+}
+
+// authzDenialTally tracks the denials seen since the last entry written for a key
+type authzDenialTally struct {
+	start      time.Time
+	suppressed int
+	// The most recent suppressed request, reported when the window is flushed
+	route     string
+	ipAddress string
+	userAgent string
+	requestID *string
+}
+
+var (
+	authzDenialsMu sync.Mutex
+	authzDenials   = map[authzDenialKey]*authzDenialTally{}
+)
+
+// authzDenial is what an authz.deny entry records
+type authzDenial struct {
+	Requirement string `json:"requirement"`
+	Route       string `json:"route"`
+	Count       int    `json:"count"`
+}
+
+// RecordAuthzDenial audits a request refused for lacking a role, permission or
+// capability. The first denial of a user for a requirement is written at once; further
+// ones within AuthzDenialWindow are counted and written as one entry when the window
+// closes.
+func RecordAuthzDenial(r *http.Request, requirement string) {
+	now := time.Now()
+	key := authzDenialKey{userID: GetActorID(r), requirement: requirement}
+	route := r.Method + " " + r.URL.Path
+
+	authzDenialsMu.Lock()
+	window := authzDenials[key]
+	if window != nil && now.Sub(window.start) < AuthzDenialWindow {
+		window.suppressed++
+		window.route = route
+		window.ipAddress = r.RemoteAddr
+		window.userAgent = r.UserAgent()
+		window.requestID = requestID(r)
+		authzDenialsMu.Unlock()
+		return
+	}
+	count := 1
+	if window != nil {
+		count += window.suppressed
+	}
+	authzDenials[key] = &authzDenialTally{start: now}
+	authzDenialsMu.Unlock()
+
+	rec := requestAuditRecord(r, "authz.deny", "route", 0, route, nil, authzDenial{Requirement: requirement, Route: route, Count: count})
+	rec.Details = authzDenialDetails(requirement, route, count)
+	rec.Severity = "warning"
+	writeAuditLog(rec)
+}
+
+// StartAuthzDenialFlusher writes the denials counted in windows that have closed
+func StartAuthzDenialFlusher(ctx context.Context) {
+	startPeriodicJob(ctx, "authorization denial flush", AuthzDenialWindow, func(now time.Time) error {
+		flushAuthzDenials(now)
+		return nil
+	})
+}
+
+func flushAuthzDenials(now time.Time) {
+	var records []auditRecord
+	authzDenialsMu.Lock()
+	for key, window := range authzDenials {
+		if now.Sub(window.start) < AuthzDenialWindow {
+			continue
+		}
+		delete(authzDenials, key)
+		if window.suppressed == 0 {
+			continue
+		}
+
+		userID := key.userID
+		ipAddress, userAgent := window.ipAddress, window.userAgent
+		records = append(records, auditRecord{
+			Action:     "authz.deny",
+			ActorID:    &userID,
+			TargetType: "route",
+			TargetName: window.route,
+			Details:    authzDenialDetails(key.requirement, window.route, window.suppressed),
+			NewValue:   authzDenial{Requirement: key.requirement, Route: window.route, Count: window.suppressed},
+			IPAddress:  &ipAddress,
+			UserAgent:  &userAgent,
+			Severity:   "warning",
+			RequestID:  window.requestID,
+		})
+	}
+	authzDenialsMu.Unlock()
+
+	for _, rec := range records {
+		writeAuditLog(rec)
+	}
+}
+
+func authzDenialDetails(requirement string, route string, count int) *string {
+	details := fmt.Sprintf("Refused %s for lacking %s", route, requirement)
+	if count > 1 {
+		details += fmt.Sprintf(" (%d attempts)", count)
+	}
+	return &details
+}
+
+// GetAuthzDenialReport ranks the users and requirements with the most authorization
+// denials over the last ?days= days (default 7), keeping the top ?limit= of each
+// (default 10). Denials still being counted in an open window are not included yet.
+func GetAuthzDenialReport(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = d
+	}
+	limit := 10
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT COALESCE(al.actor_id, 0), COALESCE(u.email, ''), al.new_value, al.created_at
+		FROM audit_logs al
+		LEFT JOIN users u ON al.actor_id = u.id
+		WHERE al.action = 'authz.deny' AND al.created_at >= ?
+		ORDER BY al.id`, time.Now().UTC().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, "Failed to fetch authorization denials", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	principals := map[int]*models.AuthzDenialPrincipal{}
+	requirements := map[string]*models.AuthzDenialRequirement{}
+	principalRequirements := map[authzDenialKey]bool{}
+	total := 0
+	for rows.Next() {
+		var userID int
+		var email string
+		var value *string
+		var createdAt time.Time
+		if err := rows.Scan(&userID, &email, &value, &createdAt); err != nil {
+			http.Error(w, "Failed to scan authorization denial", http.StatusInternalServerError)
+			return
+		}
+		var d authzDenial
+		if value != nil {
+			json.Unmarshal([]byte(*value), &d)
+		}
+		if d.Count < 1 {
+			d.Count = 1
+		}
+		total += d.Count
+
+		p := principals[userID]
+		if p == nil {
+			p = &models.AuthzDenialPrincipal{UserID: userID, Email: email}
+			principals[userID] = p
+		}
+		p.Denials += d.Count
+		p.LastDeniedAt = createdAt
+
+		req := requirements[d.Requirement]
+		if req == nil {
+			req = &models.AuthzDenialRequirement{Requirement: d.Requirement}
+			requirements[d.Requirement] = req
+		}
+		req.Denials += d.Count
+		req.LastDeniedAt = createdAt
+
+		key := authzDenialKey{userID: userID, requirement: d.Requirement}
+		if !principalRequirements[key] {
+			principalRequirements[key] = true
+			p.Requirements++
+			req.Principals++
+		}
+	}
+
+	topPrincipals := []models.AuthzDenialPrincipal{}
+	for _, p := range principals {
+		topPrincipals = append(topPrincipals, *p)
+	}
+	sort.Slice(topPrincipals, func(i, j int) bool {
+		if topPrincipals[i].Denials != topPrincipals[j].Denials {
+			return topPrincipals[i].Denials > topPrincipals[j].Denials
+		}
+		return topPrincipals[i].UserID < topPrincipals[j].UserID
+	})
+	if len(topPrincipals) > limit {
+		topPrincipals = topPrincipals[:limit]
+	}
+
+	topRequirements := []models.AuthzDenialRequirement{}
+	for _, req := range requirements {
+		topRequirements = append(topRequirements, *req)
+	}
+	sort.Slice(topRequirements, func(i, j int) bool {
+		if topRequirements[i].Denials != topRequirements[j].Denials {
+			return topRequirements[i].Denials > topRequirements[j].Denials
+		}
+		return topRequirements[i].Requirement < topRequirements[j].Requirement
+	})
+	if len(topRequirements) > limit {
+		topRequirements = topRequirements[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":         days,
+		"total":        total,
+		"principals":   topPrincipals,
+		"requirements": topRequirements,
+	})
+}
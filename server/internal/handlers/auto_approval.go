@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// autoApprovalCandidate describes an access request being evaluated against auto-approval rules
+type autoApprovalCandidate struct {
+	UserID          int
+	TargetType      string
+	TargetID        int
+	AccessLevel     string
+	DurationMinutes *int
+	At              time.Time
+}
+
+// autoApprovalEvaluation explains why a single rule did or did not match
+type autoApprovalEvaluation struct {
+	RuleID  int      `json:"rule_id"`
+	Name    string   `json:"name"`
+	Matched bool     `json:"matched"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ListAutoApprovalRules returns all auto-approval rules in evaluation order
+func ListAutoApprovalRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := loadAutoApprovalRules(false)
+	if err != nil {
+		http.Error(w, "Failed to fetch rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateAutoApprovalRule creates a new auto-approval rule
+func CreateAutoApprovalRule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAutoApprovalRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if !isValidClockTime(req.StartTime) || !isValidClockTime(req.EndTime) {
+		http.Error(w, "start_time and end_time must be in HH:MM format", http.StatusBadRequest)
+		return
+	}
+	err := validateAutoApprovalRule(models.AutoApprovalRule{
+		ToolID:       req.ToolID,
+		ToolCategory: req.ToolCategory,
+		AccessLevel:  req.AccessLevel,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO auto_approval_rules (name, description, tool_id, tool_category, access_level,
+			requester_role_id, requester_group_id, max_duration_minutes, start_time, end_time, priority, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Description, req.ToolID, req.ToolCategory, req.AccessLevel,
+		req.RequesterRoleID, req.RequesterGroupID, req.MaxDurationMinutes, req.StartTime, req.EndTime,
+		req.Priority, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "auto_approval.rule.create", "auto_approval_rule", int(id), req.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Rule created successfully"})
+}
+
+// UpdateAutoApprovalRule updates an existing auto-approval rule
+func UpdateAutoApprovalRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateAutoApprovalRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidClockTime(req.StartTime) || !isValidClockTime(req.EndTime) {
+		http.Error(w, "start_time and end_time must be in HH:MM format", http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.Description != nil {
+		updates = append(updates, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.ToolID != nil {
+		updates = append(updates, "tool_id = ?")
+		args = append(args, nullableID(*req.ToolID))
+	}
+	if req.ToolCategory != nil {
+		updates = append(updates, "tool_category = ?")
+		args = append(args, nullableString(*req.ToolCategory))
+	}
+	if req.AccessLevel != nil {
+		updates = append(updates, "access_level = ?")
+		args = append(args, nullableString(*req.AccessLevel))
+	}
+	if req.RequesterRoleID != nil {
+		updates = append(updates, "requester_role_id = ?")
+		args = append(args, nullableID(*req.RequesterRoleID))
+	}
+	if req.RequesterGroupID != nil {
+		updates = append(updates, "requester_group_id = ?")
+		args = append(args, nullableID(*req.RequesterGroupID))
+	}
+	if req.MaxDurationMinutes != nil {
+		updates = append(updates, "max_duration_minutes = ?")
+		args = append(args, nullableID(*req.MaxDurationMinutes))
+	}
+	if req.StartTime != nil {
+		updates = append(updates, "start_time = ?")
+		args = append(args, nullableString(*req.StartTime))
+	}
+	if req.EndTime != nil {
+		updates = append(updates, "end_time = ?")
+		args = append(args, nullableString(*req.EndTime))
+	}
+	if req.Priority != nil {
+		updates = append(updates, "priority = ?")
+		args = append(args, *req.Priority)
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	// Check the rule as it will be after the update, not just the fields sent
+	rule, err := loadAutoApprovalRule(ruleID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load rule", http.StatusInternalServerError)
+		return
+	}
+	if req.ToolID != nil {
+		rule.ToolID = optionalID(*req.ToolID)
+	}
+	if req.ToolCategory != nil {
+		rule.ToolCategory = optionalString(*req.ToolCategory)
+	}
+	if req.AccessLevel != nil {
+		rule.AccessLevel = optionalString(*req.AccessLevel)
+	}
+	if req.StartTime != nil {
+		rule.StartTime = optionalString(*req.StartTime)
+	}
+	if req.EndTime != nil {
+		rule.EndTime = optionalString(*req.EndTime)
+	}
+	if err := validateAutoApprovalRule(rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE auto_approval_rules SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, ruleID)
+
+	before, after, err := execWithSnapshots("auto_approval_rules", ruleID, query, args...)
+	if err != nil {
+		http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "auto_approval.rule.update", "auto_approval_rule", ruleID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Rule updated successfully"})
+}
+
+// DeleteAutoApprovalRule deletes an auto-approval rule
+func DeleteAutoApprovalRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var ruleName string
+	database.DB.QueryRow("SELECT name FROM auto_approval_rules WHERE id = ?", ruleID).Scan(&ruleName)
+
+	before, _, err := changeWithSnapshots("auto_approval_rules", ruleID, func(tx *sql.Tx) error {
+		// Keep approved requests pointing at a rule that no longer exists from breaking joins
+		if _, err := tx.Exec("UPDATE access_requests SET auto_approval_rule_id = NULL WHERE auto_approval_rule_id = ?", ruleID); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM auto_approval_rules WHERE id = ?", ruleID)
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Failed to delete rule", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "auto_approval.rule.delete", "auto_approval_rule", ruleID, ruleName, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Rule deleted successfully"})
+}
+
+// DryRunAutoApproval evaluates a hypothetical request against the active rules without creating it
+func DryRunAutoApproval(w http.ResponseWriter, r *http.Request) {
+	var req models.AutoApprovalDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TargetType == "" || req.TargetID == 0 {
+		http.Error(w, "target_type and target_id are required", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == 0 {
+		req.UserID = GetActorID(r)
+	}
+	level, err := resolveAccessLevel(req.TargetType, req.TargetID, req.AccessLevel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.AccessLevel = level
+
+	at := time.Now()
+	if req.At != nil {
+		parsed, err := time.Parse(time.RFC3339, *req.At)
+		if err != nil {
+			http.Error(w, "at must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		at = parsed
+	}
+
+	candidate := autoApprovalCandidate{
+		UserID:          req.UserID,
+		TargetType:      req.TargetType,
+		TargetID:        req.TargetID,
+		AccessLevel:     req.AccessLevel,
+		DurationMinutes: req.DurationMinutes,
+		At:              at,
+	}
+
+	matched, evaluations, err := evaluateAutoApprovalRules(candidate)
+	if err != nil {
+		http.Error(w, "Failed to evaluate rules", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"would_auto_approve": matched != nil,
+		"evaluations":        evaluations,
+	}
+	if matched != nil {
+		response["matched_rule"] = matched
+
+		conflicts, err := findSoDConflicts(req.UserID, []Entitlement{{Type: req.TargetType, ID: req.TargetID, AccessLevel: req.AccessLevel}})
+		if err == nil && len(conflicts) > 0 {
+			response["would_auto_approve"] = false
+			response["sod_conflicts"] = conflicts
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// evaluateAutoApprovalRules checks the candidate against every active rule in priority
+// order and returns the first rule that matches along with all evaluations
+func evaluateAutoApprovalRules(c autoApprovalCandidate) (*models.AutoApprovalRule, []autoApprovalEvaluation, error) {
+	rules, err := loadAutoApprovalRules(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	var toolCategory *string
+	var highRisk bool
+	if c.TargetType == "tool" {
+		database.DB.QueryRow("SELECT category, high_risk FROM tools WHERE id = ?", c.TargetID).Scan(&toolCategory, &highRisk)
+	}
+
+	entitlements, err := getUserEntitlements(c.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	holds := func(entitlementType string, id int) bool {
+		for _, e := range entitlements {
+			if e.Type == entitlementType && e.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	var matched *models.AutoApprovalRule
+	var evaluations []autoApprovalEvaluation
+	for i := range rules {
+		rule := rules[i]
+		var reasons []string
+
+		// Rules only ever cover low-risk tool access; these hold for every rule, including
+		// ones saved before create and update enforced them
+		if c.TargetType != "tool" {
+			reasons = append(reasons, "only tool access can be auto-approved")
+		}
+		if highRisk {
+			reasons = append(reasons, "tool is high risk")
+		}
+		if rule.ToolID == nil && rule.ToolCategory == nil {
+			reasons = append(reasons, "rule names no tool or tool category")
+		}
+		if rule.AccessLevel == nil {
+			reasons = append(reasons, "rule names no access level")
+		}
+		if rule.ToolID != nil && (c.TargetType != "tool" || c.TargetID != *rule.ToolID) {
+			reasons = append(reasons, "tool does not match")
+		}
+		if rule.ToolCategory != nil && (toolCategory == nil || *toolCategory != *rule.ToolCategory) {
+			reasons = append(reasons, "tool category does not match")
+		}
+		if rule.AccessLevel != nil && c.AccessLevel != *rule.AccessLevel {
+			reasons = append(reasons, "access level does not match")
+		}
+		if rule.RequesterRoleID != nil && !holds("role", *rule.RequesterRoleID) {
+			reasons = append(reasons, "requester does not hold the required role")
+		}
+		if rule.RequesterGroupID != nil && !holds("group", *rule.RequesterGroupID) {
+			reasons = append(reasons, "requester is not in the required group")
+		}
+		if rule.MaxDurationMinutes != nil &&
+			(c.DurationMinutes == nil || *c.DurationMinutes <= 0 || *c.DurationMinutes > *rule.MaxDurationMinutes) {
+			reasons = append(reasons, "duration exceeds the rule maximum")
+		}
+		if rule.StartTime != nil && rule.EndTime != nil && !withinClockWindow(c.At, *rule.StartTime, *rule.EndTime) {
+			reasons = append(reasons, "outside the allowed time of day")
+		}
+
+		ok := len(reasons) == 0
+		evaluations = append(evaluations, autoApprovalEvaluation{RuleID: rule.ID, Name: rule.Name, Matched: ok, Reasons: reasons})
+		if ok && matched == nil {
+			matched = &rule
+		}
+	}
+
+	return matched, evaluations, nil
+}
+
+// validateAutoApprovalRule checks the criteria a rule needs before it may approve
+// anything: it must be limited to a tool or tool category and to one access level, may
+// not cover a high-risk tool, and its time window needs both ends
+func validateAutoApprovalRule(rule models.AutoApprovalRule) error {
+	if rule.ToolID == nil && rule.ToolCategory == nil {
+		return errors.New("tool_id or tool_category is required")
+	}
+	if rule.AccessLevel == nil {
+		return errors.New("access_level is required")
+	}
+	if rule.ToolID != nil {
+		var highRisk bool
+		if err := database.DB.QueryRow("SELECT high_risk FROM tools WHERE id = ?", *rule.ToolID).Scan(&highRisk); err != nil {
+			return errors.New("tool not found")
+		}
+		if highRisk {
+			return errors.New("high-risk tools cannot be auto-approved")
+		}
+		if _, err := resolveAccessLevel("tool", *rule.ToolID, *rule.AccessLevel); err != nil {
+			return err
+		}
+	}
+	if (rule.StartTime == nil) != (rule.EndTime == nil) {
+		return errors.New("start_time and end_time must be set together")
+	}
+	return nil
+}
+
+const autoApprovalRuleColumns = `
+		SELECT id, name, description, tool_id, tool_category, access_level,
+			   requester_role_id, requester_group_id, max_duration_minutes,
+			   start_time, end_time, priority, is_active, created_by, created_at, updated_at
+		FROM auto_approval_rules`
+
+func loadAutoApprovalRule(ruleID int) (models.AutoApprovalRule, error) {
+	var rule models.AutoApprovalRule
+	err := database.DB.QueryRow(autoApprovalRuleColumns+" WHERE id = ?", ruleID).Scan(
+		&rule.ID, &rule.Name, &rule.Description, &rule.ToolID, &rule.ToolCategory,
+		&rule.AccessLevel, &rule.RequesterRoleID, &rule.RequesterGroupID, &rule.MaxDurationMinutes,
+		&rule.StartTime, &rule.EndTime, &rule.Priority, &rule.IsActive, &rule.CreatedBy,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	return rule, err
+}
+
+func loadAutoApprovalRules(activeOnly bool) ([]models.AutoApprovalRule, error) {
+	query := autoApprovalRuleColumns
+	if activeOnly {
+		query += " WHERE is_active = 1"
+	}
+	query += " ORDER BY priority DESC, id ASC"
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.AutoApprovalRule
+	for rows.Next() {
+		var rule models.AutoApprovalRule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Description, &rule.ToolID, &rule.ToolCategory,
+			&rule.AccessLevel, &rule.RequesterRoleID, &rule.RequesterGroupID, &rule.MaxDurationMinutes,
+			&rule.StartTime, &rule.EndTime, &rule.Priority, &rule.IsActive, &rule.CreatedBy,
+			&rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func isValidClockTime(value *string) bool {
+	if value == nil || *value == "" {
+		return true
+	}
+	_, err := time.Parse("15:04", *value)
+	return err == nil
+}
+
+// withinClockWindow reports whether t falls between start and end (HH:MM, server local time).
+// A window whose end is before its start wraps past midnight.
+func withinClockWindow(t time.Time, start string, end string) bool {
+	now := t.In(time.Local).Format("15:04")
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return sql.NullInt64{}
+	}
+	return id
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return sql.NullString{}
+	}
+	return s
+}
+
+// optionalID and optionalString mirror nullableID and nullableString for merging an
+// update into a loaded row
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
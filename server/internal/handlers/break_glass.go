@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BreakGlassMaxMinutes is the hard cap on how long a break-glass grant lasts
+var BreakGlassMaxMinutes = 60
+
+// SecurityGroupName is the group whose members are notified of every break-glass grant
+var SecurityGroupName = "security"
+
+// BreakGlassAccess grants emergency access immediately, without approval. The grant is
+// capped at BreakGlassMaxMinutes, flagged as critical in the audit log and opens a
+// review that an approver must acknowledge afterwards.
+func BreakGlassAccess(w http.ResponseWriter, r *http.Request) {
+	var req models.BreakGlassRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TargetType == "" || req.TargetID == 0 {
+		http.Error(w, "target_type and target_id are required", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" || req.IncidentReference == "" {
+		http.Error(w, "reason and incident_reference are required", http.StatusBadRequest)
+		return
+	}
+	level, err := resolveAccessLevel(req.TargetType, req.TargetID, req.AccessLevel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.AccessLevel = level
+
+	duration := BreakGlassMaxMinutes
+	if req.DurationMinutes != nil && *req.DurationMinutes > 0 && *req.DurationMinutes < duration {
+		duration = *req.DurationMinutes
+	}
+	req.DurationMinutes = &duration
+	expiresAt := time.Now().Add(time.Duration(duration) * time.Minute)
+
+	userID := GetActorID(r)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Separation of duties rules are deliberately not enforced here: emergency access
+	// must not wait, and the mandatory review is where conflicts get caught
+	result, err := tx.Exec(`
+		INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, status,
+			reason, duration_minutes, approved_at, expires_at, incident_reference)
+		VALUES (?, 'break_glass', ?, ?, ?, 'APPROVED', ?, ?, CURRENT_TIMESTAMP, ?, ?)`,
+		userID, req.TargetType, req.TargetID, req.AccessLevel, req.Reason, duration, expiresAt, req.IncidentReference)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to grant access", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	reviewResult, err := tx.Exec(`INSERT INTO break_glass_reviews (access_request_id) VALUES (?)`, id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to open review", http.StatusInternalServerError)
+		return
+	}
+
+	reviewID, _ := reviewResult.LastInsertId()
+
+	if err := enqueueProvisioning(tx, int(id), ProvisionAction); err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to queue provisioning", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogCriticalAudit(r, "access.break_glass", "access_request", int(id), req.IncidentReference, nil, &req)
+
+	notifyBreakGlass(int(id), userID, req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"review_id":  reviewID,
+		"expires_at": expiresAt,
+		"message":    "Break-glass access granted",
+	})
+}
+
+// ListBreakGlassReviews returns break-glass reviews, optionally filtered by status
+func ListBreakGlassReviews(w http.ResponseWriter, r *http.Request) {
+	if !CanApproveRequests(r) {
+		RecordAuthzDenial(r, "capability:can_approve_requests")
+		http.Error(w, "You do not have permission to review break-glass access", http.StatusForbidden)
+		return
+	}
+
+	query := `
+		SELECT bgr.id, bgr.access_request_id, bgr.status, bgr.reviewer_id, bgr.reviewed_at, bgr.notes, bgr.created_at,
+			   ar.user_id, u.email, ar.target_type, ar.target_id, ar.access_level, ar.reason,
+			   ar.incident_reference, ar.approved_at, ar.expires_at
+		FROM break_glass_reviews bgr
+		JOIN access_requests ar ON bgr.access_request_id = ar.id
+		JOIN users u ON ar.user_id = u.id`
+	args := []interface{}{}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " WHERE bgr.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY bgr.created_at DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch reviews", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var reviews []models.BreakGlassReview
+	for rows.Next() {
+		var rv models.BreakGlassReview
+		if err := rows.Scan(&rv.ID, &rv.AccessRequestID, &rv.Status, &rv.ReviewerID, &rv.ReviewedAt, &rv.Notes, &rv.CreatedAt,
+			&rv.UserID, &rv.UserEmail, &rv.TargetType, &rv.TargetID, &rv.AccessLevel, &rv.Reason,
+			&rv.IncidentReference, &rv.GrantedAt, &rv.ExpiresAt); err != nil {
+			http.Error(w, "Failed to scan review", http.StatusInternalServerError)
+			return
+		}
+		reviews = append(reviews, rv)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// AcknowledgeBreakGlassReview closes the review of a break-glass grant
+func AcknowledgeBreakGlassReview(w http.ResponseWriter, r *http.Request) {
+	if !CanApproveRequests(r) {
+		RecordAuthzDenial(r, "capability:can_approve_requests")
+		http.Error(w, "You do not have permission to review break-glass access", http.StatusForbidden)
+		return
+	}
+
+	reviewID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.AcknowledgeBreakGlassRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	reviewerID := GetActorID(r)
+
+	var requesterID int
+	var status string
+	err := database.DB.QueryRow(`
+		SELECT ar.user_id, bgr.status
+		FROM break_glass_reviews bgr
+		JOIN access_requests ar ON bgr.access_request_id = ar.id
+		WHERE bgr.id = ?`, reviewID).Scan(&requesterID, &status)
+	if err != nil {
+		http.Error(w, "Review not found", http.StatusNotFound)
+		return
+	}
+	if status != "PENDING" {
+		http.Error(w, "Review has already been acknowledged", http.StatusConflict)
+		return
+	}
+	if requesterID == reviewerID {
+		http.Error(w, "You cannot review your own break-glass access", http.StatusForbidden)
+		return
+	}
+
+	before, after, err := execWithSnapshots("break_glass_reviews", reviewID, `
+		UPDATE break_glass_reviews
+		SET status = 'ACKNOWLEDGED', reviewer_id = ?, reviewed_at = CURRENT_TIMESTAMP, notes = ?
+		WHERE id = ? AND status = 'PENDING'`,
+		reviewerID, req.Notes, reviewID)
+	if err != nil {
+		http.Error(w, "Failed to acknowledge review", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "access.break_glass.review", "break_glass_review", reviewID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Review acknowledged"})
+}
+
+// breakGlassRecipients returns every approver and every member of the security group
+func breakGlassRecipients() []int {
+	return queryUserIDs(`
+		SELECT ur.user_id FROM user_roles ur
+		JOIN roles r ON ur.role_id = r.id
+		WHERE r.can_approve_requests = 1
+		UNION
+		SELECT ugm.user_id FROM user_group_members ugm
+		JOIN user_groups g ON ugm.group_id = g.id
+		WHERE g.name = ?`, SecurityGroupName)
+}
+
+func notifyBreakGlass(requestID int, userID int, req models.BreakGlassRequest) {
+	notifyUsers(breakGlassRecipients(),
+		fmt.Sprintf("Break-glass access used for incident %s", req.IncidentReference),
+		fmt.Sprintf("User %d obtained emergency %s access to %s %d (request %d) for %d minutes. Reason: %s",
+			userID, req.AccessLevel, req.TargetType, req.TargetID, requestID, *req.DurationMinutes, req.Reason))
+}
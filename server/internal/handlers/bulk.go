@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"gatekeepr/internal/database"
@@ -21,6 +22,16 @@ func BulkAssignRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, userID := range req.UserIDs {
+		proposed := make([]Entitlement, 0, len(req.RoleIDs))
+		for _, roleID := range req.RoleIDs {
+			proposed = append(proposed, Entitlement{Type: "role", ID: roleID})
+		}
+		if !enforceSoD(w, r, userID, "bulk.roles.assign", proposed) {
+			return
+		}
+	}
+
 	actorID := GetActorID(r)
 
 	tx, err := database.DB.Begin()
@@ -29,6 +40,13 @@ func BulkAssignRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	count := 0
 	for _, userID := range req.UserIDs {
 		for _, roleID := range req.RoleIDs {
@@ -45,12 +63,21 @@ func BulkAssignRoles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "bulk.roles.assign", "user", 0, "", nil, &req)
+	for i, userID := range req.UserIDs {
+		LogAudit(r, "bulk.roles.assign", "user", userID, "", before[i], after[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -73,6 +100,16 @@ func BulkAddToGroups(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, userID := range req.UserIDs {
+		proposed := make([]Entitlement, 0, len(req.GroupIDs))
+		for _, groupID := range req.GroupIDs {
+			proposed = append(proposed, Entitlement{Type: "group", ID: groupID})
+		}
+		if !enforceSoD(w, r, userID, "bulk.groups.add", proposed) {
+			return
+		}
+	}
+
 	actorID := GetActorID(r)
 
 	tx, err := database.DB.Begin()
@@ -81,6 +118,13 @@ func BulkAddToGroups(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	count := 0
 	for _, userID := range req.UserIDs {
 		for _, groupID := range req.GroupIDs {
@@ -97,12 +141,21 @@ func BulkAddToGroups(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "bulk.groups.add", "user", 0, "", nil, &req)
+	for i, userID := range req.UserIDs {
+		LogAudit(r, "bulk.groups.add", "user", userID, "", before[i], after[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -134,6 +187,13 @@ func BulkAssignPermissions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntities(tx, "user_groups", req.GroupIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load groups", http.StatusInternalServerError)
+		return
+	}
+
 	count := 0
 	for _, groupID := range req.GroupIDs {
 		for _, permID := range req.PermissionIDs {
@@ -150,12 +210,21 @@ func BulkAssignPermissions(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntities(tx, "user_groups", req.GroupIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load groups", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "bulk.permissions.assign", "group", 0, "", nil, &req)
+	for i, groupID := range req.GroupIDs {
+		LogAudit(r, "bulk.permissions.assign", "group", groupID, "", before[i], after[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -178,16 +247,34 @@ func BulkGrantAccess(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.AccessLevel == "" {
-		req.AccessLevel = "read"
-	}
-
 	// Check if actor has grant permission
 	if !CanGrantAccess(r) {
+		RecordAuthzDenial(r, "capability:can_grant_access")
 		http.Error(w, "You do not have permission to grant access", http.StatusForbidden)
 		return
 	}
 
+	// Each tool gets the requested level, or its own lowest level when none is given
+	levels := map[int]string{}
+	for _, toolID := range req.ToolIDs {
+		level, err := resolveAccessLevel("tool", toolID, req.AccessLevel)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Tool %d: %s", toolID, err), http.StatusBadRequest)
+			return
+		}
+		levels[toolID] = level
+	}
+
+	for _, userID := range req.UserIDs {
+		proposed := make([]Entitlement, 0, len(req.ToolIDs))
+		for _, toolID := range req.ToolIDs {
+			proposed = append(proposed, Entitlement{Type: "tool", ID: toolID, AccessLevel: levels[toolID]})
+		}
+		if !enforceSoD(w, r, userID, "bulk.access.grant", proposed) {
+			return
+		}
+	}
+
 	actorID := GetActorID(r)
 
 	tx, err := database.DB.Begin()
@@ -196,6 +283,13 @@ func BulkGrantAccess(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	// For bulk grant, we create approved access requests
 	count := 0
 	for _, userID := range req.UserIDs {
@@ -203,7 +297,7 @@ func BulkGrantAccess(w http.ResponseWriter, r *http.Request) {
 			result, err := tx.Exec(`
 				INSERT INTO access_requests (user_id, request_type, target_type, target_id, access_level, status, approved_by, approved_at)
 				VALUES (?, 'tool_access', 'tool', ?, ?, 'APPROVED', ?, CURRENT_TIMESTAMP)`,
-				userID, toolID, req.AccessLevel, actorID)
+				userID, toolID, levels[toolID], actorID)
 			if err != nil {
 				tx.Rollback()
 				http.Error(w, "Failed to grant access", http.StatusInternalServerError)
@@ -211,15 +305,31 @@ func BulkGrantAccess(w http.ResponseWriter, r *http.Request) {
 			}
 			affected, _ := result.RowsAffected()
 			count += int(affected)
+
+			id, _ := result.LastInsertId()
+			if err := enqueueProvisioning(tx, int(id), ProvisionAction); err != nil {
+				tx.Rollback()
+				http.Error(w, "Failed to queue provisioning", http.StatusInternalServerError)
+				return
+			}
 		}
 	}
 
+	after, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "bulk.access.grant", "access", 0, "", nil, &req)
+	for i, userID := range req.UserIDs {
+		LogAudit(r, "bulk.access.grant", "user", userID, "", before[i], after[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -248,6 +358,13 @@ func BulkRemoveRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	count := 0
 	for _, userID := range req.UserIDs {
 		for _, roleID := range req.RoleIDs {
@@ -262,12 +379,21 @@ func BulkRemoveRoles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntities(tx, "users", req.UserIDs)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "bulk.roles.remove", "user", 0, "", nil, &req)
+	for i, userID := range req.UserIDs {
+		LogAudit(r, "bulk.roles.remove", "user", userID, "", before[i], after[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -0,0 +1,800 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	authMiddleware "gatekeepr/internal/middleware"
+	"gatekeepr/internal/models"
+	"gatekeepr/internal/notify"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Overall statuses of a bundle request, derived from its items
+const (
+	BundleStatusInProgress        = "IN_PROGRESS"
+	BundleStatusPartiallyApproved = "PARTIALLY_APPROVED"
+	BundleStatusCompleted         = "COMPLETED"
+)
+
+// bundleItemOutcome reports what happened to one item of a bundle request or decision
+type bundleItemOutcome struct {
+	TargetType  string `json:"target_type"`
+	TargetID    int    `json:"target_id"`
+	AccessLevel string `json:"access_level,omitempty"`
+	RequestID   int    `json:"request_id,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ListAccessBundles returns the bundles users can request. Users who manage policies
+// can pass include_inactive=true to see retired bundles too.
+func ListAccessBundles(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT id, name, display_name, description, is_active, created_by, created_at, updated_at
+		FROM access_bundles`
+	if r.URL.Query().Get("include_inactive") != "true" ||
+		!authMiddleware.UserHasPermission(GetActorID(r), "policies.manage") {
+		query += " WHERE is_active = TRUE"
+	}
+	query += " ORDER BY display_name"
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		http.Error(w, "Failed to fetch bundles", http.StatusInternalServerError)
+		return
+	}
+
+	var bundles []models.AccessBundle
+	for rows.Next() {
+		var b models.AccessBundle
+		if err := rows.Scan(&b.ID, &b.Name, &b.DisplayName, &b.Description, &b.IsActive,
+			&b.CreatedBy, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			rows.Close()
+			http.Error(w, "Failed to scan bundle", http.StatusInternalServerError)
+			return
+		}
+		bundles = append(bundles, b)
+	}
+	rows.Close()
+
+	for i := range bundles {
+		if bundles[i].Items, err = loadBundleItems(bundles[i].ID); err != nil {
+			http.Error(w, "Failed to fetch bundle items", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bundles)
+}
+
+// GetAccessBundle returns a bundle with its items
+func GetAccessBundle(w http.ResponseWriter, r *http.Request) {
+	bundleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	bundle, err := loadBundle(bundleID)
+	if err != nil {
+		http.Error(w, "Bundle not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bundle)
+}
+
+// CreateAccessBundle creates a bundle of tools, roles and groups
+func CreateAccessBundle(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAccessBundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.DisplayName == "" {
+		http.Error(w, "name and display_name are required", http.StatusBadRequest)
+		return
+	}
+	if msg := validateBundleItems(req.Items); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO access_bundles (name, display_name, description, created_by)
+		VALUES (?, ?, ?, ?)`,
+		req.Name, req.DisplayName, req.Description, GetActorID(r))
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to create bundle", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	if err := replaceBundleItems(tx, int(id), req.Items); err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to save bundle items", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "bundle.create", "access_bundle", int(id), req.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Bundle created successfully"})
+}
+
+// UpdateAccessBundle updates a bundle, replacing its items when they are given
+func UpdateAccessBundle(w http.ResponseWriter, r *http.Request) {
+	bundleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateAccessBundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	old, err := loadBundle(bundleID)
+	if err != nil {
+		http.Error(w, "Bundle not found", http.StatusNotFound)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.DisplayName != nil {
+		if *req.DisplayName == "" {
+			http.Error(w, "display_name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "display_name = ?")
+		args = append(args, *req.DisplayName)
+	}
+	if req.Description != nil {
+		updates = append(updates, "description = ?")
+		args = append(args, nullableString(*req.Description))
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+	if req.Items != nil {
+		if msg := validateBundleItems(*req.Items); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(updates) == 0 && req.Items == nil {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	query := "UPDATE access_bundles SET " + joinStrings(append(updates, "updated_at = CURRENT_TIMESTAMP"), ", ") + " WHERE id = ?"
+	args = append(args, bundleID)
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to update bundle", http.StatusInternalServerError)
+		return
+	}
+	if req.Items != nil {
+		if err := replaceBundleItems(tx, bundleID, *req.Items); err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to save bundle items", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "bundle.update", "access_bundle", bundleID, old.Name, old, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Bundle updated successfully"})
+}
+
+// DeleteAccessBundle deletes a bundle that has never been requested. Requested bundles
+// are kept for their history and should be deactivated instead.
+func DeleteAccessBundle(w http.ResponseWriter, r *http.Request) {
+	bundleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var bundleName string
+	if err := database.DB.QueryRow("SELECT name FROM access_bundles WHERE id = ?", bundleID).Scan(&bundleName); err != nil {
+		http.Error(w, "Bundle not found", http.StatusNotFound)
+		return
+	}
+
+	var requested bool
+	database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM access_bundle_requests WHERE bundle_id = ?)", bundleID).Scan(&requested)
+	if requested {
+		http.Error(w, "Bundle has been requested; deactivate it instead", http.StatusConflict)
+		return
+	}
+
+	before, _, err := execWithSnapshots("access_bundles", bundleID, "DELETE FROM access_bundles WHERE id = ?", bundleID)
+	if err != nil {
+		http.Error(w, "Failed to delete bundle", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "bundle.delete", "access_bundle", bundleID, bundleName, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Bundle deleted successfully"})
+}
+
+// RequestAccessBundle files one access request per bundle item for the current user.
+// Items are auto-approved by the usual rules; items the user already has a pending
+// request for are skipped.
+func RequestAccessBundle(w http.ResponseWriter, r *http.Request) {
+	bundleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.RequestAccessBundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bundle, err := loadBundle(bundleID)
+	if err != nil || !bundle.IsActive {
+		http.Error(w, "Bundle not found", http.StatusNotFound)
+		return
+	}
+	if len(bundle.Items) == 0 {
+		http.Error(w, "Bundle has no items", http.StatusBadRequest)
+		return
+	}
+
+	userID := GetActorID(r)
+
+	result, err := database.DB.Exec(`
+		INSERT INTO access_bundle_requests (bundle_id, user_id, reason, duration_minutes)
+		VALUES (?, ?, ?, ?)`,
+		bundleID, userID, req.Reason, req.DurationMinutes)
+	if err != nil {
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		return
+	}
+	id, _ := result.LastInsertId()
+	bundleRequestID := int(id)
+
+	// Items are filed one at a time rather than in a transaction since auto-approval
+	// and separation of duties checks read through the shared connection pool
+	outcomes := []bundleItemOutcome{}
+	filed := 0
+	for _, item := range bundle.Items {
+		outcome := bundleItemOutcome{TargetType: item.TargetType, TargetID: item.TargetID, AccessLevel: item.AccessLevel}
+
+		requestID, approval, err := fileAccessRequest(r, userID, models.CreateAccessRequestDTO{
+			TargetType:      item.TargetType,
+			TargetID:        item.TargetID,
+			AccessLevel:     item.AccessLevel,
+			Reason:          req.Reason,
+			DurationMinutes: req.DurationMinutes,
+		}, nil, &bundleRequestID)
+		var levelErr *accessLevelError
+		switch {
+		case errors.As(err, &levelErr):
+			outcome.Status = "FAILED"
+			outcome.Error = levelErr.Error()
+		case errors.Is(err, errDuplicateRequest):
+			outcome.Status = "SKIPPED"
+			outcome.Error = "You already have a pending request for this resource"
+		case err != nil:
+			outcome.Status = "FAILED"
+			outcome.Error = "Failed to create request"
+		default:
+			filed++
+			outcome.RequestID = requestID
+			outcome.Status = StatusPending
+			if approval != nil {
+				outcome.Status = StatusApproved
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	if filed == 0 {
+		database.DB.Exec("DELETE FROM access_bundle_requests WHERE id = ?", bundleRequestID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "No requests were created for this bundle",
+			"items": outcomes,
+		})
+		return
+	}
+
+	LogAudit(r, "access.bundle.request", "access_bundle_request", bundleRequestID, bundle.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      bundleRequestID,
+		"items":   outcomes,
+		"message": fmt.Sprintf("Requested %d of %d bundle items", filed, len(bundle.Items)),
+	})
+}
+
+// GetMyBundleRequests returns the current user's bundle requests with their aggregate status
+func GetMyBundleRequests(w http.ResponseWriter, r *http.Request) {
+	userID := GetActorID(r)
+
+	rows, err := database.DB.Query("SELECT id FROM access_bundle_requests WHERE user_id = ? ORDER BY created_at DESC", userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch bundle requests", http.StatusInternalServerError)
+		return
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	requests := []models.BundleRequest{}
+	for _, id := range ids {
+		br, err := loadBundleRequest(id)
+		if err != nil {
+			http.Error(w, "Failed to fetch bundle requests", http.StatusInternalServerError)
+			return
+		}
+		requests = append(requests, *br)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// GetBundleRequest returns a bundle request and its items to the requester or to
+// anyone who may decide one of its items
+func GetBundleRequest(w http.ResponseWriter, r *http.Request) {
+	bundleRequestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	br, err := loadBundleRequest(bundleRequestID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Bundle request not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch bundle request", http.StatusInternalServerError)
+		return
+	}
+
+	allowed := br.UserID == GetActorID(r)
+	for _, item := range br.Items {
+		if allowed {
+			break
+		}
+		allowed = canDecideRequest(r, item.ID, item.TargetType, item.TargetID)
+	}
+	if !allowed {
+		RecordAuthzDenial(r, "capability:request_participant")
+		http.Error(w, "You are not a participant in this request", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(br)
+}
+
+// ApproveBundleRequest approves every pending item of a bundle request the current
+// user may decide. Items that cannot be approved are reported and left pending so they
+// can be handled one by one.
+func ApproveBundleRequest(w http.ResponseWriter, r *http.Request) {
+	bundleRequestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.ApproveAccessRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	br, err := loadBundleRequest(bundleRequestID)
+	if err != nil {
+		http.Error(w, "Bundle request not found", http.StatusNotFound)
+		return
+	}
+
+	approverID := GetActorID(r)
+	outcomes := []bundleItemOutcome{}
+	approved := 0
+
+	for _, item := range br.Items {
+		if item.Status != StatusPending {
+			continue
+		}
+		outcome := bundleItemOutcome{TargetType: item.TargetType, TargetID: item.TargetID,
+			AccessLevel: item.AccessLevel, RequestID: item.ID, Status: item.Status}
+
+		onBehalfOf, ok := resolveRequestDecider(approverID, item.ID, item.TargetType, item.TargetID)
+		if !ok {
+			outcome.Error = "You do not have permission to approve this item"
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+		if (br.UserID == approverID || br.UserID == onBehalfOf) && !AllowSelfApproval {
+			outcome.Error = "You cannot approve your own request"
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+		if err := checkLevelApprover(approverID, item.TargetType, item.TargetID, item.AccessLevel); err != nil {
+			outcome.Error = err.Error()
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		// Conflicts are not recorded here; approving the item on its own raises them
+		// as violations or exception requests
+		proposed := []Entitlement{{Type: item.TargetType, ID: item.TargetID, AccessLevel: item.AccessLevel}}
+		conflicts, err := findSoDConflicts(br.UserID, proposed)
+		if err != nil {
+			outcome.Error = "Failed to evaluate separation of duties rules"
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+		blocked := false
+		for _, c := range conflicts {
+			if c.Rule.Enforcement != "exception" || !hasApprovedSoDException(c.Rule.ID, br.UserID, c.Entitlement) {
+				blocked = true
+				break
+			}
+		}
+		if blocked {
+			outcome.Error = "Separation of duties conflict; approve this item individually"
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		minutes := req.DurationMinutes
+		if minutes == nil {
+			minutes = item.DurationMinutes
+		}
+		var expiresAt *time.Time
+		if minutes != nil && *minutes > 0 {
+			t := time.Now().Add(time.Duration(*minutes) * time.Minute)
+			expiresAt = &t
+		}
+
+		if err := decideBundleItem(item.ID, StatusApproved,
+			"approved_by = ?, approved_at = CURRENT_TIMESTAMP, expires_at = ?", approverID, expiresAt); err != nil {
+			outcome.Error = err.Error()
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		if onBehalfOf != 0 {
+			LogAuditWithDetails(r, "access.request.approve", "access_request", item.ID, "",
+				delegationNote("Approved", approverID, onBehalfOf), nil, &req)
+		} else {
+			LogAudit(r, "access.request.approve", "access_request", item.ID, "", nil, &req)
+		}
+
+		approved++
+		outcome.Status = StatusApproved
+		outcomes = append(outcomes, outcome)
+	}
+
+	if approved == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "No pending items could be approved",
+			"items": outcomes,
+		})
+		return
+	}
+
+	LogAudit(r, "access.bundle.approve", "access_bundle_request", bundleRequestID, br.BundleName, nil, outcomes)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":   outcomes,
+		"message": fmt.Sprintf("Approved %d bundle items", approved),
+	})
+}
+
+// RejectBundleRequest rejects every pending item of a bundle request the current user
+// may decide
+func RejectBundleRequest(w http.ResponseWriter, r *http.Request) {
+	bundleRequestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.RejectAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	br, err := loadBundleRequest(bundleRequestID)
+	if err != nil {
+		http.Error(w, "Bundle request not found", http.StatusNotFound)
+		return
+	}
+
+	rejectorID := GetActorID(r)
+	outcomes := []bundleItemOutcome{}
+	rejected := 0
+
+	for _, item := range br.Items {
+		if item.Status != StatusPending {
+			continue
+		}
+		outcome := bundleItemOutcome{TargetType: item.TargetType, TargetID: item.TargetID,
+			AccessLevel: item.AccessLevel, RequestID: item.ID, Status: item.Status}
+
+		onBehalfOf, ok := resolveRequestDecider(rejectorID, item.ID, item.TargetType, item.TargetID)
+		if !ok {
+			outcome.Error = "You do not have permission to reject this item"
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		if err := decideBundleItem(item.ID, StatusRejected,
+			"rejected_by = ?, rejected_at = CURRENT_TIMESTAMP, rejection_reason = ?", rejectorID, req.Reason); err != nil {
+			outcome.Error = err.Error()
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		if onBehalfOf != 0 {
+			LogAuditWithDetails(r, "access.request.reject", "access_request", item.ID, "",
+				delegationNote("Rejected", rejectorID, onBehalfOf), nil, &req)
+		} else {
+			LogAudit(r, "access.request.reject", "access_request", item.ID, "", nil, &req)
+		}
+
+		rejected++
+		outcome.Status = StatusRejected
+		outcomes = append(outcomes, outcome)
+	}
+
+	if rejected == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "No pending items could be rejected",
+			"items": outcomes,
+		})
+		return
+	}
+
+	LogAudit(r, "access.bundle.reject", "access_bundle_request", bundleRequestID, br.BundleName, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":   outcomes,
+		"message": fmt.Sprintf("Rejected %d bundle items", rejected),
+	})
+}
+
+// decideBundleItem moves a single bundle item to its decided status in its own transaction
+// and tells the requester
+func decideBundleItem(requestID int, to string, set string, args ...interface{}) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	if err := transitionRequest(tx, requestID, to, set, args...); err != nil {
+		tx.Rollback()
+		var te *transitionError
+		if errors.As(err, &te) {
+			return err
+		}
+		return errors.New("failed to update request")
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit transaction")
+	}
+
+	switch to {
+	case StatusApproved:
+		notifyRequester(requestID, notify.EventRequestApproved)
+	case StatusRejected:
+		notifyRequester(requestID, notify.EventRequestRejected)
+	}
+	return nil
+}
+
+// validateBundleItems checks that every item targets an existing tool, role or group
+// at a level it defines, defaulting the access level. It returns a message describing
+// the first problem.
+func validateBundleItems(items []models.AccessBundleItemRequest) string {
+	if len(items) == 0 {
+		return "A bundle needs at least one item"
+	}
+
+	seen := map[string]bool{}
+	for i := range items {
+		item := &items[i]
+
+		var table string
+		switch item.TargetType {
+		case "tool":
+			table = "tools"
+		case "role":
+			table = "roles"
+		case "group":
+			table = "user_groups"
+		default:
+			return "target_type must be tool, role or group"
+		}
+
+		var exists bool
+		database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = ?)", item.TargetID).Scan(&exists)
+		if !exists {
+			return fmt.Sprintf("%s %d not found", item.TargetType, item.TargetID)
+		}
+
+		key := fmt.Sprintf("%s:%d", item.TargetType, item.TargetID)
+		if seen[key] {
+			return fmt.Sprintf("%s %d is listed more than once", item.TargetType, item.TargetID)
+		}
+		seen[key] = true
+
+		level, err := resolveAccessLevel(item.TargetType, item.TargetID, item.AccessLevel)
+		if err != nil {
+			return fmt.Sprintf("%s %d: %s", item.TargetType, item.TargetID, err)
+		}
+		item.AccessLevel = level
+	}
+	return ""
+}
+
+// replaceBundleItems swaps a bundle's items for the given set
+func replaceBundleItems(tx *sql.Tx, bundleID int, items []models.AccessBundleItemRequest) error {
+	if _, err := tx.Exec("DELETE FROM access_bundle_items WHERE bundle_id = ?", bundleID); err != nil {
+		return err
+	}
+	for _, item := range items {
+		_, err := tx.Exec(`
+			INSERT INTO access_bundle_items (bundle_id, target_type, target_id, access_level)
+			VALUES (?, ?, ?, ?)`,
+			bundleID, item.TargetType, item.TargetID, item.AccessLevel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadBundle(bundleID int) (*models.AccessBundle, error) {
+	var b models.AccessBundle
+	err := database.DB.QueryRow(`
+		SELECT id, name, display_name, description, is_active, created_by, created_at, updated_at
+		FROM access_bundles WHERE id = ?`, bundleID).
+		Scan(&b.ID, &b.Name, &b.DisplayName, &b.Description, &b.IsActive, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Items, err = loadBundleItems(bundleID)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func loadBundleItems(bundleID int) ([]models.AccessBundleItem, error) {
+	rows, err := database.DB.Query(`
+		SELECT i.id, i.bundle_id, i.target_type, i.target_id, i.access_level,
+			   COALESCE(CASE i.target_type
+				   WHEN 'tool' THEN (SELECT display_name FROM tools WHERE id = i.target_id)
+				   WHEN 'role' THEN (SELECT display_name FROM roles WHERE id = i.target_id)
+				   WHEN 'group' THEN (SELECT display_name FROM user_groups WHERE id = i.target_id)
+			   END, '')
+		FROM access_bundle_items i
+		WHERE i.bundle_id = ?
+		ORDER BY i.id`, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.AccessBundleItem{}
+	for rows.Next() {
+		var item models.AccessBundleItem
+		if err := rows.Scan(&item.ID, &item.BundleID, &item.TargetType, &item.TargetID, &item.AccessLevel,
+			&item.TargetName); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// loadBundleRequest loads a bundle request with its access requests and works out its
+// overall status
+func loadBundleRequest(bundleRequestID int) (*models.BundleRequest, error) {
+	var br models.BundleRequest
+	err := database.DB.QueryRow(`
+		SELECT br.id, br.bundle_id, br.user_id, br.reason, br.duration_minutes, br.created_at, b.display_name, u.email
+		FROM access_bundle_requests br
+		JOIN access_bundles b ON br.bundle_id = b.id
+		JOIN users u ON br.user_id = u.id
+		WHERE br.id = ?`, bundleRequestID).
+		Scan(&br.ID, &br.BundleID, &br.UserID, &br.Reason, &br.DurationMinutes, &br.CreatedAt, &br.BundleName, &br.UserEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, request_type, target_type, target_id, access_level, status, reason, duration_minutes,
+			   approved_by, approved_at, rejected_by, rejected_at, rejection_reason, expires_at,
+			   auto_approval_rule_id, bundle_request_id, provisioning_status, created_at
+		FROM access_requests
+		WHERE bundle_request_id = ?
+		ORDER BY id`, bundleRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	br.StatusCounts = map[string]int{}
+	for rows.Next() {
+		var req models.AccessRequest
+		if err := rows.Scan(&req.ID, &req.UserID, &req.RequestType, &req.TargetType, &req.TargetID,
+			&req.AccessLevel, &req.Status, &req.Reason, &req.DurationMinutes,
+			&req.ApprovedBy, &req.ApprovedAt, &req.RejectedBy, &req.RejectedAt, &req.RejectionReason, &req.ExpiresAt,
+			&req.AutoApprovalRuleID, &req.BundleRequestID, &req.ProvisioningStatus, &req.CreatedAt); err != nil {
+			return nil, err
+		}
+		br.Items = append(br.Items, req)
+		br.StatusCounts[req.Status]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	br.Status = bundleStatus(br.StatusCounts, len(br.Items))
+	return &br, nil
+}
+
+// bundleStatus summarises item statuses: PENDING until something is decided,
+// IN_PROGRESS while items remain pending, the shared status once every item agrees,
+// and otherwise PARTIALLY_APPROVED or COMPLETED depending on whether anything was granted
+func bundleStatus(counts map[string]int, total int) string {
+	switch {
+	case counts[StatusPending] == total:
+		return StatusPending
+	case counts[StatusPending] > 0:
+		return BundleStatusInProgress
+	}
+	for status, n := range counts {
+		if n == total {
+			return status
+		}
+	}
+	if counts[StatusApproved] > 0 {
+		return BundleStatusPartiallyApproved
+	}
+	return BundleStatusCompleted
+}
@@ -0,0 +1,801 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Review campaign statuses
+const (
+	CampaignActive    = "ACTIVE"
+	CampaignCompleted = "COMPLETED"
+	CampaignCancelled = "CANCELLED"
+)
+
+// reviewSnapshotItem is one entitlement captured when a campaign launches
+type reviewSnapshotItem struct {
+	UserID          int
+	EntitlementType string
+	EntitlementID   int
+	TargetType      *string
+	TargetID        *int
+	AccessLevel     *string
+}
+
+// certificationReport is the signed record of a completed campaign
+type certificationReport struct {
+	CampaignID         int                 `json:"campaign_id"`
+	Name               string              `json:"name"`
+	ScopeType          string              `json:"scope_type"`
+	ScopeID            *int                `json:"scope_id,omitempty"`
+	ScopeValue         *string             `json:"scope_value,omitempty"`
+	RevokeOnNoResponse bool                `json:"revoke_on_no_response"`
+	CreatedBy          *int                `json:"created_by,omitempty"`
+	CreatedAt          time.Time           `json:"created_at"`
+	DueAt              time.Time           `json:"due_at"`
+	CompletedAt        time.Time           `json:"completed_at"`
+	Summary            map[string]int      `json:"summary"`
+	Items              []models.ReviewItem `json:"items"`
+}
+
+const reviewItemColumns = `
+	SELECT i.id, i.campaign_id, i.user_id, i.entitlement_type, i.entitlement_id, i.target_type, i.target_id,
+		   i.access_level, i.reviewer_id, i.decision, i.comment, i.decided_by, i.decided_at, i.created_at,
+		   u.email, rv.email,
+		   CASE i.entitlement_type
+			   WHEN 'role' THEN (SELECT display_name FROM roles WHERE id = i.entitlement_id)
+			   WHEN 'group' THEN (SELECT display_name FROM user_groups WHERE id = i.entitlement_id)
+			   ELSE (SELECT display_name FROM tools WHERE i.target_type = 'tool' AND id = i.target_id)
+		   END
+	FROM review_items i
+	JOIN users u ON i.user_id = u.id
+	JOIN users rv ON i.reviewer_id = rv.id`
+
+// ListReviewCampaigns returns all campaigns with their progress
+func ListReviewCampaigns(w http.ResponseWriter, r *http.Request) {
+	query := campaignColumns
+	args := []interface{}{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " WHERE c.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY c.created_at DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch campaigns", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var campaigns []models.ReviewCampaign
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan campaign", http.StatusInternalServerError)
+			return
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaigns)
+}
+
+// GetReviewCampaign returns a single campaign with its progress
+func GetReviewCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	c, err := scanCampaign(database.DB.QueryRow(campaignColumns+" WHERE c.id = ?", campaignID))
+	if err != nil {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// CreateReviewCampaign launches a campaign: the access in scope is snapshotted into
+// review items and each item is assigned to a reviewer
+func CreateReviewCampaign(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateReviewCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.DueAt == "" {
+		http.Error(w, "name and due_at are required", http.StatusBadRequest)
+		return
+	}
+	switch req.ScopeType {
+	case "all":
+	case "tool", "role", "group":
+		if req.ScopeID == nil {
+			http.Error(w, "scope_id is required for this scope", http.StatusBadRequest)
+			return
+		}
+	case "department":
+		if req.ScopeValue == nil || *req.ScopeValue == "" {
+			http.Error(w, "scope_value is required for a department scope", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "scope_type must be all, tool, role, group or department", http.StatusBadRequest)
+		return
+	}
+
+	dueAt, err := time.Parse(time.RFC3339, req.DueAt)
+	if err != nil {
+		http.Error(w, "due_at must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	if !dueAt.After(time.Now()) {
+		http.Error(w, "due_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	actorID := GetActorID(r)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO review_campaigns (name, description, scope_type, scope_id, scope_value, due_at, revoke_on_no_response, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Description, req.ScopeType, req.ScopeID, req.ScopeValue, dueAt.Local(), req.RevokeOnNoResponse, actorID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to create campaign", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	items, err := snapshotReviewScope(tx, req.ScopeType, req.ScopeID, req.ScopeValue)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to snapshot access", http.StatusInternalServerError)
+		return
+	}
+
+	var scopeToolID *int
+	if req.ScopeType == "tool" {
+		scopeToolID = req.ScopeID
+	}
+
+	reviewers := map[int]int{}
+	for _, item := range items {
+		reviewerID := assignReviewer(tx, item, scopeToolID, actorID)
+		_, err := tx.Exec(`
+			INSERT INTO review_items (campaign_id, user_id, entitlement_type, entitlement_id, target_type, target_id, access_level, reviewer_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, item.UserID, item.EntitlementType, item.EntitlementID, item.TargetType, item.TargetID, item.AccessLevel, reviewerID)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to create review items", http.StatusInternalServerError)
+			return
+		}
+		reviewers[reviewerID]++
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "review.campaign.create", "review_campaign", int(id), req.Name, nil, &req)
+
+	for reviewerID, count := range reviewers {
+		notifyUsers([]int{reviewerID},
+			fmt.Sprintf("Access review: %s", req.Name),
+			fmt.Sprintf("You have %d access items to review by %s.", count, dueAt.Format(time.RFC1123)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"items":   len(items),
+		"message": "Campaign launched successfully",
+	})
+}
+
+// ListCampaignItems returns the review items of a campaign
+func ListCampaignItems(w http.ResponseWriter, r *http.Request) {
+	campaignID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	query := reviewItemColumns + " WHERE i.campaign_id = ?"
+	args := []interface{}{campaignID}
+	if r.URL.Query().Get("pending") == "true" {
+		query += " AND i.decision IS NULL"
+	}
+	if reviewerID := r.URL.Query().Get("reviewer_id"); reviewerID != "" {
+		query += " AND i.reviewer_id = ?"
+		args = append(args, reviewerID)
+	}
+
+	writeReviewItems(w, query+" ORDER BY i.id ASC", args...)
+}
+
+// ListMyReviewItems returns the review items assigned to the current user, including
+// those of reviewers they are an active delegate for
+func ListMyReviewItems(w http.ResponseWriter, r *http.Request) {
+	actorID := GetActorID(r)
+	reviewers := append([]int{actorID}, activeDelegators(actorID)...)
+
+	placeholders := []string{}
+	args := []interface{}{}
+	for _, id := range reviewers {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	query := reviewItemColumns + `
+		JOIN review_campaigns c ON i.campaign_id = c.id
+		WHERE c.status = 'ACTIVE' AND i.reviewer_id IN (` + joinStrings(placeholders, ", ") + `)`
+	if r.URL.Query().Get("pending") == "true" {
+		query += " AND i.decision IS NULL"
+	}
+
+	writeReviewItems(w, query+" ORDER BY i.campaign_id ASC, i.id ASC", args...)
+}
+
+// DecideReviewItem records a keep or revoke decision. Revocations take effect immediately.
+func DecideReviewItem(w http.ResponseWriter, r *http.Request) {
+	itemID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.ReviewDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Decision != "keep" && req.Decision != "revoke" {
+		http.Error(w, "decision must be keep or revoke", http.StatusBadRequest)
+		return
+	}
+
+	actorID := GetActorID(r)
+
+	item, campaignStatus, err := loadReviewItem(itemID)
+	if err != nil {
+		http.Error(w, "Review item not found", http.StatusNotFound)
+		return
+	}
+	if campaignStatus != CampaignActive {
+		http.Error(w, "Campaign is "+campaignStatus, http.StatusConflict)
+		return
+	}
+	if item.Decision != nil {
+		http.Error(w, "Item has already been decided", http.StatusConflict)
+		return
+	}
+	if item.UserID == actorID {
+		http.Error(w, "You cannot review your own access", http.StatusForbidden)
+		return
+	}
+
+	onBehalfOf := 0
+	if item.ReviewerID != actorID {
+		for _, id := range activeDelegators(actorID) {
+			if id == item.ReviewerID {
+				onBehalfOf = id
+				break
+			}
+		}
+		if onBehalfOf == 0 {
+			http.Error(w, "This item is assigned to another reviewer", http.StatusForbidden)
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		UPDATE review_items SET decision = ?, comment = ?, decided_by = ?, decided_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND decision IS NULL`, req.Decision, req.Comment, actorID, itemID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to record decision", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		tx.Rollback()
+		http.Error(w, "Item has already been decided", http.StatusConflict)
+		return
+	}
+
+	if req.Decision == "revoke" {
+		if err := revokeReviewedAccess(tx, item); err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to revoke access", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	action := "review.item." + req.Decision
+	if onBehalfOf != 0 {
+		LogAuditWithDetails(r, action, "review_item", itemID, "",
+			delegationNote("Reviewed", actorID, onBehalfOf), nil, &req)
+	} else {
+		LogAudit(r, action, "review_item", itemID, "", nil, &req)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Decision recorded successfully"})
+}
+
+// ReassignReviewItem hands an undecided item to another reviewer
+func ReassignReviewItem(w http.ResponseWriter, r *http.Request) {
+	itemID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.ReassignReviewItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ReviewerID == 0 {
+		http.Error(w, "reviewer_id is required", http.StatusBadRequest)
+		return
+	}
+
+	item, campaignStatus, err := loadReviewItem(itemID)
+	if err != nil {
+		http.Error(w, "Review item not found", http.StatusNotFound)
+		return
+	}
+	if campaignStatus != CampaignActive || item.Decision != nil {
+		http.Error(w, "Only undecided items of active campaigns can be reassigned", http.StatusConflict)
+		return
+	}
+	if req.ReviewerID == item.UserID {
+		http.Error(w, "Users cannot review their own access", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := database.DB.Exec("UPDATE review_items SET reviewer_id = ? WHERE id = ?", req.ReviewerID, itemID); err != nil {
+		http.Error(w, "Failed to reassign item", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "review.item.reassign", "review_item", itemID, "",
+		models.ReassignReviewItemRequest{ReviewerID: item.ReviewerID}, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Item reassigned successfully"})
+}
+
+// CompleteReviewCampaign closes a campaign before its due date and produces its report
+func CompleteReviewCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	summary, err := completeCampaign(campaignID, time.Now())
+	if err != nil {
+		writeCampaignError(w, err, "Failed to complete campaign")
+		return
+	}
+
+	LogAudit(r, "review.campaign.complete", "review_campaign", campaignID, "", nil, summary)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Campaign completed successfully", "summary": summary})
+}
+
+// CancelReviewCampaign abandons a campaign. Decisions already recorded stay in effect.
+func CancelReviewCampaign(w http.ResponseWriter, r *http.Request) {
+	campaignID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	before, after, err := changeWithSnapshots("review_campaigns", campaignID, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE review_campaigns SET status = 'CANCELLED', completed_at = ?
+			WHERE id = ? AND status = 'ACTIVE'`, time.Now(), campaignID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return errCampaignNotActive
+		}
+		return nil
+	})
+	if errors.Is(err, errCampaignNotActive) {
+		http.Error(w, "Campaign is not active", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to cancel campaign", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "review.campaign.cancel", "review_campaign", campaignID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Campaign cancelled successfully"})
+}
+
+// GetCampaignReport returns the completion report of a campaign and its signature.
+// Reports completed while SIGNING_KEY was not set are returned unsigned.
+func GetCampaignReport(w http.ResponseWriter, r *http.Request) {
+	campaignID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var report, signature *string
+	err := database.DB.QueryRow("SELECT report, report_signature FROM review_campaigns WHERE id = ?", campaignID).
+		Scan(&report, &signature)
+	if err != nil {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	}
+	if report == nil {
+		http.Error(w, "Campaign has not been completed", http.StatusConflict)
+		return
+	}
+
+	response := map[string]interface{}{
+		"report": json.RawMessage(*report),
+		"signed": signature != nil,
+	}
+	if signature != nil {
+		response["signature"] = *signature
+		response["algorithm"] = "HMAC-SHA256"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// VerifyCampaignReport checks that a report is exactly as gatekeepr signed it
+func VerifyCampaignReport(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Report) == 0 || req.Signature == "" {
+		http.Error(w, "report and signature are required", http.StatusBadRequest)
+		return
+	}
+	if !signingKeySet() {
+		http.Error(w, "Set SIGNING_KEY to verify reports", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"valid": verifySignature(req.Report, req.Signature)})
+}
+
+// StartCampaignWorker completes campaigns once they are past their due date
+func StartCampaignWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "Review campaign check", interval, processDueCampaigns)
+}
+
+func processDueCampaigns(now time.Time) error {
+	rows, err := database.DB.Query("SELECT id FROM review_campaigns WHERE status = 'ACTIVE' AND due_at <= ?", now)
+	if err != nil {
+		return err
+	}
+	var due []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			due = append(due, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range due {
+		summary, err := completeCampaign(id, now)
+		if err != nil {
+			return fmt.Errorf("campaign %d: %w", id, err)
+		}
+		LogSystemAudit("review.campaign.complete", "review_campaign", id, "", "Due date reached", nil, summary)
+	}
+	return nil
+}
+
+var errCampaignNotActive = errors.New("campaign is not active")
+
+func writeCampaignError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+	case errors.Is(err, errCampaignNotActive):
+		http.Error(w, "Campaign is not active", http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// completeCampaign closes an active campaign. Unanswered items are revoked when the
+// campaign is configured to, and the final state is written to a signed report.
+func completeCampaign(campaignID int, now time.Time) (map[string]int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var report certificationReport
+	var status string
+	err = tx.QueryRow(`
+		SELECT id, name, scope_type, scope_id, scope_value, revoke_on_no_response, created_by, created_at, due_at, status
+		FROM review_campaigns WHERE id = ?`, campaignID).Scan(
+		&report.CampaignID, &report.Name, &report.ScopeType, &report.ScopeID, &report.ScopeValue,
+		&report.RevokeOnNoResponse, &report.CreatedBy, &report.CreatedAt, &report.DueAt, &status)
+	if err != nil {
+		return nil, err
+	}
+	if status != CampaignActive {
+		return nil, errCampaignNotActive
+	}
+
+	if report.RevokeOnNoResponse {
+		pending, err := queryReviewItems(tx, reviewItemColumns+" WHERE i.campaign_id = ? AND i.decision IS NULL", campaignID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range pending {
+			if err := revokeReviewedAccess(tx, item); err != nil {
+				return nil, err
+			}
+		}
+		_, err = tx.Exec(`
+			UPDATE review_items SET decision = 'revoke', comment = 'No response by the due date', decided_at = ?
+			WHERE campaign_id = ? AND decision IS NULL`, now, campaignID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report.CompletedAt = now.UTC()
+	report.Items, err = queryReviewItems(tx, reviewItemColumns+" WHERE i.campaign_id = ? ORDER BY i.id ASC", campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Summary = map[string]int{"total": len(report.Items), "kept": 0, "revoked": 0, "auto_revoked": 0, "no_response": 0}
+	for _, item := range report.Items {
+		switch {
+		case item.Decision == nil:
+			report.Summary["no_response"]++
+		case *item.Decision == "keep":
+			report.Summary["kept"]++
+		case item.DecidedBy == nil:
+			report.Summary["auto_revoked"]++
+		default:
+			report.Summary["revoked"]++
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without SIGNING_KEY the report is kept unsigned rather than signed with a key
+	// anyone could use to forge one
+	var signature *string
+	if signingKeySet() {
+		sig := signPayload(data)
+		signature = &sig
+	}
+
+	_, err = tx.Exec(`
+		UPDATE review_campaigns SET status = 'COMPLETED', completed_at = ?, report = ?, report_signature = ?
+		WHERE id = ?`, now, string(data), signature, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Summary, tx.Commit()
+}
+
+// snapshotReviewScope collects the role assignments, group memberships and live grants
+// of active users that fall within a campaign's scope
+func snapshotReviewScope(tx *sql.Tx, scopeType string, scopeID *int, scopeValue *string) ([]reviewSnapshotItem, error) {
+	roles := `
+		SELECT ur.user_id, 'role', ur.role_id, NULL, NULL, NULL
+		FROM user_roles ur JOIN users u ON ur.user_id = u.id
+		WHERE u.is_active = 1`
+	groups := `
+		SELECT ugm.user_id, 'group', ugm.group_id, NULL, NULL, NULL
+		FROM user_group_members ugm JOIN users u ON ugm.user_id = u.id
+		WHERE u.is_active = 1`
+	grants := `
+		SELECT ar.user_id, 'grant', ar.id, ar.target_type, ar.target_id, ar.access_level
+		FROM access_requests ar JOIN users u ON ar.user_id = u.id
+		WHERE u.is_active = 1 AND ar.status = 'APPROVED' AND (ar.expires_at IS NULL OR ar.expires_at > ?)`
+	now := time.Now()
+
+	type scopedQuery struct {
+		query string
+		args  []interface{}
+	}
+	var queries []scopedQuery
+
+	switch scopeType {
+	case "all":
+		queries = []scopedQuery{{roles, nil}, {groups, nil}, {grants, []interface{}{now}}}
+	case "department":
+		queries = []scopedQuery{
+			{roles + " AND u.department = ?", []interface{}{*scopeValue}},
+			{groups + " AND u.department = ?", []interface{}{*scopeValue}},
+			{grants + " AND u.department = ?", []interface{}{now, *scopeValue}},
+		}
+	case "role":
+		queries = []scopedQuery{{roles + " AND ur.role_id = ?", []interface{}{*scopeID}}}
+	case "group":
+		queries = []scopedQuery{
+			{groups + " AND ugm.group_id = ?", []interface{}{*scopeID}},
+			{grants + " AND ar.target_type = 'group' AND ar.target_id = ?", []interface{}{now, *scopeID}},
+		}
+	case "tool":
+		queries = []scopedQuery{
+			{roles + " AND ur.role_id IN (SELECT role_id FROM role_tool_access WHERE tool_id = ?)", []interface{}{*scopeID}},
+			{groups + " AND ugm.group_id IN (SELECT group_id FROM group_tool_access WHERE tool_id = ?)", []interface{}{*scopeID}},
+			{grants + " AND ar.target_type = 'tool' AND ar.target_id = ?", []interface{}{now, *scopeID}},
+		}
+	}
+
+	var items []reviewSnapshotItem
+	for _, q := range queries {
+		rows, err := tx.Query(q.query, q.args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var item reviewSnapshotItem
+			if err := rows.Scan(&item.UserID, &item.EntitlementType, &item.EntitlementID,
+				&item.TargetType, &item.TargetID, &item.AccessLevel); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		rows.Close()
+	}
+	return items, nil
+}
+
+// assignReviewer picks who reviews an item: an owner of the tool concerned, then the
+// user's manager, then the person who launched the campaign. Users never review
+// their own access when anyone else is available.
+func assignReviewer(tx *sql.Tx, item reviewSnapshotItem, scopeToolID *int, fallback int) int {
+	toolID := scopeToolID
+	if item.TargetType != nil && *item.TargetType == "tool" {
+		toolID = item.TargetID
+	}
+
+	var reviewerID int
+	if toolID != nil {
+		err := tx.QueryRow(`
+			SELECT owner_id FROM tool_owners
+			WHERE tool_id = ? AND owner_type = 'user' AND owner_id != ?
+			UNION ALL
+			SELECT ugm.user_id FROM tool_owners o
+			JOIN user_group_members ugm ON o.owner_id = ugm.group_id
+			WHERE o.tool_id = ? AND o.owner_type = 'group' AND ugm.user_id != ?
+			LIMIT 1`, *toolID, item.UserID, *toolID, item.UserID).Scan(&reviewerID)
+		if err == nil {
+			return reviewerID
+		}
+	}
+
+	var managerID *int
+	tx.QueryRow("SELECT manager_id FROM users WHERE id = ?", item.UserID).Scan(&managerID)
+	if managerID != nil && *managerID != item.UserID {
+		return *managerID
+	}
+
+	return fallback
+}
+
+// revokeReviewedAccess removes the entitlement behind a review item. Access that is
+// already gone is not an error.
+func revokeReviewedAccess(tx *sql.Tx, item models.ReviewItem) error {
+	switch item.EntitlementType {
+	case "role":
+		_, err := tx.Exec("DELETE FROM user_roles WHERE user_id = ? AND role_id = ?", item.UserID, item.EntitlementID)
+		return err
+	case "group":
+		_, err := tx.Exec("DELETE FROM user_group_members WHERE user_id = ? AND group_id = ?", item.UserID, item.EntitlementID)
+		return err
+	case "grant":
+		err := transitionRequest(tx, item.EntitlementID, StatusRevoked, "")
+		var te *transitionError
+		if errors.As(err, &te) || errors.Is(err, errRequestNotFound) {
+			return nil
+		}
+		return err
+	}
+	return fmt.Errorf("unknown entitlement type %q", item.EntitlementType)
+}
+
+func loadReviewItem(itemID int) (models.ReviewItem, string, error) {
+	var item models.ReviewItem
+	var status string
+	err := database.DB.QueryRow(`
+		SELECT i.id, i.campaign_id, i.user_id, i.entitlement_type, i.entitlement_id, i.reviewer_id, i.decision, c.status
+		FROM review_items i
+		JOIN review_campaigns c ON i.campaign_id = c.id
+		WHERE i.id = ?`, itemID).Scan(
+		&item.ID, &item.CampaignID, &item.UserID, &item.EntitlementType, &item.EntitlementID,
+		&item.ReviewerID, &item.Decision, &status)
+	return item, status, err
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryReviewItems(q queryer, query string, args ...interface{}) ([]models.ReviewItem, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ReviewItem{}
+	for rows.Next() {
+		var item models.ReviewItem
+		var entitlementName *string
+		if err := rows.Scan(&item.ID, &item.CampaignID, &item.UserID, &item.EntitlementType, &item.EntitlementID,
+			&item.TargetType, &item.TargetID, &item.AccessLevel, &item.ReviewerID, &item.Decision, &item.Comment,
+			&item.DecidedBy, &item.DecidedAt, &item.CreatedAt, &item.UserEmail, &item.ReviewerEmail,
+			&entitlementName); err != nil {
+			return nil, err
+		}
+		if entitlementName != nil {
+			item.EntitlementName = *entitlementName
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func writeReviewItems(w http.ResponseWriter, query string, args ...interface{}) {
+	items, err := queryReviewItems(database.DB, query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch review items", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+const campaignColumns = `
+	SELECT c.id, c.name, c.description, c.scope_type, c.scope_id, c.scope_value, c.status, c.due_at,
+		   c.revoke_on_no_response, c.created_by, c.created_at, c.completed_at, c.report_signature,
+		   (SELECT COUNT(*) FROM review_items WHERE campaign_id = c.id),
+		   (SELECT COUNT(*) FROM review_items WHERE campaign_id = c.id AND decision IS NOT NULL),
+		   (SELECT COUNT(*) FROM review_items WHERE campaign_id = c.id AND decision = 'revoke')
+	FROM review_campaigns c`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCampaign(row rowScanner) (models.ReviewCampaign, error) {
+	var c models.ReviewCampaign
+	err := row.Scan(&c.ID, &c.Name, &c.Description, &c.ScopeType, &c.ScopeID, &c.ScopeValue, &c.Status, &c.DueAt,
+		&c.RevokeOnNoResponse, &c.CreatedBy, &c.CreatedAt, &c.CompletedAt, &c.ReportSignature,
+		&c.TotalItems, &c.DecidedItems, &c.RevokedItems)
+	return c, err
+}
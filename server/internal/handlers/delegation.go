@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	authMiddleware "gatekeepr/internal/middleware"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListDelegations returns the delegations the current user created or received
+func ListDelegations(w http.ResponseWriter, r *http.Request) {
+	actorID := GetActorID(r)
+
+	query := `
+		SELECT d.id, d.delegator_id, d.delegate_id, d.starts_at, d.ends_at, d.reason, d.revoked_at, d.created_at,
+			   dr.email, de.email
+		FROM approval_delegations d
+		JOIN users dr ON d.delegator_id = dr.id
+		JOIN users de ON d.delegate_id = de.id`
+	args := []interface{}{}
+
+	switch r.URL.Query().Get("role") {
+	case "delegator":
+		query += " WHERE d.delegator_id = ?"
+		args = append(args, actorID)
+	case "delegate":
+		query += " WHERE d.delegate_id = ?"
+		args = append(args, actorID)
+	default:
+		query += " WHERE (d.delegator_id = ? OR d.delegate_id = ?)"
+		args = append(args, actorID, actorID)
+	}
+	if r.URL.Query().Get("active") == "true" {
+		now := time.Now()
+		query += " AND d.revoked_at IS NULL AND d.starts_at <= ? AND d.ends_at > ?"
+		args = append(args, now, now)
+	}
+	query += " ORDER BY d.starts_at DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch delegations", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var delegations []models.ApprovalDelegation
+	for rows.Next() {
+		var d models.ApprovalDelegation
+		if err := rows.Scan(&d.ID, &d.DelegatorID, &d.DelegateID, &d.StartsAt, &d.EndsAt, &d.Reason, &d.RevokedAt,
+			&d.CreatedAt, &d.DelegatorEmail, &d.DelegateEmail); err != nil {
+			http.Error(w, "Failed to scan delegation", http.StatusInternalServerError)
+			return
+		}
+		delegations = append(delegations, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delegations)
+}
+
+// CreateDelegation lets an approver hand their approvals to a delegate for a date range.
+// The delegate must sit at or above the approver's hierarchy level.
+func CreateDelegation(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	actorID := GetActorID(r)
+
+	if req.DelegateID == 0 || req.StartsAt == "" || req.EndsAt == "" {
+		http.Error(w, "delegate_id, starts_at and ends_at are required", http.StatusBadRequest)
+		return
+	}
+	if req.DelegateID == actorID {
+		http.Error(w, "You cannot delegate to yourself", http.StatusBadRequest)
+		return
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, req.StartsAt)
+	if err != nil {
+		http.Error(w, "starts_at must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	endsAt, err := time.Parse(time.RFC3339, req.EndsAt)
+	if err != nil {
+		http.Error(w, "ends_at must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	if !endsAt.After(startsAt) || !endsAt.After(time.Now()) {
+		http.Error(w, "ends_at must be after starts_at and in the future", http.StatusBadRequest)
+		return
+	}
+
+	if !hasApprovalRights(actorID) {
+		http.Error(w, "Only users who approve requests can delegate approvals", http.StatusForbidden)
+		return
+	}
+
+	var delegateActive bool
+	if err := database.DB.QueryRow("SELECT is_active FROM users WHERE id = ?", req.DelegateID).Scan(&delegateActive); err != nil {
+		http.Error(w, "Delegate not found", http.StatusNotFound)
+		return
+	}
+	if !delegateActive {
+		http.Error(w, "Delegate is not an active user", http.StatusBadRequest)
+		return
+	}
+
+	required := authMiddleware.GetUserMaxHierarchy(actorID)
+	if authMiddleware.GetUserMaxHierarchy(req.DelegateID) < required {
+		http.Error(w, fmt.Sprintf("Delegate must hold a role at hierarchy level %d or above", required), http.StatusForbidden)
+		return
+	}
+
+	// Stored in server local time so range checks compare consistently with time.Now()
+	result, err := database.DB.Exec(`
+		INSERT INTO approval_delegations (delegator_id, delegate_id, starts_at, ends_at, reason)
+		VALUES (?, ?, ?, ?, ?)`,
+		actorID, req.DelegateID, startsAt.Local(), endsAt.Local(), req.Reason)
+	if err != nil {
+		http.Error(w, "Failed to create delegation", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "delegation.create", "approval_delegation", int(id), "", nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Delegation created successfully"})
+}
+
+// RevokeDelegation ends a delegation early
+func RevokeDelegation(w http.ResponseWriter, r *http.Request) {
+	delegationID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var delegatorID int
+	var revokedAt *time.Time
+	err := database.DB.QueryRow("SELECT delegator_id, revoked_at FROM approval_delegations WHERE id = ?", delegationID).
+		Scan(&delegatorID, &revokedAt)
+	if err != nil {
+		http.Error(w, "Delegation not found", http.StatusNotFound)
+		return
+	}
+	if delegatorID != GetActorID(r) {
+		http.Error(w, "You can only revoke your own delegations", http.StatusForbidden)
+		return
+	}
+	if revokedAt != nil {
+		http.Error(w, "Delegation has already been revoked", http.StatusConflict)
+		return
+	}
+
+	before, after, err := execWithSnapshots("approval_delegations", delegationID,
+		"UPDATE approval_delegations SET revoked_at = ? WHERE id = ?", time.Now(), delegationID)
+	if err != nil {
+		http.Error(w, "Failed to revoke delegation", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "delegation.revoke", "approval_delegation", delegationID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Delegation revoked successfully"})
+}
+
+// resolveRequestDecider checks if a user may decide a request. When they may only do
+// so as a delegate, onBehalfOf is the approver they act for; otherwise it is 0.
+func resolveRequestDecider(userID int, requestID int, targetType string, targetID int) (onBehalfOf int, ok bool) {
+	if userID == 0 {
+		return 0, false
+	}
+	if userCanDecideRequest(userID, requestID, targetType, targetID) {
+		return 0, true
+	}
+	for _, delegatorID := range activeDelegators(userID) {
+		if userCanDecideRequest(delegatorID, requestID, targetType, targetID) {
+			return delegatorID, true
+		}
+	}
+	return 0, false
+}
+
+// activeDelegators returns the users whose approvals are currently delegated to a user.
+// A delegation stops counting if the delegate has since dropped below the delegator's
+// hierarchy level.
+func activeDelegators(delegateID int) []int {
+	now := time.Now()
+	ids := queryUserIDs(`
+		SELECT DISTINCT delegator_id FROM approval_delegations
+		WHERE delegate_id = ? AND revoked_at IS NULL AND starts_at <= ? AND ends_at > ?`,
+		delegateID, now, now)
+
+	level := authMiddleware.GetUserMaxHierarchy(delegateID)
+	var delegators []int
+	for _, id := range ids {
+		if level >= authMiddleware.GetUserMaxHierarchy(id) {
+			delegators = append(delegators, id)
+		}
+	}
+	return delegators
+}
+
+// activeDelegates returns the users currently acting on behalf of a delegator
+func activeDelegates(delegatorID int) []int {
+	now := time.Now()
+	ids := queryUserIDs(`
+		SELECT DISTINCT delegate_id FROM approval_delegations
+		WHERE delegator_id = ? AND revoked_at IS NULL AND starts_at <= ? AND ends_at > ?`,
+		delegatorID, now, now)
+
+	required := authMiddleware.GetUserMaxHierarchy(delegatorID)
+	var delegates []int
+	for _, id := range ids {
+		if authMiddleware.GetUserMaxHierarchy(id) >= required {
+			delegates = append(delegates, id)
+		}
+	}
+	return delegates
+}
+
+// hasApprovalRights checks if a user approves requests through a role or by owning a tool
+func hasApprovalRights(userID int) bool {
+	if userCanApproveRequests(userID) {
+		return true
+	}
+
+	var ownsTool bool
+	database.DB.QueryRow(`SELECT EXISTS(`+ownedToolsSubquery+`)`, userID, userID).Scan(&ownsTool)
+	return ownsTool
+}
+
+// delegationNote describes a decision taken by a delegate, e.g.
+// "Approved by alice@example.com on behalf of bob@example.com"
+func delegationNote(verb string, delegateID int, delegatorID int) string {
+	var delegateEmail, delegatorEmail string
+	database.DB.QueryRow("SELECT email FROM users WHERE id = ?", delegateID).Scan(&delegateEmail)
+	database.DB.QueryRow("SELECT email FROM users WHERE id = ?", delegatorID).Scan(&delegatorEmail)
+	return fmt.Sprintf("%s by %s on behalf of %s", verb, delegateEmail, delegatorEmail)
+}
+
+// decisionNote explains a decision for its audit entry: who it was made on behalf of
+// and through which channel. It is empty for a plain decision in the UI.
+func decisionNote(verb string, deciderID int, onBehalfOf int, via string) string {
+	var notes []string
+	if onBehalfOf != 0 {
+		notes = append(notes, delegationNote(verb, deciderID, onBehalfOf))
+	}
+	if via != "" {
+		notes = append(notes, verb+" via "+via)
+	}
+	return joinStrings(notes, "; ")
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// toolGrant is an approved tool grant checked for dormancy
+type toolGrant struct {
+	models.DormantGrant
+	ToolCategory *string
+	CreatedAt    time.Time
+}
+
+// lastActivity is when the grant was last used, or granted if it never was
+func (g toolGrant) lastActivity() time.Time {
+	if g.LastUsedAt != nil {
+		return *g.LastUsedAt
+	}
+	if g.ApprovedAt != nil {
+		return *g.ApprovedAt
+	}
+	return g.CreatedAt
+}
+
+// ListDormancyPolicies returns all dormancy policies
+func ListDormancyPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := loadDormancyPolicies(false)
+	if err != nil {
+		http.Error(w, "Failed to fetch policies", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// CreateDormancyPolicy creates a new dormancy policy
+func CreateDormancyPolicy(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDormancyPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.InactiveDays <= 0 {
+		http.Error(w, "name and inactive_days are required", http.StatusBadRequest)
+		return
+	}
+	if req.Action == "" {
+		req.Action = "flag"
+	}
+	if req.Action != "flag" && req.Action != "revoke" {
+		http.Error(w, "action must be flag or revoke", http.StatusBadRequest)
+		return
+	}
+	noticeDays := 7
+	if req.NoticeDays != nil {
+		noticeDays = *req.NoticeDays
+	}
+	if noticeDays < 0 || noticeDays > req.InactiveDays {
+		http.Error(w, "notice_days must be between 0 and inactive_days", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO dormancy_policies (name, tool_id, tool_category, inactive_days, action, notice_days, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.ToolID, req.ToolCategory, req.InactiveDays, req.Action, noticeDays, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create policy", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "dormancy.policy.create", "dormancy_policy", int(id), req.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Policy created successfully"})
+}
+
+// UpdateDormancyPolicy updates an existing dormancy policy
+func UpdateDormancyPolicy(w http.ResponseWriter, r *http.Request) {
+	policyID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateDormancyPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.ToolID != nil {
+		updates = append(updates, "tool_id = ?")
+		args = append(args, nullableID(*req.ToolID))
+	}
+	if req.ToolCategory != nil {
+		updates = append(updates, "tool_category = ?")
+		args = append(args, nullableString(*req.ToolCategory))
+	}
+	if req.InactiveDays != nil {
+		if *req.InactiveDays <= 0 {
+			http.Error(w, "inactive_days must be positive", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "inactive_days = ?")
+		args = append(args, *req.InactiveDays)
+	}
+	if req.Action != nil {
+		if *req.Action != "flag" && *req.Action != "revoke" {
+			http.Error(w, "action must be flag or revoke", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "action = ?")
+		args = append(args, *req.Action)
+	}
+	if req.NoticeDays != nil {
+		if *req.NoticeDays < 0 {
+			http.Error(w, "notice_days cannot be negative", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "notice_days = ?")
+		args = append(args, *req.NoticeDays)
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	// The notice window must still fit inside the inactivity threshold once merged
+	var inactiveDays, noticeDays int
+	err := database.DB.QueryRow("SELECT inactive_days, notice_days FROM dormancy_policies WHERE id = ?", policyID).
+		Scan(&inactiveDays, &noticeDays)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Policy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load policy", http.StatusInternalServerError)
+		return
+	}
+	if req.InactiveDays != nil {
+		inactiveDays = *req.InactiveDays
+	}
+	if req.NoticeDays != nil {
+		noticeDays = *req.NoticeDays
+	}
+	if noticeDays > inactiveDays {
+		http.Error(w, "notice_days must be between 0 and inactive_days", http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE dormancy_policies SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, policyID)
+
+	before, after, err := execWithSnapshots("dormancy_policies", policyID, query, args...)
+	if err != nil {
+		http.Error(w, "Failed to update policy", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "dormancy.policy.update", "dormancy_policy", policyID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Policy updated successfully"})
+}
+
+// DeleteDormancyPolicy deletes a dormancy policy
+func DeleteDormancyPolicy(w http.ResponseWriter, r *http.Request) {
+	policyID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var policyName string
+	database.DB.QueryRow("SELECT name FROM dormancy_policies WHERE id = ?", policyID).Scan(&policyName)
+
+	before, _, err := execWithSnapshots("dormancy_policies", policyID, "DELETE FROM dormancy_policies WHERE id = ?", policyID)
+	if err != nil {
+		http.Error(w, "Failed to delete policy", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "dormancy.policy.delete", "dormancy_policy", policyID, policyName, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Policy deleted successfully"})
+}
+
+// GetDormancyReport lists tool grants unused and active accounts without a login for
+// at least ?days= days (default 90)
+func GetDormancyReport(w http.ResponseWriter, r *http.Request) {
+	days := 90
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = d
+	}
+	now := time.Now()
+	threshold := daysDuration(days)
+
+	grants, err := loadToolGrants()
+	if err != nil {
+		http.Error(w, "Failed to fetch grants", http.StatusInternalServerError)
+		return
+	}
+
+	dormantGrants := []models.DormantGrant{}
+	for _, g := range grants {
+		idle := now.Sub(g.lastActivity())
+		if idle < threshold {
+			continue
+		}
+		g.IdleDays = int(idle.Hours() / 24)
+		dormantGrants = append(dormantGrants, g.DormantGrant)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, email, last_login_at, created_at FROM users
+		WHERE is_active = 1
+		ORDER BY id`)
+	if err != nil {
+		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	dormantAccounts := []models.DormantAccount{}
+	for rows.Next() {
+		var a models.DormantAccount
+		if err := rows.Scan(&a.UserID, &a.Email, &a.LastLoginAt, &a.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan user", http.StatusInternalServerError)
+			return
+		}
+		last := a.CreatedAt
+		if a.LastLoginAt != nil {
+			last = *a.LastLoginAt
+		}
+		idle := now.Sub(last)
+		if idle < threshold {
+			continue
+		}
+		a.IdleDays = int(idle.Hours() / 24)
+		dormantAccounts = append(dormantAccounts, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":             days,
+		"dormant_grants":   dormantGrants,
+		"dormant_accounts": dormantAccounts,
+	})
+}
+
+// StartDormancyWorker applies dormancy policies every interval until ctx is cancelled
+func StartDormancyWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "Dormancy check", interval, processDormancy)
+}
+
+// processDormancy warns users whose grants are about to go dormant and, once the
+// notice period has passed without use, flags or revokes them
+func processDormancy(now time.Time) error {
+	policies, err := loadDormancyPolicies(true)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+
+	grants, err := loadToolGrants()
+	if err != nil {
+		return err
+	}
+
+	for _, g := range grants {
+		policy := resolveDormancyPolicy(policies, g)
+		if policy == nil {
+			continue
+		}
+
+		idle := now.Sub(g.lastActivity())
+		threshold := daysDuration(policy.InactiveDays)
+		notice := daysDuration(policy.NoticeDays)
+
+		if g.NotifiedAt == nil {
+			if idle >= threshold-notice {
+				warnDormantGrant(g, policy, now)
+			}
+			continue
+		}
+
+		if idle < threshold || now.Sub(*g.NotifiedAt) < notice {
+			continue
+		}
+
+		if policy.Action == "revoke" {
+			revokeDormantGrant(g, policy)
+		} else if g.FlaggedAt == nil {
+			flagDormantGrant(g, policy, now)
+		}
+	}
+	return nil
+}
+
+func warnDormantGrant(g toolGrant, policy *models.DormancyPolicy, now time.Time) {
+	if _, err := database.DB.Exec("UPDATE access_requests SET dormant_notified_at = ? WHERE id = ?", now, g.RequestID); err != nil {
+		log.Printf("Failed to record dormancy notice for request %d: %v", g.RequestID, err)
+		return
+	}
+
+	verb := "flagged for review"
+	if policy.Action == "revoke" {
+		verb = "revoked"
+	}
+	when := "shortly"
+	if policy.NoticeDays > 0 {
+		when = fmt.Sprintf("in %d days", policy.NoticeDays)
+	}
+	notifyUsers([]int{g.UserID},
+		fmt.Sprintf("Your access to %s is unused", g.ToolName),
+		fmt.Sprintf("You have not used %s in %d days. Your %s access will be %s %s unless you use it.",
+			g.ToolName, int(now.Sub(g.lastActivity()).Hours()/24), g.AccessLevel, verb, when))
+}
+
+func flagDormantGrant(g toolGrant, policy *models.DormancyPolicy, now time.Time) {
+	if _, err := database.DB.Exec("UPDATE access_requests SET dormant_flagged_at = ? WHERE id = ?", now, g.RequestID); err != nil {
+		log.Printf("Failed to flag request %d as dormant: %v", g.RequestID, err)
+		return
+	}
+
+	LogSystemAudit("access.dormant.flag", "access_request", g.RequestID, g.ToolName,
+		fmt.Sprintf("Unused for %d days (dormancy policy %s)", policy.InactiveDays, policy.Name), nil, nil)
+}
+
+func revokeDormantGrant(g toolGrant, policy *models.DormancyPolicy) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to revoke dormant request %d: %v", g.RequestID, err)
+		return
+	}
+	if err := transitionRequest(tx, g.RequestID, StatusRevoked, ""); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to revoke dormant request %d: %v", g.RequestID, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to revoke dormant request %d: %v", g.RequestID, err)
+		return
+	}
+
+	reason := fmt.Sprintf("Unused for %d days (dormancy policy %s)", policy.InactiveDays, policy.Name)
+	LogSystemAudit("access.dormant.revoke", "access_request", g.RequestID, g.ToolName, reason,
+		map[string]string{"status": StatusApproved}, map[string]string{"status": StatusRevoked})
+
+	notifyUsers([]int{g.UserID},
+		fmt.Sprintf("Your access to %s was revoked", g.ToolName),
+		fmt.Sprintf("Your %s access to %s was revoked: %s. Request it again if you still need it.",
+			g.AccessLevel, g.ToolName, reason))
+}
+
+// resolveDormancyPolicy picks the policy for a grant: a policy for its tool wins over
+// one for its tool category, which wins over the default policy
+func resolveDormancyPolicy(policies []models.DormancyPolicy, g toolGrant) *models.DormancyPolicy {
+	var byCategory, fallback *models.DormancyPolicy
+	for i := range policies {
+		p := &policies[i]
+		switch {
+		case p.ToolID != nil:
+			if *p.ToolID == g.ToolID {
+				return p
+			}
+		case p.ToolCategory != nil:
+			if byCategory == nil && g.ToolCategory != nil && *p.ToolCategory == *g.ToolCategory {
+				byCategory = p
+			}
+		default:
+			if fallback == nil {
+				fallback = p
+			}
+		}
+	}
+	if byCategory != nil {
+		return byCategory
+	}
+	return fallback
+}
+
+func loadDormancyPolicies(activeOnly bool) ([]models.DormancyPolicy, error) {
+	query := `
+		SELECT id, name, tool_id, tool_category, inactive_days, action, notice_days,
+			   is_active, created_by, created_at, updated_at
+		FROM dormancy_policies`
+	if activeOnly {
+		query += " WHERE is_active = 1"
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []models.DormancyPolicy
+	for rows.Next() {
+		var p models.DormancyPolicy
+		if err := rows.Scan(&p.ID, &p.Name, &p.ToolID, &p.ToolCategory, &p.InactiveDays, &p.Action, &p.NoticeDays,
+			&p.IsActive, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// loadToolGrants returns the live tool grants of active users with their last use
+func loadToolGrants() ([]toolGrant, error) {
+	rows, err := database.DB.Query(`
+		SELECT ar.id, ar.user_id, u.email, ar.target_id, t.display_name, t.category, ar.access_level,
+			   ar.approved_at, ar.created_at, tu.last_used_at, ar.dormant_notified_at, ar.dormant_flagged_at
+		FROM access_requests ar
+		JOIN users u ON ar.user_id = u.id
+		JOIN tools t ON ar.target_id = t.id
+		LEFT JOIN tool_usage tu ON tu.user_id = ar.user_id AND tu.tool_id = ar.target_id
+		WHERE ar.target_type = 'tool' AND ar.status = 'APPROVED' AND u.is_active = 1
+		  AND (ar.expires_at IS NULL OR ar.expires_at > ?)
+		ORDER BY ar.id`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []toolGrant
+	for rows.Next() {
+		var g toolGrant
+		if err := rows.Scan(&g.RequestID, &g.UserID, &g.UserEmail, &g.ToolID, &g.ToolName, &g.ToolCategory,
+			&g.AccessLevel, &g.ApprovedAt, &g.CreatedAt, &g.LastUsedAt, &g.NotifiedAt, &g.FlaggedAt); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+func daysDuration(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
@@ -136,13 +136,13 @@ func UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	query := "UPDATE user_groups SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
 	args = append(args, groupID)
 
-	_, err := database.DB.Exec(query, args...)
+	before, after, err := execWithSnapshots("user_groups", groupID, query, args...)
 	if err != nil {
 		http.Error(w, "Failed to update group", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "group.update", "group", groupID, "", nil, &req)
+	LogAudit(r, "group.update", "group", groupID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Group updated successfully"})
@@ -156,13 +156,13 @@ func DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	var groupName string
 	database.DB.QueryRow("SELECT name FROM user_groups WHERE id = ?", groupID).Scan(&groupName)
 
-	_, err := database.DB.Exec("DELETE FROM user_groups WHERE id = ?", groupID)
+	before, _, err := execWithSnapshots("user_groups", groupID, "DELETE FROM user_groups WHERE id = ?", groupID)
 	if err != nil {
 		http.Error(w, "Failed to delete group", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "group.delete", "group", groupID, groupName, nil, nil)
+	LogAudit(r, "group.delete", "group", groupID, groupName, before, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Group deleted successfully"})
@@ -220,6 +220,13 @@ func AddGroupMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, userID := range req.UserIDs {
+		proposed := []Entitlement{{Type: "group", ID: groupID}}
+		if !enforceSoD(w, r, userID, "group.members.add", proposed) {
+			return
+		}
+	}
+
 	actorID := GetActorID(r)
 
 	tx, err := database.DB.Begin()
@@ -228,6 +235,13 @@ func AddGroupMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntity(tx, "user_groups", groupID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load group", http.StatusInternalServerError)
+		return
+	}
+
 	for _, userID := range req.UserIDs {
 		_, err = tx.Exec(`
 			INSERT OR IGNORE INTO user_group_members (user_id, group_id, added_by)
@@ -239,12 +253,19 @@ func AddGroupMembers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntity(tx, "user_groups", groupID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load group", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "group.members.add", "group", groupID, "", nil, &req)
+	LogAudit(r, "group.members.add", "group", groupID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Members added successfully"})
@@ -255,13 +276,14 @@ func RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
 	groupID, _ := strconv.Atoi(chi.URLParam(r, "id"))
 	userID, _ := strconv.Atoi(chi.URLParam(r, "userId"))
 
-	_, err := database.DB.Exec("DELETE FROM user_group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	before, after, err := execWithSnapshots("user_groups", groupID,
+		"DELETE FROM user_group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
 	if err != nil {
 		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "group.members.remove", "group", groupID, "", map[string]int{"user_id": userID}, nil)
+	LogAudit(r, "group.members.remove", "group", groupID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Member removed successfully"})
@@ -284,6 +306,13 @@ func SetGroupPermissions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntity(tx, "user_groups", groupID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load group", http.StatusInternalServerError)
+		return
+	}
+
 	// Remove existing permissions
 	_, err = tx.Exec("DELETE FROM group_permissions WHERE group_id = ?", groupID)
 	if err != nil {
@@ -302,12 +331,19 @@ func SetGroupPermissions(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntity(tx, "user_groups", groupID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load group", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "group.permissions.update", "group", groupID, "", nil, &req)
+	LogAudit(r, "group.permissions.update", "group", groupID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Permissions updated successfully"})
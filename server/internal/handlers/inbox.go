@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+	"gatekeepr/internal/notify"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// InboxChannelName is the channel name of the in-app inbox
+const InboxChannelName = "inbox"
+
+// inboxHeartbeat is how often an idle notification stream sends a comment line so
+// proxies do not close it
+var inboxHeartbeat = 30 * time.Second
+
+// InboxChannel stores notifications in the recipient's in-app inbox and pushes them to
+// any open notification streams of that user
+type InboxChannel struct{}
+
+func (InboxChannel) Name() string { return InboxChannelName }
+
+func (InboxChannel) Send(ctx context.Context, msg notify.Message) error {
+	var requestID *int
+	if msg.RequestID != 0 {
+		requestID = &msg.RequestID
+	}
+
+	result, err := database.DB.ExecContext(ctx, `
+		INSERT INTO notifications (user_id, event, subject, body, request_id)
+		VALUES (?, ?, ?, ?, ?)`, msg.Recipient.UserID, msg.Event, msg.Subject, msg.Body, requestID)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+
+	inbox.publish(msg.Recipient.UserID, models.Notification{
+		ID:        int(id),
+		UserID:    msg.Recipient.UserID,
+		Event:     msg.Event,
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+		RequestID: requestID,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+// inboxHub fans new notifications out to the open streams of each user
+type inboxHub struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan models.Notification]struct{}
+}
+
+var inbox = &inboxHub{subscribers: map[int]map[chan models.Notification]struct{}{}}
+
+func (h *inboxHub) subscribe(userID int) chan models.Notification {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan models.Notification, 16)
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = map[chan models.Notification]struct{}{}
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	return ch
+}
+
+func (h *inboxHub) unsubscribe(userID int, ch chan models.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[userID], ch)
+	if len(h.subscribers[userID]) == 0 {
+		delete(h.subscribers, userID)
+	}
+}
+
+// publish never blocks: a stream too slow to keep up misses the push but still sees
+// the notification when it next lists the inbox
+func (h *inboxHub) publish(userID int, n models.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+// ListNotifications returns the current user's inbox, newest first. ?unread=true
+// limits it to unread notifications.
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := GetActorID(r)
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	where := "WHERE user_id = ?"
+	if query.Get("unread") == "true" {
+		where += " AND read_at IS NULL"
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM notifications "+where, userID).Scan(&total); err != nil {
+		http.Error(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, event, subject, body, request_id, read_at, created_at
+		FROM notifications `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`, userID, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Event, &n.Subject, &n.Body, &n.RequestID,
+			&n.ReadAt, &n.CreatedAt); err != nil {
+			http.Error(w, "Failed to scan notification", http.StatusInternalServerError)
+			return
+		}
+		notifications = append(notifications, n)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PaginatedResponse{
+		Data:       notifications,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (total + limit - 1) / limit,
+	})
+}
+
+// GetUnreadNotificationCount returns how many notifications the current user has not read
+func GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	count, err := unreadNotificationCount(GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"unread": count})
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	result, err := database.DB.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, ?)
+		WHERE id = ? AND user_id = ?`, time.Now(), notificationID, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the current user as read
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	result, err := database.DB.Exec(`
+		UPDATE notifications SET read_at = ?
+		WHERE user_id = ? AND read_at IS NULL`, time.Now(), GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+	n, _ := result.RowsAffected()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Notifications marked as read",
+		"updated": n,
+	})
+}
+
+// DeleteNotification removes one notification from the current user's inbox
+func DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	notificationID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	result, err := database.DB.Exec("DELETE FROM notifications WHERE id = ? AND user_id = ?",
+		notificationID, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification deleted successfully"})
+}
+
+// ClearNotifications empties the current user's inbox. ?read=true only removes
+// notifications that were already read.
+func ClearNotifications(w http.ResponseWriter, r *http.Request) {
+	query := "DELETE FROM notifications WHERE user_id = ?"
+	if r.URL.Query().Get("read") == "true" {
+		query += " AND read_at IS NOT NULL"
+	}
+
+	result, err := database.DB.Exec(query, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to clear notifications", http.StatusInternalServerError)
+		return
+	}
+	n, _ := result.RowsAffected()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Notifications cleared",
+		"deleted": n,
+	})
+}
+
+// StreamNotifications is a Server-Sent Events stream of the current user's new
+// notifications. It opens with an "unread" event carrying the unread count, then sends
+// a "notification" event for each new notification.
+func StreamNotifications(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	userID := GetActorID(r)
+
+	ch := inbox.subscribe(userID)
+	defer inbox.unsubscribe(userID, ch)
+
+	count, err := unreadNotificationCount(userID)
+	if err != nil {
+		http.Error(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent(w, "unread", map[string]int{"unread": count})
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(inboxHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case n := <-ch:
+			writeEvent(w, "notification", n)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+func unreadNotificationCount(userID int) (int, error) {
+	var count int
+	err := database.DB.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).
+		Scan(&count)
+	return count, err
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// startPeriodicJob runs job every interval in the background until ctx is cancelled.
+// Failures are logged and the job is retried on the next tick.
+func startPeriodicJob(ctx context.Context, name string, interval time.Duration, job func(now time.Time) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := job(now); err != nil {
+					log.Printf("%s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+}
+
+// retryBackoff doubles the wait after each failed attempt of a queued call, from 30
+// seconds up to an hour
+func retryBackoff(attempts int) time.Duration {
+	wait := 30 * time.Second
+	for i := 1; i < attempts && wait < time.Hour; i++ {
+		wait *= 2
+	}
+	if wait > time.Hour {
+		wait = time.Hour
+	}
+	return wait
+}
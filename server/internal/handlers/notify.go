@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+	"gatekeepr/internal/notify"
+)
+
+// NotificationChannels deliver notifications. They are configured at startup; with
+// none configured, notifications are written to the server log.
+var NotificationChannels = []notify.Channel{notify.LogChannel{}}
+
+// GetNotificationPreferences lists, for every event and configured channel, whether
+// the current user receives it. Everything is enabled unless turned off.
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := GetActorID(r)
+
+	disabled, err := loadDisabledChannels([]int{userID})
+	if err != nil {
+		http.Error(w, "Failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
+
+	prefs := []models.NotificationPreference{}
+	for _, event := range notify.Events {
+		for _, ch := range NotificationChannels {
+			prefs = append(prefs, models.NotificationPreference{
+				Event:   event,
+				Channel: ch.Name(),
+				Enabled: !disabled[userID][event][ch.Name()],
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdateNotificationPreferences turns events on or off per channel for the current user
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := GetActorID(r)
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	events := map[string]bool{}
+	for _, e := range notify.Events {
+		events[e] = true
+	}
+	channels := map[string]bool{}
+	for _, ch := range NotificationChannels {
+		channels[ch.Name()] = true
+	}
+	for _, p := range req.Preferences {
+		if !events[p.Event] {
+			http.Error(w, fmt.Sprintf("Unknown notification event %q", p.Event), http.StatusBadRequest)
+			return
+		}
+		if !channels[p.Channel] {
+			http.Error(w, fmt.Sprintf("Unknown notification channel %q", p.Channel), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	before, err := snapshotDisabledChannels(tx, userID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load preferences", http.StatusInternalServerError)
+		return
+	}
+	for _, p := range req.Preferences {
+		_, err := tx.Exec(`
+			INSERT INTO notification_preferences (user_id, event, channel, enabled)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id, event, channel) DO UPDATE SET enabled = excluded.enabled`,
+			userID, p.Event, p.Channel, p.Enabled)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to update preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+	after, err := snapshotDisabledChannels(tx, userID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load preferences", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "notification.preferences.update", "user", userID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification preferences updated"})
+}
+
+// snapshotDisabledChannels captures the event:channel pairs a user has turned off, for
+// the audit log
+func snapshotDisabledChannels(q queryer, userID int) (interface{}, error) {
+	disabled, err := querySnapshotSet(q, `SELECT event || ':' || channel FROM notification_preferences
+		WHERE user_id = ? AND NOT enabled ORDER BY 1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"disabled": disabled}, nil
+}
+
+// SendTestNotification sends a test message to the current user on every configured
+// channel, ignoring preferences, and reports how each channel fared
+func SendTestNotification(w http.ResponseWriter, r *http.Request) {
+	recipients := loadRecipients([]int{GetActorID(r)})
+	if len(recipients) == 0 {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	msg := notify.Message{
+		Event:     notify.EventGeneral,
+		Recipient: recipients[0],
+		Subject:   "gatekeepr test notification",
+		Body:      "Notifications are reaching you on this channel.",
+	}
+
+	results := map[string]string{}
+	for _, ch := range NotificationChannels {
+		if err := ch.Send(r.Context(), msg); err != nil {
+			results[ch.Name()] = err.Error()
+			continue
+		}
+		results[ch.Name()] = "sent"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// notifyUsers delivers a one-off notice to the given users
+func notifyUsers(userIDs []int, subject string, body string) {
+	sendNotifications(userIDs, notify.EventGeneral, subject, body)
+}
+
+// notifyRequest tells the given users about an access request event using the
+// event's template
+func notifyRequest(userIDs []int, event string, requestID int) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	notice, err := loadRequestNotice(requestID)
+	if err != nil {
+		log.Printf("Failed to load request %d for %s notification: %v", requestID, event, err)
+		return
+	}
+	subject, body, err := notify.Render(event, notice)
+	if err != nil {
+		log.Printf("Failed to render %s notification: %v", event, err)
+		return
+	}
+	deliverNotifications(userIDs, event, requestID, func(notify.Recipient, notify.Channel) (string, string, error) {
+		return subject, body, nil
+	})
+}
+
+// notifyRequester tells the user behind a request about an event on it
+func notifyRequester(requestID int, event string) {
+	notifyRequest(queryUserIDs("SELECT user_id FROM access_requests WHERE id = ?", requestID), event, requestID)
+}
+
+// notifyRequestApprovers tells everyone who may decide a new request that it is waiting
+func notifyRequestApprovers(requestID int) {
+	var c slaCandidate
+	err := database.DB.QueryRow(`
+		SELECT id, user_id, target_type, target_id, escalation_group_id
+		FROM access_requests WHERE id = ?`, requestID).
+		Scan(&c.ID, &c.UserID, &c.TargetType, &c.TargetID, &c.EscalationGroupID)
+	if err != nil {
+		log.Printf("Failed to load request %d to notify approvers: %v", requestID, err)
+		return
+	}
+	approvers := requestApprovers(c)
+	if len(approvers) == 0 {
+		return
+	}
+
+	notice, err := loadRequestNotice(requestID)
+	if err != nil {
+		log.Printf("Failed to load request %d to notify approvers: %v", requestID, err)
+		return
+	}
+	deliverNotifications(approvers, notify.EventRequestCreated, requestID, func(rcpt notify.Recipient, ch notify.Channel) (string, string, error) {
+		return requestLinkNotice(notice, rcpt, ch)
+	})
+}
+
+// sendNotifications delivers the same message to active users on every channel they
+// have not turned off for the event
+func sendNotifications(userIDs []int, event string, subject string, body string) {
+	deliverNotifications(userIDs, event, 0, func(notify.Recipient, notify.Channel) (string, string, error) {
+		return subject, body, nil
+	})
+}
+
+// deliverNotifications renders a message per recipient and channel and delivers it to
+// active users on every channel they have not turned off for the event. Delivery
+// happens in the background so a slow mail server never holds up a request; failures
+// are logged.
+func deliverNotifications(userIDs []int, event string, requestID int, render func(rcpt notify.Recipient, ch notify.Channel) (string, string, error)) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	recipients := loadRecipients(userIDs)
+	disabled, err := loadDisabledChannels(userIDs)
+	if err != nil {
+		log.Printf("Failed to load notification preferences: %v", err)
+	}
+	channels := NotificationChannels
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for _, rcpt := range recipients {
+			for _, ch := range channels {
+				if disabled[rcpt.UserID][event][ch.Name()] {
+					continue
+				}
+				subject, body, err := render(rcpt, ch)
+				if err != nil {
+					log.Printf("Failed to render %s notification: %v", event, err)
+					continue
+				}
+				msg := notify.Message{Event: event, Recipient: rcpt, Subject: subject, Body: body, RequestID: requestID}
+				if err := ch.Send(ctx, msg); err != nil {
+					log.Printf("Failed to send %s notification to %s via %s: %v", event, rcpt.Email, ch.Name(), err)
+				}
+			}
+		}
+	}()
+}
+
+// loadRecipients returns the active users among userIDs
+func loadRecipients(userIDs []int) []notify.Recipient {
+	var recipients []notify.Recipient
+	for _, id := range userIDs {
+		var rcpt notify.Recipient
+		var firstName, lastName *string
+		err := database.DB.QueryRow(`
+			SELECT id, email, first_name, last_name FROM users
+			WHERE id = ? AND is_active = 1`, id).Scan(&rcpt.UserID, &rcpt.Email, &firstName, &lastName)
+		if err != nil {
+			continue
+		}
+		if firstName != nil {
+			rcpt.Name = *firstName
+			if lastName != nil {
+				rcpt.Name += " " + *lastName
+			}
+		}
+		recipients = append(recipients, rcpt)
+	}
+	return recipients
+}
+
+// loadDisabledChannels maps user, event and channel to true for every channel a user
+// turned off
+func loadDisabledChannels(userIDs []int) (map[int]map[string]map[string]bool, error) {
+	disabled := map[int]map[string]map[string]bool{}
+	for _, id := range userIDs {
+		rows, err := database.DB.Query(`
+			SELECT event, channel FROM notification_preferences
+			WHERE user_id = ? AND enabled = 0`, id)
+		if err != nil {
+			return disabled, err
+		}
+		for rows.Next() {
+			var event, channel string
+			if err := rows.Scan(&event, &channel); err != nil {
+				rows.Close()
+				return disabled, err
+			}
+			if disabled[id] == nil {
+				disabled[id] = map[string]map[string]bool{}
+			}
+			if disabled[id][event] == nil {
+				disabled[id][event] = map[string]bool{}
+			}
+			disabled[id][event][channel] = true
+		}
+		rows.Close()
+	}
+	return disabled, nil
+}
+
+// loadRequestNotice gathers what the request templates show about a request
+func loadRequestNotice(requestID int) (notify.RequestNotice, error) {
+	var n notify.RequestNotice
+	var targetID int
+	err := database.DB.QueryRow(`
+		SELECT ar.id, u.email, ar.target_type, ar.target_id, ar.access_level,
+			   COALESCE(ar.reason, ''), COALESCE(ar.rejection_reason, ''), ar.expires_at,
+			   COALESCE(d.email, ''),
+			   COALESCE(CASE ar.target_type
+				   WHEN 'tool' THEN (SELECT display_name FROM tools WHERE id = ar.target_id)
+				   WHEN 'role' THEN (SELECT display_name FROM roles WHERE id = ar.target_id)
+				   WHEN 'group' THEN (SELECT display_name FROM user_groups WHERE id = ar.target_id)
+			   END, '')
+		FROM access_requests ar
+		JOIN users u ON ar.user_id = u.id
+		LEFT JOIN users d ON d.id = COALESCE(ar.approved_by, ar.rejected_by)
+		WHERE ar.id = ?`, requestID).
+		Scan(&n.RequestID, &n.Requester, &n.TargetType, &targetID, &n.AccessLevel,
+			&n.Reason, &n.RejectionReason, &n.ExpiresAt, &n.DecidedBy, &n.TargetName)
+	if err != nil {
+		return n, err
+	}
+	if n.TargetName == "" {
+		n.TargetName = fmt.Sprintf("%s %d", n.TargetType, targetID)
+	}
+	if n.ExpiresAt != nil {
+		t := n.ExpiresAt.Local()
+		n.ExpiresAt = &t
+	}
+	return n, nil
+}
+
+// queryUserIDs runs a query returning a single user ID column and collects the results
+func queryUserIDs(query string, args ...interface{}) []int {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
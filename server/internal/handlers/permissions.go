@@ -136,13 +136,13 @@ func UpdatePermission(w http.ResponseWriter, r *http.Request) {
 	query := "UPDATE permissions SET " + joinStrings(updates, ", ") + " WHERE id = ?"
 	args = append(args, permID)
 
-	_, err := database.DB.Exec(query, args...)
+	before, after, err := execWithSnapshots("permissions", permID, query, args...)
 	if err != nil {
 		http.Error(w, "Failed to update permission", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "permission.update", "permission", permID, "", nil, &req)
+	LogAudit(r, "permission.update", "permission", permID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Permission updated successfully"})
@@ -156,13 +156,13 @@ func DeletePermission(w http.ResponseWriter, r *http.Request) {
 	var permName string
 	database.DB.QueryRow("SELECT name FROM permissions WHERE id = ?", permID).Scan(&permName)
 
-	_, err := database.DB.Exec("DELETE FROM permissions WHERE id = ?", permID)
+	before, _, err := execWithSnapshots("permissions", permID, "DELETE FROM permissions WHERE id = ?", permID)
 	if err != nil {
 		http.Error(w, "Failed to delete permission", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "permission.delete", "permission", permID, permName, nil, nil)
+	LogAudit(r, "permission.delete", "permission", permID, permName, before, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Permission deleted successfully"})
@@ -0,0 +1,547 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/connectors"
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Provisioning job actions
+const (
+	ProvisionAction   = "provision"
+	DeprovisionAction = "deprovision"
+)
+
+// Provisioning job statuses
+const (
+	JobPending   = "PENDING"
+	JobSucceeded = "SUCCEEDED"
+	JobFailed    = "FAILED"
+)
+
+// Provisioning statuses shown on access requests
+const (
+	ProvisioningPending       = "PENDING"
+	ProvisioningProvisioned   = "PROVISIONED"
+	ProvisioningDeprovisioned = "DEPROVISIONED"
+	ProvisioningFailed        = "FAILED"
+)
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// provisioningJob is a due job together with the connector that runs it
+type provisioningJob struct {
+	ID              int
+	ConnectorID     int
+	AccessRequestID int
+	Action          string
+	Attempts        int
+	ConnectorType   string
+	Config          json.RawMessage
+	MaxAttempts     int
+}
+
+const connectorColumns = `id, name, type, tool_id, config, max_attempts, auto_remediate, is_active, created_by,
+	created_at, updated_at`
+
+// ListConnectors returns all connectors with their secrets redacted
+func ListConnectors(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query("SELECT " + connectorColumns + " FROM connectors ORDER BY name")
+	if err != nil {
+		http.Error(w, "Failed to fetch connectors", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var list []models.Connector
+	for rows.Next() {
+		c, err := scanConnector(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan connector", http.StatusInternalServerError)
+			return
+		}
+		c.Config = connectors.RedactConfig(c.Type, c.Config)
+		list = append(list, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateConnector attaches a connector to a tool. A tool has at most one connector.
+func CreateConnector(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateConnectorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.Type == "" || req.ToolID == 0 || len(req.Config) == 0 {
+		http.Error(w, "name, type, tool_id and config are required", http.StatusBadRequest)
+		return
+	}
+	if _, err := connectors.New(req.Type, req.Config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxAttempts := 5
+	if req.MaxAttempts != nil {
+		if *req.MaxAttempts <= 0 {
+			http.Error(w, "max_attempts must be positive", http.StatusBadRequest)
+			return
+		}
+		maxAttempts = *req.MaxAttempts
+	}
+
+	var exists bool
+	database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tools WHERE id = ?)", req.ToolID).Scan(&exists)
+	if !exists {
+		http.Error(w, "Tool not found", http.StatusBadRequest)
+		return
+	}
+	database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM connectors WHERE tool_id = ?)", req.ToolID).Scan(&exists)
+	if exists {
+		http.Error(w, "Tool already has a connector", http.StatusConflict)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO connectors (name, type, tool_id, config, max_attempts, auto_remediate, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Type, req.ToolID, string(req.Config), maxAttempts, req.AutoRemediate, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create connector", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "connector.create", "connector", int(id), req.Name, nil, map[string]interface{}{
+		"type":           req.Type,
+		"tool_id":        req.ToolID,
+		"config":         connectors.RedactConfig(req.Type, req.Config),
+		"auto_remediate": req.AutoRemediate,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Connector created successfully"})
+}
+
+// UpdateConnector updates a connector. Secrets sent back in redacted form keep their
+// stored values.
+func UpdateConnector(w http.ResponseWriter, r *http.Request) {
+	connectorID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateConnectorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	old, err := scanConnector(database.DB.QueryRow("SELECT "+connectorColumns+" FROM connectors WHERE id = ?", connectorID))
+	if err != nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if len(req.Config) > 0 {
+		config := connectors.RestoreSecrets(old.Type, req.Config, old.Config)
+		if _, err := connectors.New(old.Type, config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "config = ?")
+		args = append(args, string(config))
+	}
+	if req.MaxAttempts != nil {
+		if *req.MaxAttempts <= 0 {
+			http.Error(w, "max_attempts must be positive", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "max_attempts = ?")
+		args = append(args, *req.MaxAttempts)
+	}
+	if req.AutoRemediate != nil {
+		updates = append(updates, "auto_remediate = ?")
+		args = append(args, *req.AutoRemediate)
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE connectors SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, connectorID)
+
+	if _, err := database.DB.Exec(query, args...); err != nil {
+		http.Error(w, "Failed to update connector", http.StatusInternalServerError)
+		return
+	}
+
+	if len(req.Config) > 0 {
+		req.Config = connectors.RedactConfig(old.Type, req.Config)
+	}
+	old.Config = connectors.RedactConfig(old.Type, old.Config)
+	LogAudit(r, "connector.update", "connector", connectorID, old.Name, old, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Connector updated successfully"})
+}
+
+// DeleteConnector removes a connector and its job queue
+func DeleteConnector(w http.ResponseWriter, r *http.Request) {
+	connectorID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var name string
+	if err := database.DB.QueryRow("SELECT name FROM connectors WHERE id = ?", connectorID).Scan(&name); err != nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+
+	before, _, err := execWithSnapshots("connectors", connectorID, "DELETE FROM connectors WHERE id = ?", connectorID)
+	if err != nil {
+		http.Error(w, "Failed to delete connector", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "connector.delete", "connector", connectorID, name, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Connector deleted successfully"})
+}
+
+// TestConnector checks that the target system is reachable by listing its accounts
+func TestConnector(w http.ResponseWriter, r *http.Request) {
+	connectorID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	c, err := scanConnector(database.DB.QueryRow("SELECT "+connectorColumns+" FROM connectors WHERE id = ?", connectorID))
+	if err != nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := connectors.New(c.Type, c.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	accounts, err := conn.ListAccounts(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "accounts": len(accounts)})
+}
+
+// ListConnectorJobs returns a connector's job queue, newest first
+func ListConnectorJobs(w http.ResponseWriter, r *http.Request) {
+	connectorID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	query := "SELECT " + provisioningJobColumns + " FROM provisioning_jobs WHERE connector_id = ?"
+	args := []interface{}{connectorID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT 200"
+
+	jobs, err := queryProvisioningJobs(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// RetryProvisioningJob puts a failed job back in the queue with a fresh attempt budget
+func RetryProvisioningJob(w http.ResponseWriter, r *http.Request) {
+	jobID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var status string
+	var requestID int
+	err := database.DB.QueryRow("SELECT status, access_request_id FROM provisioning_jobs WHERE id = ?", jobID).
+		Scan(&status, &requestID)
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if status != JobFailed {
+		http.Error(w, "Only failed jobs can be retried", http.StatusConflict)
+		return
+	}
+
+	before, after, err := changeWithSnapshots("provisioning_jobs", jobID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE provisioning_jobs SET status = ?, attempts = 0, next_attempt_at = ?, completed_at = NULL
+			WHERE id = ?`, JobPending, time.Now(), jobID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE access_requests SET provisioning_status = ? WHERE id = ?", ProvisioningPending, requestID)
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Failed to retry job", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "connector.job.retry", "provisioning_job", jobID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Job queued for retry"})
+}
+
+// GetRequestProvisioning returns the provisioning jobs behind a request
+func GetRequestProvisioning(w http.ResponseWriter, r *http.Request) {
+	requestID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if !canParticipateInRequest(w, r, requestID) {
+		return
+	}
+
+	var status *string
+	database.DB.QueryRow("SELECT provisioning_status FROM access_requests WHERE id = ?", requestID).Scan(&status)
+
+	jobs, err := queryProvisioningJobs("SELECT "+provisioningJobColumns+" FROM provisioning_jobs WHERE access_request_id = ? ORDER BY id", requestID)
+	if err != nil {
+		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"provisioning_status": status, "jobs": jobs})
+}
+
+// StartProvisioningWorker runs due provisioning jobs every interval until ctx is cancelled
+func StartProvisioningWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "provisioning worker", interval, func(now time.Time) error {
+		return runProvisioningJobs(ctx, now)
+	})
+}
+
+// queueTransitionProvisioning queues connector calls for a request that was just
+// approved, or whose grant was just revoked or expired
+func queueTransitionProvisioning(tx *sql.Tx, requestID int, from string, to string) error {
+	switch {
+	case to == StatusApproved:
+		return enqueueProvisioning(tx, requestID, ProvisionAction)
+	case from == StatusApproved && (to == StatusRevoked || to == StatusExpired):
+		return enqueueProvisioning(tx, requestID, DeprovisionAction)
+	}
+	return nil
+}
+
+// enqueueProvisioning queues a job for a tool grant when its tool has an active
+// connector. Requests for roles and groups are not provisioned.
+func enqueueProvisioning(ex sqlExecutor, requestID int, action string) error {
+	var userID, toolID int
+	var targetType string
+	err := ex.QueryRow("SELECT user_id, target_type, target_id FROM access_requests WHERE id = ?", requestID).
+		Scan(&userID, &targetType, &toolID)
+	if err != nil {
+		return err
+	}
+	if targetType != "tool" {
+		return nil
+	}
+
+	var connectorID int
+	err = ex.QueryRow("SELECT id FROM connectors WHERE tool_id = ? AND is_active = TRUE", toolID).Scan(&connectorID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Losing one grant must not remove access the user still holds through another,
+	// so the remaining grant is pushed instead to keep the target system's level right.
+	// Nothing is left to remove for the lost grant itself.
+	if action == DeprovisionAction {
+		var remainingID int
+		err := ex.QueryRow(`
+			SELECT id FROM access_requests
+			WHERE user_id = ? AND target_type = 'tool' AND target_id = ? AND id != ? AND status = 'APPROVED'
+			  AND (expires_at IS NULL OR expires_at > ?)
+			ORDER BY approved_at DESC LIMIT 1`,
+			userID, toolID, requestID, time.Now()).Scan(&remainingID)
+		if err == nil {
+			_, err = ex.Exec("UPDATE access_requests SET provisioning_status = ? WHERE id = ?", ProvisioningDeprovisioned, requestID)
+			if err != nil {
+				return err
+			}
+			requestID, action = remainingID, ProvisionAction
+		}
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO provisioning_jobs (connector_id, access_request_id, action, next_attempt_at)
+		VALUES (?, ?, ?, ?)`, connectorID, requestID, action, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec("UPDATE access_requests SET provisioning_status = ? WHERE id = ?", ProvisioningPending, requestID)
+	return err
+}
+
+// runProvisioningJobs runs every due job. Jobs for the same user on the same connector
+// run strictly in the order they were queued.
+func runProvisioningJobs(ctx context.Context, now time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT j.id, j.connector_id, j.access_request_id, j.action, j.attempts, c.type, c.config, c.max_attempts
+		FROM provisioning_jobs j
+		JOIN connectors c ON j.connector_id = c.id
+		JOIN access_requests ar ON j.access_request_id = ar.id
+		WHERE j.status = 'PENDING' AND j.next_attempt_at <= ? AND c.is_active = TRUE
+		  AND NOT EXISTS (
+			  SELECT 1 FROM provisioning_jobs e
+			  JOIN access_requests ear ON e.access_request_id = ear.id
+			  WHERE e.connector_id = j.connector_id AND ear.user_id = ar.user_id
+			    AND e.status = 'PENDING' AND e.id < j.id
+		  )
+		ORDER BY j.connector_id, j.id`, now)
+	if err != nil {
+		return err
+	}
+
+	var jobs []provisioningJob
+	for rows.Next() {
+		var j provisioningJob
+		var config string
+		if err := rows.Scan(&j.ID, &j.ConnectorID, &j.AccessRequestID, &j.Action, &j.Attempts,
+			&j.ConnectorType, &config, &j.MaxAttempts); err != nil {
+			rows.Close()
+			return err
+		}
+		j.Config = json.RawMessage(config)
+		jobs = append(jobs, j)
+	}
+	rows.Close()
+
+	for _, j := range jobs {
+		runProvisioningJob(ctx, j, now)
+	}
+	return nil
+}
+
+// runProvisioningJob calls the connector and records the outcome. Failures are retried
+// with exponential backoff until the connector's attempt budget runs out or the error
+// is permanent.
+func runProvisioningJob(ctx context.Context, j provisioningJob, now time.Time) {
+	err := callConnector(ctx, j)
+	attempts := j.Attempts + 1
+
+	if err == nil {
+		status := ProvisioningProvisioned
+		if j.Action == DeprovisionAction {
+			status = ProvisioningDeprovisioned
+		}
+		database.DB.Exec(`
+			UPDATE provisioning_jobs SET status = ?, attempts = ?, last_error = NULL, completed_at = ?
+			WHERE id = ?`, JobSucceeded, attempts, now, j.ID)
+		database.DB.Exec("UPDATE access_requests SET provisioning_status = ? WHERE id = ?", status, j.AccessRequestID)
+		return
+	}
+
+	if connectors.IsPermanent(err) || attempts >= j.MaxAttempts {
+		database.DB.Exec(`
+			UPDATE provisioning_jobs SET status = ?, attempts = ?, last_error = ?, completed_at = ?
+			WHERE id = ?`, JobFailed, attempts, err.Error(), now, j.ID)
+		database.DB.Exec("UPDATE access_requests SET provisioning_status = ? WHERE id = ?", ProvisioningFailed, j.AccessRequestID)
+
+		LogSystemAudit("connector.job.fail", "provisioning_job", j.ID, "",
+			fmt.Sprintf("%s of request %d failed after %d attempts: %v", j.Action, j.AccessRequestID, attempts, err),
+			nil, nil)
+		return
+	}
+
+	database.DB.Exec(`
+		UPDATE provisioning_jobs SET attempts = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`, attempts, err.Error(), now.Add(retryBackoff(attempts)), j.ID)
+}
+
+func callConnector(ctx context.Context, j provisioningJob) error {
+	conn, err := connectors.New(j.ConnectorType, j.Config)
+	if err != nil {
+		return &connectors.PermanentError{Err: err}
+	}
+
+	var grant connectors.Grant
+	err = database.DB.QueryRow(`
+		SELECT ar.id, ar.user_id, u.email, ar.target_id, t.name, ar.access_level, ar.expires_at
+		FROM access_requests ar
+		JOIN users u ON ar.user_id = u.id
+		JOIN tools t ON ar.target_id = t.id
+		WHERE ar.id = ?`, j.AccessRequestID).
+		Scan(&grant.RequestID, &grant.UserID, &grant.UserEmail, &grant.ToolID, &grant.ToolName, &grant.AccessLevel, &grant.ExpiresAt)
+	if err != nil {
+		return &connectors.PermanentError{Err: fmt.Errorf("load grant: %w", err)}
+	}
+
+	if j.Action == DeprovisionAction {
+		return conn.Deprovision(ctx, grant)
+	}
+	return conn.Provision(ctx, grant)
+}
+
+const provisioningJobColumns = `id, connector_id, access_request_id, action, status, attempts, last_error,
+	next_attempt_at, completed_at, created_at`
+
+func queryProvisioningJobs(query string, args ...interface{}) ([]models.ProvisioningJob, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ProvisioningJob{}
+	for rows.Next() {
+		var j models.ProvisioningJob
+		if err := rows.Scan(&j.ID, &j.ConnectorID, &j.AccessRequestID, &j.Action, &j.Status, &j.Attempts, &j.LastError,
+			&j.NextAttemptAt, &j.CompletedAt, &j.CreatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func scanConnector(row rowScanner) (models.Connector, error) {
+	var c models.Connector
+	var config string
+	err := row.Scan(&c.ID, &c.Name, &c.Type, &c.ToolID, &config, &c.MaxAttempts, &c.AutoRemediate, &c.IsActive,
+		&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	c.Config = json.RawMessage(config)
+	return c, err
+}
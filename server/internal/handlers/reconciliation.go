@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/connectors"
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Reconciliation run statuses
+const (
+	ReconciliationRunning   = "RUNNING"
+	ReconciliationCompleted = "COMPLETED"
+	ReconciliationFailed    = "FAILED"
+)
+
+// Kinds of drift found by reconciliation
+const (
+	FindingOrphaned      = "ORPHANED"
+	FindingMissing       = "MISSING"
+	FindingLevelMismatch = "LEVEL_MISMATCH"
+)
+
+// expectedAccount is the account a live grant should have produced in the target system
+type expectedAccount struct {
+	RequestID   int
+	UserID      int
+	UserEmail   string
+	AccessLevel string
+}
+
+const reconciliationRunColumns = `id, connector_id, triggered_by, remediate, status, account_count, grant_count,
+	orphaned_count, missing_count, mismatch_count, error, started_at, completed_at`
+
+const reconciliationFindingColumns = `id, run_id, kind, user_id, user_email, access_request_id, expected_level,
+	actual_level, external_id, remediation, remediation_error, remediated_at`
+
+// ReconcileConnector compares a connector's accounts with gatekeepr's grants now and,
+// when asked, fixes the drift it finds
+func ReconcileConnector(w http.ResponseWriter, r *http.Request) {
+	connectorID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.ReconcileConnectorRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	c, err := scanConnector(database.DB.QueryRow("SELECT "+connectorColumns+" FROM connectors WHERE id = ?", connectorID))
+	if err != nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+	if req.Remediate && !c.IsActive {
+		http.Error(w, "Inactive connectors can be reconciled but not remediated", http.StatusConflict)
+		return
+	}
+
+	actorID := GetActorID(r)
+	runID, err := reconcileConnector(r.Context(), c, &actorID, req.Remediate)
+	if runID == 0 {
+		http.Error(w, "Failed to start reconciliation", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "connector.reconcile", "connector", c.ID, c.Name, nil, map[string]interface{}{
+		"run_id":    runID,
+		"remediate": req.Remediate,
+	})
+
+	run, loadErr := loadReconciliationRun(runID)
+	if loadErr != nil {
+		http.Error(w, "Failed to fetch reconciliation run", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(run)
+}
+
+// ListReconciliationRuns returns a connector's reconciliation runs, newest first
+func ListReconciliationRuns(w http.ResponseWriter, r *http.Request) {
+	connectorID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	rows, err := database.DB.Query("SELECT "+reconciliationRunColumns+`
+		FROM reconciliation_runs WHERE connector_id = ?
+		ORDER BY id DESC LIMIT 100`, connectorID)
+	if err != nil {
+		http.Error(w, "Failed to fetch reconciliation runs", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	runs := []models.ReconciliationRun{}
+	for rows.Next() {
+		run, err := scanReconciliationRun(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan reconciliation run", http.StatusInternalServerError)
+			return
+		}
+		runs = append(runs, run)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// GetReconciliationRun returns a reconciliation run with its findings
+func GetReconciliationRun(w http.ResponseWriter, r *http.Request) {
+	runID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	run, err := loadReconciliationRun(runID)
+	if err != nil {
+		http.Error(w, "Reconciliation run not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// RemediateReconciliationFinding fixes a single finding left open by a run
+func RemediateReconciliationFinding(w http.ResponseWriter, r *http.Request) {
+	findingID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	f, err := scanReconciliationFinding(database.DB.QueryRow(
+		"SELECT "+reconciliationFindingColumns+" FROM reconciliation_findings WHERE id = ?", findingID))
+	if err != nil {
+		http.Error(w, "Finding not found", http.StatusNotFound)
+		return
+	}
+	if f.RemediatedAt != nil {
+		http.Error(w, "Finding has already been remediated", http.StatusConflict)
+		return
+	}
+
+	var connectorID int
+	database.DB.QueryRow("SELECT connector_id FROM reconciliation_runs WHERE id = ?", f.RunID).Scan(&connectorID)
+	c, err := scanConnector(database.DB.QueryRow("SELECT "+connectorColumns+" FROM connectors WHERE id = ?", connectorID))
+	if err != nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+	if !c.IsActive {
+		http.Error(w, "Connector is inactive", http.StatusConflict)
+		return
+	}
+
+	remediation, err := remediateFinding(r.Context(), c, f, time.Now())
+	if err != nil {
+		http.Error(w, "Remediation failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	LogAudit(r, "reconciliation.remediate", "reconciliation_finding", f.ID, f.UserEmail,
+		map[string]interface{}{"kind": f.Kind, "expected_level": f.ExpectedLevel, "actual_level": f.ActualLevel},
+		map[string]string{"remediation": remediation})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Finding remediated: " + remediation})
+}
+
+// StartReconciliationWorker reconciles every active connector each interval until ctx
+// is cancelled, fixing drift for connectors with auto_remediate set
+func StartReconciliationWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "reconciliation worker", interval, func(now time.Time) error {
+		rows, err := database.DB.Query("SELECT " + connectorColumns + " FROM connectors WHERE is_active = TRUE ORDER BY id")
+		if err != nil {
+			return err
+		}
+		var list []models.Connector
+		for rows.Next() {
+			c, err := scanConnector(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			list = append(list, c)
+		}
+		rows.Close()
+
+		for _, c := range list {
+			if _, err := reconcileConnector(ctx, c, nil, c.AutoRemediate); err != nil {
+				log.Printf("Reconciliation of connector %s failed: %v", c.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// reconcileConnector records a run comparing the connector's accounts with the live
+// grants on its tool. Accounts held through roles or groups are not provisioned by
+// connectors and are left alone, as are users with provisioning jobs still pending.
+// It returns the run ID, which is 0 when the run could not be recorded at all.
+func reconcileConnector(ctx context.Context, c models.Connector, triggeredBy *int, remediate bool) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO reconciliation_runs (connector_id, triggered_by, remediate, status, started_at)
+		VALUES (?, ?, ?, ?, ?)`, c.ID, triggeredBy, remediate, ReconciliationRunning, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+	runID := int(id)
+
+	fail := func(err error) (int, error) {
+		database.DB.Exec("UPDATE reconciliation_runs SET status = ?, error = ?, completed_at = ? WHERE id = ?",
+			ReconciliationFailed, err.Error(), time.Now(), runID)
+		return runID, err
+	}
+
+	conn, err := connectors.New(c.Type, c.Config)
+	if err != nil {
+		return fail(err)
+	}
+	accounts, err := conn.ListAccounts(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	expected, err := loadExpectedAccounts(c.ToolID)
+	if err != nil {
+		return fail(err)
+	}
+	unmanaged, err := loadUnmanagedEmails(c)
+	if err != nil {
+		return fail(err)
+	}
+	userIDs, err := loadUserIDsByEmail()
+	if err != nil {
+		return fail(err)
+	}
+
+	var findings []models.ReconciliationFinding
+	seen := map[string]bool{}
+	for _, a := range accounts {
+		email := strings.ToLower(a.UserEmail)
+		seen[email] = true
+		if unmanaged[email] {
+			continue
+		}
+
+		f := models.ReconciliationFinding{UserEmail: a.UserEmail, ActualLevel: &a.AccessLevel}
+		if a.ExternalID != "" {
+			f.ExternalID = &a.ExternalID
+		}
+		if userID, ok := userIDs[email]; ok {
+			f.UserID = &userID
+		}
+
+		e, granted := expected[email]
+		switch {
+		case !granted:
+			f.Kind = FindingOrphaned
+		case e.AccessLevel != a.AccessLevel:
+			f.Kind = FindingLevelMismatch
+			f.AccessRequestID = &e.RequestID
+			f.ExpectedLevel = &e.AccessLevel
+		default:
+			continue
+		}
+		findings = append(findings, f)
+	}
+
+	for email, e := range expected {
+		if seen[email] || unmanaged[email] {
+			continue
+		}
+		findings = append(findings, models.ReconciliationFinding{
+			Kind:            FindingMissing,
+			UserID:          &e.UserID,
+			UserEmail:       e.UserEmail,
+			AccessRequestID: &e.RequestID,
+			ExpectedLevel:   &e.AccessLevel,
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Kind != findings[j].Kind {
+			return findings[i].Kind < findings[j].Kind
+		}
+		return findings[i].UserEmail < findings[j].UserEmail
+	})
+
+	counts := map[string]int{}
+	for _, f := range findings {
+		counts[f.Kind]++
+		result, err := database.DB.Exec(`
+			INSERT INTO reconciliation_findings
+				(run_id, kind, user_id, user_email, access_request_id, expected_level, actual_level, external_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, f.Kind, f.UserID, f.UserEmail, f.AccessRequestID, f.ExpectedLevel, f.ActualLevel, f.ExternalID)
+		if err != nil {
+			return fail(fmt.Errorf("record finding: %w", err))
+		}
+		if !remediate {
+			continue
+		}
+
+		id, _ := result.LastInsertId()
+		f.ID, f.RunID = int(id), runID
+		remediation, err := remediateFinding(ctx, c, f, time.Now())
+		if err != nil {
+			log.Printf("Failed to remediate reconciliation finding %d: %v", f.ID, err)
+			continue
+		}
+		LogSystemAudit("reconciliation.remediate", "reconciliation_finding", f.ID, f.UserEmail,
+			fmt.Sprintf("%s account on connector %s: %s (reconciliation run %d)", f.Kind, c.Name, remediation, runID),
+			map[string]interface{}{"expected_level": f.ExpectedLevel, "actual_level": f.ActualLevel},
+			map[string]string{"remediation": remediation})
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE reconciliation_runs
+		SET status = ?, account_count = ?, grant_count = ?, orphaned_count = ?, missing_count = ?,
+			mismatch_count = ?, completed_at = ?
+		WHERE id = ?`,
+		ReconciliationCompleted, len(accounts), len(expected), counts[FindingOrphaned], counts[FindingMissing],
+		counts[FindingLevelMismatch], time.Now(), runID)
+	if err != nil {
+		return runID, err
+	}
+
+	if len(findings) > 0 {
+		LogSystemAudit("connector.drift", "connector", c.ID, c.Name,
+			fmt.Sprintf("Reconciliation run %d found %d orphaned, %d missing and %d mismatched accounts",
+				runID, counts[FindingOrphaned], counts[FindingMissing], counts[FindingLevelMismatch]),
+			nil, nil)
+	}
+	return runID, nil
+}
+
+// remediateFinding fixes one finding and records the outcome on it. Orphaned accounts
+// are removed from the target system directly since no grant stands behind them;
+// missing and mismatched accounts are re-provisioned through the job queue so they
+// get its retries.
+func remediateFinding(ctx context.Context, c models.Connector, f models.ReconciliationFinding, now time.Time) (string, error) {
+	var remediation string
+	var err error
+
+	switch f.Kind {
+	case FindingOrphaned:
+		remediation = "deprovisioned"
+		var conn connectors.Connector
+		conn, err = connectors.New(c.Type, c.Config)
+		if err == nil {
+			grant := connectors.Grant{UserEmail: f.UserEmail, ToolID: c.ToolID}
+			if f.UserID != nil {
+				grant.UserID = *f.UserID
+			}
+			if f.ActualLevel != nil {
+				grant.AccessLevel = *f.ActualLevel
+			}
+			database.DB.QueryRow("SELECT name FROM tools WHERE id = ?", c.ToolID).Scan(&grant.ToolName)
+			err = conn.Deprovision(ctx, grant)
+		}
+	case FindingMissing, FindingLevelMismatch:
+		remediation = "provisioning queued"
+		if f.AccessRequestID == nil {
+			err = fmt.Errorf("finding has no grant to provision")
+		} else {
+			err = enqueueProvisioning(database.DB, *f.AccessRequestID, ProvisionAction)
+		}
+	default:
+		err = fmt.Errorf("unknown finding kind %q", f.Kind)
+	}
+
+	if err != nil {
+		database.DB.Exec("UPDATE reconciliation_findings SET remediation_error = ? WHERE id = ?", err.Error(), f.ID)
+		return "", err
+	}
+	database.DB.Exec(`
+		UPDATE reconciliation_findings SET remediation = ?, remediation_error = NULL, remediated_at = ?
+		WHERE id = ?`, remediation, now, f.ID)
+	return remediation, nil
+}
+
+// loadExpectedAccounts returns, keyed by lowercased email, the highest live grant each
+// user holds on a tool through access requests
+func loadExpectedAccounts(toolID int) (map[string]expectedAccount, error) {
+	rows, err := database.DB.Query(`
+		SELECT ar.id, ar.user_id, u.email, ar.access_level
+		FROM access_requests ar
+		JOIN users u ON ar.user_id = u.id
+		WHERE ar.target_type = 'tool' AND ar.target_id = ? AND ar.status = 'APPROVED'
+		  AND (ar.expires_at IS NULL OR ar.expires_at > ?)
+		ORDER BY ar.id`, toolID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranks, err := accessLevelRanks()
+	if err != nil {
+		return nil, err
+	}
+
+	expected := map[string]expectedAccount{}
+	for rows.Next() {
+		var e expectedAccount
+		if err := rows.Scan(&e.RequestID, &e.UserID, &e.UserEmail, &e.AccessLevel); err != nil {
+			return nil, err
+		}
+		email := strings.ToLower(e.UserEmail)
+		if held, ok := expected[email]; ok &&
+			levelRank(ranks, toolID, held.AccessLevel) >= levelRank(ranks, toolID, e.AccessLevel) {
+			continue
+		}
+		expected[email] = e
+	}
+	return expected, rows.Err()
+}
+
+// loadUnmanagedEmails returns the lowercased emails reconciliation must skip on a
+// connector: users with access to its tool through a role or group, and users whose
+// provisioning jobs have not finished yet
+func loadUnmanagedEmails(c models.Connector) (map[string]bool, error) {
+	rows, err := database.DB.Query(`
+		SELECT u.email FROM role_tool_access rta
+		JOIN user_roles ur ON rta.role_id = ur.role_id
+		JOIN users u ON ur.user_id = u.id
+		WHERE rta.tool_id = ?
+		UNION
+		SELECT u.email FROM group_tool_access gta
+		JOIN user_group_members ugm ON gta.group_id = ugm.group_id
+		JOIN users u ON ugm.user_id = u.id
+		WHERE gta.tool_id = ?
+		UNION
+		SELECT u.email FROM provisioning_jobs j
+		JOIN access_requests ar ON j.access_request_id = ar.id
+		JOIN users u ON ar.user_id = u.id
+		WHERE j.connector_id = ? AND j.status = 'PENDING'`, c.ToolID, c.ToolID, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := map[string]bool{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails[strings.ToLower(email)] = true
+	}
+	return emails, rows.Err()
+}
+
+func loadUserIDsByEmail() (map[string]int, error) {
+	rows, err := database.DB.Query("SELECT id, email FROM users")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]int{}
+	for rows.Next() {
+		var id int
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return nil, err
+		}
+		ids[strings.ToLower(email)] = id
+	}
+	return ids, rows.Err()
+}
+
+func loadReconciliationRun(runID int) (models.ReconciliationRun, error) {
+	run, err := scanReconciliationRun(database.DB.QueryRow(
+		"SELECT "+reconciliationRunColumns+" FROM reconciliation_runs WHERE id = ?", runID))
+	if err != nil {
+		return run, err
+	}
+
+	rows, err := database.DB.Query("SELECT "+reconciliationFindingColumns+`
+		FROM reconciliation_findings WHERE run_id = ? ORDER BY id`, runID)
+	if err != nil {
+		return run, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f, err := scanReconciliationFinding(rows)
+		if err != nil {
+			return run, err
+		}
+		run.Findings = append(run.Findings, f)
+	}
+	return run, rows.Err()
+}
+
+func scanReconciliationRun(row rowScanner) (models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	err := row.Scan(&run.ID, &run.ConnectorID, &run.TriggeredBy, &run.Remediate, &run.Status, &run.AccountCount,
+		&run.GrantCount, &run.OrphanedCount, &run.MissingCount, &run.MismatchCount, &run.Error,
+		&run.StartedAt, &run.CompletedAt)
+	return run, err
+}
+
+func scanReconciliationFinding(row rowScanner) (models.ReconciliationFinding, error) {
+	var f models.ReconciliationFinding
+	err := row.Scan(&f.ID, &f.RunID, &f.Kind, &f.UserID, &f.UserEmail, &f.AccessRequestID, &f.ExpectedLevel,
+		&f.ActualLevel, &f.ExternalID, &f.Remediation, &f.RemediationError, &f.RemediatedAt)
+	return f, err
+}
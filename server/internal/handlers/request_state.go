@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Access request statuses
+const (
+	StatusPending   = "PENDING"
+	StatusApproved  = "APPROVED"
+	StatusRejected  = "REJECTED"
+	StatusCancelled = "CANCELLED"
+	StatusRevoked   = "REVOKED"
+	StatusExpired   = "EXPIRED"
+	StatusExtended  = "EXTENDED"
+)
+
+// requestTransitions lists the statuses each status may move to. Statuses
+// without an entry are terminal.
+var requestTransitions = map[string][]string{
+	StatusPending:  {StatusApproved, StatusRejected, StatusCancelled, StatusExpired},
+	StatusApproved: {StatusRevoked, StatusExpired, StatusExtended},
+}
+
+// errRequestNotFound is returned when a transition targets a request that does not exist
+var errRequestNotFound = errors.New("access request not found")
+
+// transitionError reports a status change the state machine does not allow
+type transitionError struct {
+	From string
+	To   string
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("cannot move request from %s to %s", e.From, e.To)
+}
+
+func canTransition(from string, to string) bool {
+	for _, allowed := range requestTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionRequest moves a request to a new status inside tx after validating the
+// change against the state machine. set holds extra assignments for the UPDATE
+// (e.g. "approved_by = ?") and args their values. Provisioning jobs for the change
+// are queued in the same transaction.
+func transitionRequest(tx *sql.Tx, requestID int, to string, set string, args ...interface{}) error {
+	var from string
+	err := tx.QueryRow("SELECT status FROM access_requests WHERE id = ?", requestID).Scan(&from)
+	if err == sql.ErrNoRows {
+		return errRequestNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !canTransition(from, to) {
+		return &transitionError{From: from, To: to}
+	}
+
+	query := "UPDATE access_requests SET status = ?"
+	if set != "" {
+		query += ", " + set
+	}
+	query += " WHERE id = ? AND status = ?"
+
+	params := append([]interface{}{to}, args...)
+	params = append(params, requestID, from)
+
+	result, err := tx.Exec(query, params...)
+	if err != nil {
+		return err
+	}
+	// Another writer changed the status between our read and the update
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return &transitionError{From: from, To: to}
+	}
+
+	// Grants that start or end are pushed to the tool's connector, if it has one
+	return queueTransitionProvisioning(tx, requestID, from, to)
+}
+
+// writeTransitionError maps a transitionRequest error to an HTTP response
+func writeTransitionError(w http.ResponseWriter, err error, fallback string) {
+	var te *transitionError
+	switch {
+	case errors.As(err, &te):
+		http.Error(w, te.Error(), http.StatusConflict)
+	case errors.Is(err, errRequestNotFound):
+		http.Error(w, "Request not found", http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -165,13 +165,13 @@ func UpdateRole(w http.ResponseWriter, r *http.Request) {
 	query := "UPDATE roles SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
 	args = append(args, roleID)
 
-	_, err := database.DB.Exec(query, args...)
+	before, after, err := execWithSnapshots("roles", roleID, query, args...)
 	if err != nil {
 		http.Error(w, "Failed to update role", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "role.update", "role", roleID, "", nil, &req)
+	LogAudit(r, "role.update", "role", roleID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Role updated successfully"})
@@ -191,13 +191,13 @@ func DeleteRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := database.DB.Exec("DELETE FROM roles WHERE id = ?", roleID)
+	before, _, err := execWithSnapshots("roles", roleID, "DELETE FROM roles WHERE id = ?", roleID)
 	if err != nil {
 		http.Error(w, "Failed to delete role", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "role.delete", "role", roleID, roleName, nil, nil)
+	LogAudit(r, "role.delete", "role", roleID, roleName, before, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Role deleted successfully"})
@@ -251,6 +251,13 @@ func SetRolePermissions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotEntity(tx, "roles", roleID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load role", http.StatusInternalServerError)
+		return
+	}
+
 	// Remove existing permissions
 	_, err = tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", roleID)
 	if err != nil {
@@ -269,12 +276,19 @@ func SetRolePermissions(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	after, err := snapshotEntity(tx, "roles", roleID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to load role", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "role.permissions.update", "role", roleID, "", nil, &req)
+	LogAudit(r, "role.permissions.update", "role", roleID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Permissions updated successfully"})
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"gatekeepr/internal/auth"
+)
+
+// SigningKey is the HMAC key for documents that must be verifiable later, such as
+// certification reports. It falls back to the JWT secret when not configured.
+var SigningKey = auth.SecretKey
+
+// errNoSigningKey is returned when SigningKey was left on the JWT secret. Its default
+// is in the source, so anything signed with it could be forged by anyone who has read
+// the code: a rewritten audit chain, a doctored report or an action link.
+var errNoSigningKey = errors.New("SIGNING_KEY is not set")
+
+// signingKeySet reports whether SIGNING_KEY was configured. Signatures that someone
+// may rely on later are only made when it is.
+func signingKeySet() bool {
+	return !bytes.Equal(SigningKey, auth.SecretKey)
+}
+
+// signPayload returns the hex-encoded HMAC-SHA256 of data
+func signPayload(data []byte) string {
+	return signWithKey(SigningKey, data)
+}
+
+// signWithKey returns the hex-encoded HMAC-SHA256 of data under a caller-supplied key,
+// such as a webhook's shared secret
+func signWithKey(key []byte, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature checks a signature produced by signPayload in constant time
+func verifySignature(data []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, SigningKey)
+	mac.Write(data)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,489 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// slaCandidate is a pending request checked against its SLA policy
+type slaCandidate struct {
+	ID                int
+	UserID            int
+	UserEmail         string
+	TargetType        string
+	TargetID          int
+	AccessLevel       string
+	ToolCategory      *string
+	CreatedAt         time.Time
+	RemindedAt        *time.Time
+	EscalatedAt       *time.Time
+	EscalationGroupID *int
+}
+
+// ListSLAPolicies returns all SLA policies
+func ListSLAPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := loadSLAPolicies(false)
+	if err != nil {
+		http.Error(w, "Failed to fetch policies", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// CreateSLAPolicy creates a new SLA policy
+func CreateSLAPolicy(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateSLAPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.SLAMinutes <= 0 {
+		http.Error(w, "name and sla_minutes are required", http.StatusBadRequest)
+		return
+	}
+	if req.TimeoutAction == "" {
+		req.TimeoutAction = "reject"
+	}
+	if req.TimeoutAction != "reject" && req.TimeoutAction != "expire" {
+		http.Error(w, "timeout_action must be reject or expire", http.StatusBadRequest)
+		return
+	}
+	if req.EscalateAfterMinutes != nil && req.EscalationGroupID == nil {
+		http.Error(w, "escalation_group_id is required to escalate", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO sla_policies (name, tool_id, tool_category, sla_minutes, reminder_after_minutes,
+			escalate_after_minutes, escalation_group_id, timeout_after_minutes, timeout_action, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.ToolID, req.ToolCategory, req.SLAMinutes, req.ReminderAfterMinutes,
+		req.EscalateAfterMinutes, req.EscalationGroupID, req.TimeoutAfterMinutes, req.TimeoutAction, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create policy", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "sla.policy.create", "sla_policy", int(id), req.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Policy created successfully"})
+}
+
+// UpdateSLAPolicy updates an existing SLA policy
+func UpdateSLAPolicy(w http.ResponseWriter, r *http.Request) {
+	policyID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateSLAPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.ToolID != nil {
+		updates = append(updates, "tool_id = ?")
+		args = append(args, nullableID(*req.ToolID))
+	}
+	if req.ToolCategory != nil {
+		updates = append(updates, "tool_category = ?")
+		args = append(args, nullableString(*req.ToolCategory))
+	}
+	if req.SLAMinutes != nil {
+		if *req.SLAMinutes <= 0 {
+			http.Error(w, "sla_minutes must be positive", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "sla_minutes = ?")
+		args = append(args, *req.SLAMinutes)
+	}
+	if req.ReminderAfterMinutes != nil {
+		updates = append(updates, "reminder_after_minutes = ?")
+		args = append(args, nullableID(*req.ReminderAfterMinutes))
+	}
+	if req.EscalateAfterMinutes != nil {
+		updates = append(updates, "escalate_after_minutes = ?")
+		args = append(args, nullableID(*req.EscalateAfterMinutes))
+	}
+	if req.EscalationGroupID != nil {
+		updates = append(updates, "escalation_group_id = ?")
+		args = append(args, nullableID(*req.EscalationGroupID))
+	}
+	if req.TimeoutAfterMinutes != nil {
+		updates = append(updates, "timeout_after_minutes = ?")
+		args = append(args, nullableID(*req.TimeoutAfterMinutes))
+	}
+	if req.TimeoutAction != nil {
+		if *req.TimeoutAction != "reject" && *req.TimeoutAction != "expire" {
+			http.Error(w, "timeout_action must be reject or expire", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "timeout_action = ?")
+		args = append(args, *req.TimeoutAction)
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE sla_policies SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, policyID)
+
+	before, after, err := execWithSnapshots("sla_policies", policyID, query, args...)
+	if err != nil {
+		http.Error(w, "Failed to update policy", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "sla.policy.update", "sla_policy", policyID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Policy updated successfully"})
+}
+
+// DeleteSLAPolicy deletes an SLA policy
+func DeleteSLAPolicy(w http.ResponseWriter, r *http.Request) {
+	policyID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var policyName string
+	database.DB.QueryRow("SELECT name FROM sla_policies WHERE id = ?", policyID).Scan(&policyName)
+
+	before, _, err := execWithSnapshots("sla_policies", policyID, "DELETE FROM sla_policies WHERE id = ?", policyID)
+	if err != nil {
+		http.Error(w, "Failed to delete policy", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "sla.policy.delete", "sla_policy", policyID, policyName, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Policy deleted successfully"})
+}
+
+// ListSLABreaches returns pending requests that have waited longer than their policy's SLA
+func ListSLABreaches(w http.ResponseWriter, r *http.Request) {
+	policies, err := loadSLAPolicies(true)
+	if err != nil {
+		http.Error(w, "Failed to fetch policies", http.StatusInternalServerError)
+		return
+	}
+
+	candidates, err := loadSLACandidates()
+	if err != nil {
+		http.Error(w, "Failed to fetch requests", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	breaches := []models.SLABreach{}
+	for _, c := range candidates {
+		policy := resolveSLAPolicy(policies, c)
+		if policy == nil {
+			continue
+		}
+		waited := now.Sub(c.CreatedAt)
+		if waited < minutes(policy.SLAMinutes) {
+			continue
+		}
+		breaches = append(breaches, models.SLABreach{
+			RequestID:         c.ID,
+			UserID:            c.UserID,
+			UserEmail:         c.UserEmail,
+			TargetType:        c.TargetType,
+			TargetID:          c.TargetID,
+			AccessLevel:       c.AccessLevel,
+			CreatedAt:         c.CreatedAt,
+			PolicyID:          policy.ID,
+			PolicyName:        policy.Name,
+			SLAMinutes:        policy.SLAMinutes,
+			WaitingMinutes:    int(waited.Minutes()),
+			EscalatedAt:       c.EscalatedAt,
+			EscalationGroupID: c.EscalationGroupID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breaches)
+}
+
+// StartSLAWorker checks pending requests against their SLA policies every interval
+// until ctx is cancelled
+func StartSLAWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "SLA check", interval, processSLAs)
+}
+
+// processSLAs sends reminders, escalates and times out pending requests according
+// to the policy that applies to each of them
+func processSLAs(now time.Time) error {
+	policies, err := loadSLAPolicies(true)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+
+	candidates, err := loadSLACandidates()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range candidates {
+		policy := resolveSLAPolicy(policies, c)
+		if policy == nil {
+			continue
+		}
+		waited := now.Sub(c.CreatedAt)
+
+		if policy.TimeoutAfterMinutes != nil && waited >= minutes(*policy.TimeoutAfterMinutes) {
+			timeOutRequest(c, policy)
+			continue
+		}
+
+		if policy.EscalateAfterMinutes != nil && policy.EscalationGroupID != nil &&
+			c.EscalatedAt == nil && waited >= minutes(*policy.EscalateAfterMinutes) {
+			escalateRequest(c, policy, now)
+			c.EscalationGroupID = policy.EscalationGroupID
+		}
+
+		if policy.ReminderAfterMinutes != nil && *policy.ReminderAfterMinutes > 0 {
+			last := c.CreatedAt
+			if c.RemindedAt != nil {
+				last = *c.RemindedAt
+			}
+			if now.Sub(last) >= minutes(*policy.ReminderAfterMinutes) {
+				remindApprovers(c, now)
+			}
+		}
+	}
+	return nil
+}
+
+func escalateRequest(c slaCandidate, policy *models.SLAPolicy, now time.Time) {
+	_, err := database.DB.Exec(`
+		UPDATE access_requests SET escalated_at = ?, escalation_group_id = ?
+		WHERE id = ? AND status = 'PENDING'`, now, *policy.EscalationGroupID, c.ID)
+	if err != nil {
+		log.Printf("Failed to escalate request %d: %v", c.ID, err)
+		return
+	}
+
+	LogSystemAudit("access.request.escalate", "access_request", c.ID, "",
+		fmt.Sprintf("No decision within %d minutes (SLA policy %s)", *policy.EscalateAfterMinutes, policy.Name),
+		nil, map[string]interface{}{"escalation_group_id": *policy.EscalationGroupID, "sla_policy_id": policy.ID})
+
+	members := queryUserIDs("SELECT user_id FROM user_group_members WHERE group_id = ? AND user_id != ?",
+		*policy.EscalationGroupID, c.UserID)
+	notifyUsers(members,
+		fmt.Sprintf("Access request %d escalated to you", c.ID),
+		fmt.Sprintf("%s has been waiting %s for %s access to %s %d.",
+			c.UserEmail, now.Sub(c.CreatedAt).Round(time.Minute), c.AccessLevel, c.TargetType, c.TargetID))
+}
+
+func remindApprovers(c slaCandidate, now time.Time) {
+	if _, err := database.DB.Exec("UPDATE access_requests SET reminded_at = ? WHERE id = ?", now, c.ID); err != nil {
+		log.Printf("Failed to record reminder for request %d: %v", c.ID, err)
+		return
+	}
+
+	notifyUsers(requestApprovers(c),
+		fmt.Sprintf("Reminder: access request %d is awaiting a decision", c.ID),
+		fmt.Sprintf("%s has been waiting %s for %s access to %s %d.",
+			c.UserEmail, now.Sub(c.CreatedAt).Round(time.Minute), c.AccessLevel, c.TargetType, c.TargetID))
+}
+
+// timeOutRequest closes a request that received no decision before the policy's final
+// timeout, rejecting or expiring it as configured
+func timeOutRequest(c slaCandidate, policy *models.SLAPolicy) {
+	reason := fmt.Sprintf("No decision within %d minutes (SLA policy %s)", *policy.TimeoutAfterMinutes, policy.Name)
+
+	to, set := StatusRejected, "rejected_at = CURRENT_TIMESTAMP, rejection_reason = ?"
+	if policy.TimeoutAction == "expire" {
+		to, set = StatusExpired, "rejection_reason = ?"
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to time out request %d: %v", c.ID, err)
+		return
+	}
+	if err := transitionRequest(tx, c.ID, to, set, reason); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to time out request %d: %v", c.ID, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to time out request %d: %v", c.ID, err)
+		return
+	}
+
+	LogSystemAudit("access.request.timeout", "access_request", c.ID, "", reason,
+		map[string]string{"status": StatusPending}, map[string]string{"status": to})
+
+	notifyUsers([]int{c.UserID},
+		fmt.Sprintf("Access request %d was closed", c.ID),
+		fmt.Sprintf("Your request for %s access to %s %d is now %s: %s.", c.AccessLevel, c.TargetType, c.TargetID, to, reason))
+}
+
+// resolveSLAPolicy picks the policy for a request: a policy for its tool wins over one
+// for its tool category, which wins over the default policy
+func resolveSLAPolicy(policies []models.SLAPolicy, c slaCandidate) *models.SLAPolicy {
+	var byCategory, fallback *models.SLAPolicy
+	for i := range policies {
+		p := &policies[i]
+		switch {
+		case p.ToolID != nil:
+			if c.TargetType == "tool" && *p.ToolID == c.TargetID {
+				return p
+			}
+		case p.ToolCategory != nil:
+			if byCategory == nil && c.ToolCategory != nil && *p.ToolCategory == *c.ToolCategory {
+				byCategory = p
+			}
+		default:
+			if fallback == nil {
+				fallback = p
+			}
+		}
+	}
+	if byCategory != nil {
+		return byCategory
+	}
+	return fallback
+}
+
+// requestApprovers returns everyone who may decide a request other than the requester:
+// approvers, owners of the requested tool, the group it was escalated to and their delegates
+func requestApprovers(c slaCandidate) []int {
+	query := `
+		SELECT ur.user_id FROM user_roles ur
+		JOIN roles r ON ur.role_id = r.id
+		WHERE r.can_approve_requests = 1`
+	args := []interface{}{}
+
+	if c.TargetType == "tool" {
+		query += `
+		UNION
+		SELECT owner_id FROM tool_owners WHERE tool_id = ? AND owner_type = 'user'
+		UNION
+		SELECT ugm.user_id FROM tool_owners o
+		JOIN user_group_members ugm ON o.owner_id = ugm.group_id
+		WHERE o.tool_id = ? AND o.owner_type = 'group'`
+		args = append(args, c.TargetID, c.TargetID)
+	}
+	if c.EscalationGroupID != nil {
+		query += `
+		UNION
+		SELECT user_id FROM user_group_members WHERE group_id = ?`
+		args = append(args, *c.EscalationGroupID)
+	}
+
+	// Delegates stand in for approvers who are away, so they are reminded too
+	seen := map[int]bool{c.UserID: true}
+	var approvers []int
+	for _, id := range queryUserIDs(query, args...) {
+		for _, candidate := range append([]int{id}, activeDelegates(id)...) {
+			if !seen[candidate] {
+				seen[candidate] = true
+				approvers = append(approvers, candidate)
+			}
+		}
+	}
+	return approvers
+}
+
+// isInEscalationGroup checks if a user belongs to the group a request was escalated to
+func isInEscalationGroup(userID int, requestID int) bool {
+	if userID == 0 {
+		return false
+	}
+
+	var isMember bool
+	database.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM access_requests ar
+			JOIN user_group_members ugm ON ugm.group_id = ar.escalation_group_id
+			WHERE ar.id = ? AND ugm.user_id = ?
+		)`, requestID, userID).Scan(&isMember)
+	return isMember
+}
+
+func loadSLAPolicies(activeOnly bool) ([]models.SLAPolicy, error) {
+	query := `
+		SELECT id, name, tool_id, tool_category, sla_minutes, reminder_after_minutes,
+			   escalate_after_minutes, escalation_group_id, timeout_after_minutes, timeout_action,
+			   is_active, created_by, created_at, updated_at
+		FROM sla_policies`
+	if activeOnly {
+		query += " WHERE is_active = 1"
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []models.SLAPolicy
+	for rows.Next() {
+		var p models.SLAPolicy
+		if err := rows.Scan(&p.ID, &p.Name, &p.ToolID, &p.ToolCategory, &p.SLAMinutes, &p.ReminderAfterMinutes,
+			&p.EscalateAfterMinutes, &p.EscalationGroupID, &p.TimeoutAfterMinutes, &p.TimeoutAction,
+			&p.IsActive, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+func loadSLACandidates() ([]slaCandidate, error) {
+	rows, err := database.DB.Query(`
+		SELECT ar.id, ar.user_id, u.email, ar.target_type, ar.target_id, ar.access_level, t.category,
+			   ar.created_at, ar.reminded_at, ar.escalated_at, ar.escalation_group_id
+		FROM access_requests ar
+		JOIN users u ON ar.user_id = u.id
+		LEFT JOIN tools t ON ar.target_type = 'tool' AND t.id = ar.target_id
+		WHERE ar.status = 'PENDING'
+		ORDER BY ar.created_at ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []slaCandidate
+	for rows.Next() {
+		var c slaCandidate
+		if err := rows.Scan(&c.ID, &c.UserID, &c.UserEmail, &c.TargetType, &c.TargetID, &c.AccessLevel, &c.ToolCategory,
+			&c.CreatedAt, &c.RemindedAt, &c.EscalatedAt, &c.EscalationGroupID); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
@@ -0,0 +1,530 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AllowSelfApproval lets approvers act on their own access requests when set
+var AllowSelfApproval bool
+
+// Entitlement is something a user can hold: a role, a group membership or access to a tool
+type Entitlement struct {
+	Type        string `json:"type"`
+	ID          int    `json:"id"`
+	AccessLevel string `json:"access_level,omitempty"`
+}
+
+// sodConflict pairs a violated rule with the proposed entitlement that triggered it
+type sodConflict struct {
+	Rule        models.SoDRule `json:"rule"`
+	Entitlement Entitlement    `json:"entitlement"`
+	ViolationID int64          `json:"violation_id"`
+	Status      string         `json:"status"`
+}
+
+func isValidEntitlementType(t string) bool {
+	return t == "role" || t == "group" || t == "tool"
+}
+
+// ListSoDRules returns all separation of duties rules
+func ListSoDRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := loadSoDRules(false)
+	if err != nil {
+		http.Error(w, "Failed to fetch rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateSoDRule creates a new separation of duties rule
+func CreateSoDRule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateSoDRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.LeftID == 0 || req.RightID == 0 {
+		http.Error(w, "name, left_id and right_id are required", http.StatusBadRequest)
+		return
+	}
+	if !isValidEntitlementType(req.LeftType) || !isValidEntitlementType(req.RightType) {
+		http.Error(w, "left_type and right_type must be one of role, group or tool", http.StatusBadRequest)
+		return
+	}
+	if req.LeftType == req.RightType && req.LeftID == req.RightID {
+		http.Error(w, "A rule cannot conflict an entitlement with itself", http.StatusBadRequest)
+		return
+	}
+	if req.Enforcement == "" {
+		req.Enforcement = "block"
+	}
+	if req.Enforcement != "block" && req.Enforcement != "exception" {
+		http.Error(w, "enforcement must be block or exception", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO sod_rules (name, description, left_type, left_id, left_access_level, right_type, right_id, right_access_level, enforcement, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Description, req.LeftType, req.LeftID, req.LeftAccessLevel,
+		req.RightType, req.RightID, req.RightAccessLevel, req.Enforcement, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "sod.rule.create", "sod_rule", int(id), req.Name, nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Rule created successfully"})
+}
+
+// UpdateSoDRule updates an existing separation of duties rule
+func UpdateSoDRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateSoDRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.Description != nil {
+		updates = append(updates, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.Enforcement != nil {
+		if *req.Enforcement != "block" && *req.Enforcement != "exception" {
+			http.Error(w, "enforcement must be block or exception", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "enforcement = ?")
+		args = append(args, *req.Enforcement)
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE sod_rules SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, ruleID)
+
+	before, after, err := execWithSnapshots("sod_rules", ruleID, query, args...)
+	if err != nil {
+		http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "sod.rule.update", "sod_rule", ruleID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Rule updated successfully"})
+}
+
+// DeleteSoDRule deletes a separation of duties rule
+func DeleteSoDRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var ruleName string
+	database.DB.QueryRow("SELECT name FROM sod_rules WHERE id = ?", ruleID).Scan(&ruleName)
+
+	before, _, err := execWithSnapshots("sod_rules", ruleID, "DELETE FROM sod_rules WHERE id = ?", ruleID)
+	if err != nil {
+		http.Error(w, "Failed to delete rule", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "sod.rule.delete", "sod_rule", ruleID, ruleName, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Rule deleted successfully"})
+}
+
+// ListSoDViolations returns recorded violations with filters
+func ListSoDViolations(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT v.id, v.rule_id, v.user_id, v.action, v.entitlement_type, v.entitlement_id,
+			   v.access_level, v.status, v.requested_by, v.decided_by, v.decided_at,
+			   v.decision_reason, v.created_at, sr.name, u.email
+		FROM sod_violations v
+		JOIN sod_rules sr ON v.rule_id = sr.id
+		JOIN users u ON v.user_id = u.id
+		WHERE 1=1`
+	args := []interface{}{}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND v.status = ?"
+		args = append(args, status)
+	}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query += " AND v.user_id = ?"
+		args = append(args, userID)
+	}
+
+	query += " ORDER BY v.created_at DESC LIMIT 100"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch violations", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var violations []models.SoDViolation
+	for rows.Next() {
+		var v models.SoDViolation
+		if err := rows.Scan(&v.ID, &v.RuleID, &v.UserID, &v.Action, &v.EntitlementType, &v.EntitlementID,
+			&v.AccessLevel, &v.Status, &v.RequestedBy, &v.DecidedBy, &v.DecidedAt,
+			&v.DecisionReason, &v.CreatedAt, &v.RuleName, &v.UserEmail); err != nil {
+			http.Error(w, "Failed to scan violation", http.StatusInternalServerError)
+			return
+		}
+		violations = append(violations, v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(violations)
+}
+
+// ApproveSoDException grants an exception for a violation awaiting exception approval
+func ApproveSoDException(w http.ResponseWriter, r *http.Request) {
+	decideSoDException(w, r, "EXCEPTION_APPROVED", "sod.exception.approve")
+}
+
+// RejectSoDException refuses an exception for a violation awaiting exception approval
+func RejectSoDException(w http.ResponseWriter, r *http.Request) {
+	decideSoDException(w, r, "EXCEPTION_REJECTED", "sod.exception.reject")
+}
+
+func decideSoDException(w http.ResponseWriter, r *http.Request, status string, action string) {
+	violationID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.DecideSoDExceptionRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	actorID := GetActorID(r)
+
+	var userID, requestedBy int
+	var current string
+	err := database.DB.QueryRow(`
+		SELECT user_id, COALESCE(requested_by, 0), status FROM sod_violations WHERE id = ?`,
+		violationID).Scan(&userID, &requestedBy, &current)
+	if err != nil {
+		http.Error(w, "Violation not found", http.StatusNotFound)
+		return
+	}
+	if current != "PENDING_EXCEPTION" {
+		http.Error(w, "Violation is not awaiting exception approval", http.StatusConflict)
+		return
+	}
+	if actorID == userID || actorID == requestedBy {
+		http.Error(w, "You cannot decide an exception you are party to", http.StatusForbidden)
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE sod_violations
+		SET status = ?, decided_by = ?, decided_at = CURRENT_TIMESTAMP, decision_reason = ?
+		WHERE id = ? AND status = 'PENDING_EXCEPTION'`,
+		status, actorID, req.Reason, violationID)
+	if err != nil {
+		http.Error(w, "Failed to record decision", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, action, "sod_violation", violationID, "", nil, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Exception decision recorded"})
+}
+
+// enforceSoD checks the proposed entitlements for a user against all active rules.
+// It writes a 409 response and returns false when any conflict is not covered by an
+// approved exception; otherwise the caller may proceed.
+func enforceSoD(w http.ResponseWriter, r *http.Request, userID int, action string, proposed []Entitlement) bool {
+	conflicts, err := findSoDConflicts(userID, proposed)
+	if err != nil {
+		http.Error(w, "Failed to evaluate separation of duties rules", http.StatusInternalServerError)
+		return false
+	}
+
+	actorID := GetActorID(r)
+
+	var blocking []sodConflict
+	for _, c := range conflicts {
+		status := "BLOCKED"
+		if c.Rule.Enforcement == "exception" {
+			if hasApprovedSoDException(c.Rule.ID, userID, c.Entitlement) {
+				continue
+			}
+			status = "PENDING_EXCEPTION"
+		}
+
+		c.Status = status
+		c.ViolationID = recordSoDViolation(c, userID, actorID, action)
+		blocking = append(blocking, c)
+	}
+
+	if len(blocking) == 0 {
+		return true
+	}
+
+	LogAudit(r, "sod.violation", "user", userID, "", nil, blocking)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      "Separation of duties violation",
+		"violations": blocking,
+	})
+	return false
+}
+
+// findSoDConflicts returns every active rule that would be violated if the user
+// held both their current entitlements and all of the proposed ones.
+func findSoDConflicts(userID int, proposed []Entitlement) ([]sodConflict, error) {
+	rules, err := loadSoDRules(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	held, err := getUserEntitlements(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []sodConflict
+	seen := map[string]bool{}
+	for i, p := range proposed {
+		others := append([]Entitlement{}, held...)
+		others = append(others, proposed[:i]...)
+		others = append(others, proposed[i+1:]...)
+
+		for _, rule := range rules {
+			violated := (matchesSoDSide(rule.LeftType, rule.LeftID, rule.LeftAccessLevel, p) &&
+				holdsSoDSide(rule.RightType, rule.RightID, rule.RightAccessLevel, others)) ||
+				(matchesSoDSide(rule.RightType, rule.RightID, rule.RightAccessLevel, p) &&
+					holdsSoDSide(rule.LeftType, rule.LeftID, rule.LeftAccessLevel, others))
+			if !violated {
+				continue
+			}
+			key := strconv.Itoa(rule.ID) + ":" + p.Type + ":" + strconv.Itoa(p.ID)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			conflicts = append(conflicts, sodConflict{Rule: rule, Entitlement: p})
+		}
+	}
+
+	return conflicts, nil
+}
+
+func matchesSoDSide(sideType string, sideID int, level *string, e Entitlement) bool {
+	if sideType != e.Type || sideID != e.ID {
+		return false
+	}
+	return level == nil || *level == "" || *level == e.AccessLevel
+}
+
+func holdsSoDSide(sideType string, sideID int, level *string, held []Entitlement) bool {
+	for _, e := range held {
+		if matchesSoDSide(sideType, sideID, level, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadSoDRules(activeOnly bool) ([]models.SoDRule, error) {
+	query := `
+		SELECT id, name, description, left_type, left_id, left_access_level,
+			   right_type, right_id, right_access_level, enforcement, is_active,
+			   created_by, created_at, updated_at
+		FROM sod_rules`
+	if activeOnly {
+		query += " WHERE is_active = 1"
+	}
+	query += " ORDER BY name"
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.SoDRule
+	for rows.Next() {
+		var rule models.SoDRule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Description, &rule.LeftType, &rule.LeftID,
+			&rule.LeftAccessLevel, &rule.RightType, &rule.RightID, &rule.RightAccessLevel,
+			&rule.Enforcement, &rule.IsActive, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// getUserEntitlements returns the roles, groups and effective tool access a user currently holds
+func getUserEntitlements(userID int) ([]Entitlement, error) {
+	var entitlements []Entitlement
+
+	rows, err := database.DB.Query(`
+		SELECT 'role', role_id, '' FROM user_roles WHERE user_id = ?
+		UNION ALL
+		SELECT 'group', group_id, '' FROM user_group_members WHERE user_id = ?`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Entitlement
+		if err := rows.Scan(&e.Type, &e.ID, &e.AccessLevel); err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Every level held counts, not just the highest, so rules on a lower level still
+	// match users who also hold a higher one
+	tools, err := loadHeldToolAccess(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tools {
+		entitlements = append(entitlements, Entitlement{Type: "tool", ID: t.ToolID, AccessLevel: t.AccessLevel})
+	}
+
+	return entitlements, nil
+}
+
+// getEffectiveToolAccess resolves the highest access level held on each tool through
+// approved grants, roles and groups
+func getEffectiveToolAccess(userID int) ([]models.ToolAccess, error) {
+	held, err := loadHeldToolAccess(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks, err := accessLevelRanks()
+	if err != nil {
+		return nil, err
+	}
+
+	var access []models.ToolAccess
+	index := map[int]int{}
+	for _, a := range held {
+		i, ok := index[a.ToolID]
+		if !ok {
+			index[a.ToolID] = len(access)
+			access = append(access, a)
+			continue
+		}
+		if levelRank(ranks, a.ToolID, a.AccessLevel) > levelRank(ranks, a.ToolID, access[i].AccessLevel) {
+			access[i] = a
+		}
+	}
+	return access, nil
+}
+
+// loadHeldToolAccess lists every tool access level a user holds through approved grants,
+// roles and groups, including several levels on the same tool
+func loadHeldToolAccess(userID int) ([]models.ToolAccess, error) {
+	rows, err := database.DB.Query(`
+		SELECT target_id, access_level FROM access_requests
+		WHERE user_id = ? AND target_type = 'tool' AND status = 'APPROVED'
+		  AND (expires_at IS NULL OR expires_at > ?)
+		UNION
+		SELECT rta.tool_id, rta.access_level FROM role_tool_access rta
+		JOIN user_roles ur ON rta.role_id = ur.role_id
+		WHERE ur.user_id = ?
+		UNION
+		SELECT gta.tool_id, gta.access_level FROM group_tool_access gta
+		JOIN user_group_members ugm ON gta.group_id = ugm.group_id
+		WHERE ugm.user_id = ?`, userID, time.Now(), userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var access []models.ToolAccess
+	for rows.Next() {
+		var a models.ToolAccess
+		if err := rows.Scan(&a.ToolID, &a.AccessLevel); err != nil {
+			return nil, err
+		}
+		access = append(access, a)
+	}
+	return access, rows.Err()
+}
+
+func hasApprovedSoDException(ruleID int, userID int, e Entitlement) bool {
+	var exists bool
+	database.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM sod_violations
+			WHERE rule_id = ? AND user_id = ? AND entitlement_type = ? AND entitlement_id = ?
+			  AND status = 'EXCEPTION_APPROVED'
+		)`, ruleID, userID, e.Type, e.ID).Scan(&exists)
+	return exists
+}
+
+// recordSoDViolation stores a detected conflict, reusing an open exception request if one exists
+func recordSoDViolation(c sodConflict, userID int, actorID int, action string) int64 {
+	if c.Status == "PENDING_EXCEPTION" {
+		var existingID int64
+		err := database.DB.QueryRow(`
+			SELECT id FROM sod_violations
+			WHERE rule_id = ? AND user_id = ? AND entitlement_type = ? AND entitlement_id = ?
+			  AND status = 'PENDING_EXCEPTION'`,
+			c.Rule.ID, userID, c.Entitlement.Type, c.Entitlement.ID).Scan(&existingID)
+		if err == nil {
+			return existingID
+		}
+	}
+
+	var level *string
+	if c.Entitlement.AccessLevel != "" {
+		level = &c.Entitlement.AccessLevel
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO sod_violations (rule_id, user_id, action, entitlement_type, entitlement_id, access_level, status, requested_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Rule.ID, userID, action, c.Entitlement.Type, c.Entitlement.ID, level, c.Status, actorID)
+	if err != nil {
+		return 0
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"gatekeepr/internal/database"
+	authMiddleware "gatekeepr/internal/middleware"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultAccessLevels apply to tools that have not declared their own, least privileged first
+var defaultAccessLevels = []string{"read", "write", "admin"}
+
+// accessLevelError reports a requested level the tool does not define
+type accessLevelError struct {
+	Level   string
+	Allowed []string
+}
+
+func (e *accessLevelError) Error() string {
+	return fmt.Sprintf("access level %q is not defined for this tool; expected one of: %s",
+		e.Level, joinStrings(e.Allowed, ", "))
+}
+
+// ListToolAccessLevels returns a tool's access levels, least privileged first
+func ListToolAccessLevels(w http.ResponseWriter, r *http.Request) {
+	toolID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var exists bool
+	database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM tools WHERE id = ?)", toolID).Scan(&exists)
+	if !exists {
+		http.Error(w, "Tool not found", http.StatusNotFound)
+		return
+	}
+
+	levels, err := loadToolAccessLevels(toolID)
+	if err != nil {
+		http.Error(w, "Failed to fetch access levels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levels)
+}
+
+// SetToolAccessLevels replaces a tool's access levels. Levels still held or requested
+// cannot be removed.
+func SetToolAccessLevels(w http.ResponseWriter, r *http.Request) {
+	toolID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.SetToolAccessLevelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var toolName string
+	if err := database.DB.QueryRow("SELECT name FROM tools WHERE id = ?", toolID).Scan(&toolName); err != nil {
+		http.Error(w, "Tool not found", http.StatusNotFound)
+		return
+	}
+
+	names := map[string]bool{}
+	for _, level := range req.Levels {
+		if level.Name == "" {
+			http.Error(w, "Every level needs a name", http.StatusBadRequest)
+			return
+		}
+		if names[level.Name] {
+			http.Error(w, fmt.Sprintf("Level %q is listed more than once", level.Name), http.StatusBadRequest)
+			return
+		}
+		names[level.Name] = true
+	}
+	if len(req.Levels) == 0 {
+		for _, name := range defaultAccessLevels {
+			names[name] = true
+		}
+	}
+
+	inUse, err := accessLevelsInUse(toolID)
+	if err != nil {
+		http.Error(w, "Failed to check access levels in use", http.StatusInternalServerError)
+		return
+	}
+	var missing []string
+	for _, name := range inUse {
+		if !names[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		http.Error(w, "Access levels still in use: "+joinStrings(missing, ", "), http.StatusConflict)
+		return
+	}
+
+	old, _ := loadToolAccessLevels(toolID)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM tool_access_levels WHERE tool_id = ?", toolID); err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to update access levels", http.StatusInternalServerError)
+		return
+	}
+	for i, level := range req.Levels {
+		_, err := tx.Exec(`
+			INSERT INTO tool_access_levels (tool_id, name, description, rank, auto_approve, approver_min_hierarchy)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			toolID, level.Name, level.Description, i, level.AutoApprove, level.ApproverMinHierarchy)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to update access levels", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "tool.access_levels.update", "tool", toolID, toolName, old, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Access levels updated successfully"})
+}
+
+// loadToolAccessLevels returns the levels a tool declares, or the defaults when it
+// declares none
+func loadToolAccessLevels(toolID int) ([]models.ToolAccessLevel, error) {
+	rows, err := database.DB.Query(`
+		SELECT tool_id, name, description, rank, auto_approve, approver_min_hierarchy
+		FROM tool_access_levels WHERE tool_id = ?
+		ORDER BY rank`, toolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var levels []models.ToolAccessLevel
+	for rows.Next() {
+		var l models.ToolAccessLevel
+		if err := rows.Scan(&l.ToolID, &l.Name, &l.Description, &l.Rank, &l.AutoApprove, &l.ApproverMinHierarchy); err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(levels) == 0 {
+		for i, name := range defaultAccessLevels {
+			levels = append(levels, models.ToolAccessLevel{ToolID: toolID, Name: name, Rank: i})
+		}
+	}
+	return levels, nil
+}
+
+// resolveAccessLevel validates a requested level against the target's levels. An empty
+// level becomes the tool's least privileged one. Roles and groups have no levels and
+// keep the historical "read" default.
+func resolveAccessLevel(targetType string, targetID int, level string) (string, error) {
+	if targetType != "tool" {
+		if level == "" {
+			level = "read"
+		}
+		return level, nil
+	}
+
+	levels, err := loadToolAccessLevels(targetID)
+	if err != nil {
+		return "", err
+	}
+	if level == "" {
+		return levels[0].Name, nil
+	}
+
+	allowed := make([]string, 0, len(levels))
+	for _, l := range levels {
+		if l.Name == level {
+			return level, nil
+		}
+		allowed = append(allowed, l.Name)
+	}
+	return "", &accessLevelError{Level: level, Allowed: allowed}
+}
+
+// findToolAccessLevel returns the definition of a tool level, or nil for non-tool
+// targets and unknown levels
+func findToolAccessLevel(targetType string, targetID int, level string) *models.ToolAccessLevel {
+	if targetType != "tool" {
+		return nil
+	}
+	levels, err := loadToolAccessLevels(targetID)
+	if err != nil {
+		return nil
+	}
+	for i := range levels {
+		if levels[i].Name == level {
+			return &levels[i]
+		}
+	}
+	return nil
+}
+
+// checkLevelApprover enforces a level's minimum approver hierarchy
+func checkLevelApprover(approverID int, targetType string, targetID int, level string) error {
+	def := findToolAccessLevel(targetType, targetID, level)
+	if def == nil || def.ApproverMinHierarchy == nil {
+		return nil
+	}
+	if authMiddleware.GetUserMaxHierarchy(approverID) < *def.ApproverMinHierarchy {
+		return fmt.Errorf("approving %s access requires a role at hierarchy level %d or above",
+			level, *def.ApproverMinHierarchy)
+	}
+	return nil
+}
+
+// accessLevelRanks maps each tool with declared levels to its level ranks
+func accessLevelRanks() (map[int]map[string]int, error) {
+	rows, err := database.DB.Query("SELECT tool_id, name, rank FROM tool_access_levels")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranks := map[int]map[string]int{}
+	for rows.Next() {
+		var toolID, rank int
+		var name string
+		if err := rows.Scan(&toolID, &name, &rank); err != nil {
+			return nil, err
+		}
+		if ranks[toolID] == nil {
+			ranks[toolID] = map[string]int{}
+		}
+		ranks[toolID][name] = rank
+	}
+	return ranks, rows.Err()
+}
+
+// levelRank orders a level within its tool. Levels the tool does not define rank
+// below all defined ones.
+func levelRank(ranks map[int]map[string]int, toolID int, level string) int {
+	if toolRanks, ok := ranks[toolID]; ok {
+		if rank, ok := toolRanks[level]; ok {
+			return rank
+		}
+		return -1
+	}
+	for i, name := range defaultAccessLevels {
+		if name == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// accessLevelsInUse lists the levels of a tool referenced by open requests, live
+// grants, role and group access, or bundles
+func accessLevelsInUse(toolID int) ([]string, error) {
+	rows, err := database.DB.Query(`
+		SELECT access_level FROM access_requests
+		WHERE target_type = 'tool' AND target_id = ? AND status IN ('PENDING', 'APPROVED')
+		UNION
+		SELECT access_level FROM role_tool_access WHERE tool_id = ?
+		UNION
+		SELECT access_level FROM group_tool_access WHERE tool_id = ?
+		UNION
+		SELECT access_level FROM access_bundle_items WHERE target_type = 'tool' AND target_id = ?`,
+		toolID, toolID, toolID, toolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var levels []string
+	for rows.Next() {
+		var level *string
+		if err := rows.Scan(&level); err != nil {
+			return nil, err
+		}
+		if level != nil {
+			levels = append(levels, *level)
+		}
+	}
+	sort.Strings(levels)
+	return levels, rows.Err()
+}
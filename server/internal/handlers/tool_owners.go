@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	authMiddleware "gatekeepr/internal/middleware"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListToolOwners returns the users and groups that own a tool
+func ListToolOwners(w http.ResponseWriter, r *http.Request) {
+	toolID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	rows, err := database.DB.Query(`
+		SELECT o.tool_id, o.owner_type, o.owner_id, o.added_by, o.added_at,
+			   CASE o.owner_type
+				   WHEN 'user' THEN (SELECT email FROM users WHERE id = o.owner_id)
+				   ELSE (SELECT name FROM user_groups WHERE id = o.owner_id)
+			   END
+		FROM tool_owners o
+		WHERE o.tool_id = ?
+		ORDER BY o.owner_type, o.owner_id`, toolID)
+	if err != nil {
+		http.Error(w, "Failed to fetch owners", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var owners []models.ToolOwner
+	for rows.Next() {
+		var o models.ToolOwner
+		var name *string
+		if err := rows.Scan(&o.ToolID, &o.OwnerType, &o.OwnerID, &o.AddedBy, &o.AddedAt, &name); err != nil {
+			http.Error(w, "Failed to scan owner", http.StatusInternalServerError)
+			return
+		}
+		if name != nil {
+			o.OwnerName = *name
+		}
+		owners = append(owners, o)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owners)
+}
+
+// AddToolOwner makes a user or group an owner of a tool
+func AddToolOwner(w http.ResponseWriter, r *http.Request) {
+	toolID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.AddToolOwnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (req.OwnerType != "user" && req.OwnerType != "group") || req.OwnerID == 0 {
+		http.Error(w, "owner_type must be user or group and owner_id is required", http.StatusBadRequest)
+		return
+	}
+
+	before, after, err := execWithSnapshots("tools", toolID, `
+		INSERT OR IGNORE INTO tool_owners (tool_id, owner_type, owner_id, added_by)
+		VALUES (?, ?, ?, ?)`, toolID, req.OwnerType, req.OwnerID, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to add owner", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "tool.owners.add", "tool", toolID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Owner added successfully"})
+}
+
+// RemoveToolOwner removes a user or group from a tool's owners
+func RemoveToolOwner(w http.ResponseWriter, r *http.Request) {
+	toolID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	ownerType := chi.URLParam(r, "ownerType")
+	ownerID, _ := strconv.Atoi(chi.URLParam(r, "ownerId"))
+
+	before, after, err := execWithSnapshots("tools", toolID, `
+		DELETE FROM tool_owners WHERE tool_id = ? AND owner_type = ? AND owner_id = ?`,
+		toolID, ownerType, ownerID)
+	if err != nil {
+		http.Error(w, "Failed to remove owner", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "tool.owners.remove", "tool", toolID, "", before, after)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Owner removed successfully"})
+}
+
+// GetToolAccessHolders returns everyone who currently has access to a tool.
+// Available to the tool's owners and to users who manage tool access.
+func GetToolAccessHolders(w http.ResponseWriter, r *http.Request) {
+	toolID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	actorID := GetActorID(r)
+
+	if !IsToolOwner(actorID, toolID) && !authMiddleware.UserHasPermission(actorID, "tools.manage_access") {
+		RecordAuthzDenial(r, "capability:tool_owner|permission:tools.manage_access")
+		http.Error(w, "You do not own this tool", http.StatusForbidden)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT ar.expires_at, ar.user_id, u.email, ar.access_level, 'grant', ar.id
+		FROM access_requests ar
+		JOIN users u ON ar.user_id = u.id
+		WHERE ar.target_type = 'tool' AND ar.target_id = ? AND ar.status = 'APPROVED'
+		  AND (ar.expires_at IS NULL OR ar.expires_at > ?)
+		UNION ALL
+		SELECT NULL, ur.user_id, u.email, rta.access_level, 'role', rta.role_id
+		FROM role_tool_access rta
+		JOIN user_roles ur ON rta.role_id = ur.role_id
+		JOIN users u ON ur.user_id = u.id
+		WHERE rta.tool_id = ?
+		UNION ALL
+		SELECT NULL, ugm.user_id, u.email, gta.access_level, 'group', gta.group_id
+		FROM group_tool_access gta
+		JOIN user_group_members ugm ON gta.group_id = ugm.group_id
+		JOIN users u ON ugm.user_id = u.id
+		WHERE gta.tool_id = ?
+		ORDER BY 3`, toolID, time.Now(), toolID, toolID)
+	if err != nil {
+		http.Error(w, "Failed to fetch access holders", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var holders []models.ToolAccessHolder
+	for rows.Next() {
+		var h models.ToolAccessHolder
+		if err := rows.Scan(&h.ExpiresAt, &h.UserID, &h.UserEmail, &h.AccessLevel, &h.Source, &h.SourceID); err != nil {
+			http.Error(w, "Failed to scan access holder", http.StatusInternalServerError)
+			return
+		}
+		holders = append(holders, h)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holders)
+}
+
+// ListMyOwnedTools returns the tools the current user owns directly or through a group
+func ListMyOwnedTools(w http.ResponseWriter, r *http.Request) {
+	actorID := GetActorID(r)
+
+	rows, err := database.DB.Query(`
+		SELECT id, name, display_name, description, category, icon, high_risk, is_active, created_at, updated_at
+		FROM tools
+		WHERE id IN (`+ownedToolsSubquery+`)
+		ORDER BY category, name`, actorID, actorID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tools", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var tools []models.Tool
+	for rows.Next() {
+		var tool models.Tool
+		if err := rows.Scan(&tool.ID, &tool.Name, &tool.DisplayName, &tool.Description,
+			&tool.Category, &tool.Icon, &tool.HighRisk, &tool.IsActive, &tool.CreatedAt, &tool.UpdatedAt); err != nil {
+			http.Error(w, "Failed to scan tool", http.StatusInternalServerError)
+			return
+		}
+		tools = append(tools, tool)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tools)
+}
+
+// ownedToolsSubquery selects the IDs of tools owned by a user; it takes the user ID twice
+const ownedToolsSubquery = `
+	SELECT o.tool_id FROM tool_owners o
+	WHERE (o.owner_type = 'user' AND o.owner_id = ?)
+	   OR (o.owner_type = 'group' AND o.owner_id IN (SELECT group_id FROM user_group_members WHERE user_id = ?))`
+
+// IsToolOwner checks if a user owns a tool directly or through group membership
+func IsToolOwner(userID int, toolID int) bool {
+	if userID == 0 {
+		return false
+	}
+
+	var isOwner bool
+	database.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM (`+ownedToolsSubquery+`) WHERE tool_id = ?)`,
+		userID, userID, toolID).Scan(&isOwner)
+	return isOwner
+}
+
+// canDecideRequest checks if the current user may approve or reject a request, in
+// their own right or as an active delegate of someone who may
+func canDecideRequest(r *http.Request, requestID int, targetType string, targetID int) bool {
+	_, ok := resolveRequestDecider(GetActorID(r), requestID, targetType, targetID)
+	return ok
+}
+
+// userCanDecideRequest checks if a user may decide a request through a role that
+// approves requests, by owning the tool, or by belonging to the group the request
+// was escalated to
+func userCanDecideRequest(userID int, requestID int, targetType string, targetID int) bool {
+	if userCanApproveRequests(userID) {
+		return true
+	}
+	if targetType == "tool" && IsToolOwner(userID, targetID) {
+		return true
+	}
+	return isInEscalationGroup(userID, requestID)
+}
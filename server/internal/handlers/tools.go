@@ -16,7 +16,7 @@ func ListTools(w http.ResponseWriter, r *http.Request) {
 	category := r.URL.Query().Get("category")
 	activeOnly := r.URL.Query().Get("active_only") == "true"
 
-	query := `SELECT id, name, display_name, description, category, icon, is_active, created_at, updated_at FROM tools`
+	query := `SELECT id, name, display_name, description, category, icon, high_risk, is_active, created_at, updated_at FROM tools`
 	where := []string{}
 	args := []interface{}{}
 
@@ -44,7 +44,7 @@ func ListTools(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var tool models.Tool
 		if err := rows.Scan(&tool.ID, &tool.Name, &tool.DisplayName, &tool.Description,
-			&tool.Category, &tool.Icon, &tool.IsActive, &tool.CreatedAt, &tool.UpdatedAt); err != nil {
+			&tool.Category, &tool.Icon, &tool.HighRisk, &tool.IsActive, &tool.CreatedAt, &tool.UpdatedAt); err != nil {
 			http.Error(w, "Failed to scan tool", http.StatusInternalServerError)
 			return
 		}
@@ -62,10 +62,10 @@ func GetTool(w http.ResponseWriter, r *http.Request) {
 
 	var tool models.Tool
 	err := database.DB.QueryRow(`
-		SELECT id, name, display_name, description, category, icon, is_active, created_at, updated_at
+		SELECT id, name, display_name, description, category, icon, high_risk, is_active, created_at, updated_at
 		FROM tools WHERE id = ?`, toolID).Scan(
 		&tool.ID, &tool.Name, &tool.DisplayName, &tool.Description,
-		&tool.Category, &tool.Icon, &tool.IsActive, &tool.CreatedAt, &tool.UpdatedAt)
+		&tool.Category, &tool.Icon, &tool.HighRisk, &tool.IsActive, &tool.CreatedAt, &tool.UpdatedAt)
 	if err != nil {
 		http.Error(w, "Tool not found", http.StatusNotFound)
 		return
@@ -89,9 +89,9 @@ func CreateTool(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result, err := database.DB.Exec(`
-		INSERT INTO tools (name, display_name, description, category, icon)
-		VALUES (?, ?, ?, ?, ?)`,
-		req.Name, req.DisplayName, req.Description, req.Category, req.Icon)
+		INSERT INTO tools (name, display_name, description, category, icon, high_risk)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		req.Name, req.DisplayName, req.Description, req.Category, req.Icon, req.HighRisk)
 	if err != nil {
 		http.Error(w, "Failed to create tool", http.StatusInternalServerError)
 		return
@@ -136,6 +136,10 @@ func UpdateTool(w http.ResponseWriter, r *http.Request) {
 		updates = append(updates, "icon = ?")
 		args = append(args, *req.Icon)
 	}
+	if req.HighRisk != nil {
+		updates = append(updates, "high_risk = ?")
+		args = append(args, *req.HighRisk)
+	}
 	if req.IsActive != nil {
 		updates = append(updates, "is_active = ?")
 		args = append(args, *req.IsActive)
@@ -149,13 +153,13 @@ func UpdateTool(w http.ResponseWriter, r *http.Request) {
 	query := "UPDATE tools SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
 	args = append(args, toolID)
 
-	_, err := database.DB.Exec(query, args...)
+	before, after, err := execWithSnapshots("tools", toolID, query, args...)
 	if err != nil {
 		http.Error(w, "Failed to update tool", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "tool.update", "tool", toolID, "", nil, &req)
+	LogAudit(r, "tool.update", "tool", toolID, "", before, after)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Tool updated successfully"})
@@ -169,13 +173,13 @@ func DeleteTool(w http.ResponseWriter, r *http.Request) {
 	var toolName string
 	database.DB.QueryRow("SELECT name FROM tools WHERE id = ?", toolID).Scan(&toolName)
 
-	_, err := database.DB.Exec("DELETE FROM tools WHERE id = ?", toolID)
+	before, _, err := execWithSnapshots("tools", toolID, "DELETE FROM tools WHERE id = ?", toolID)
 	if err != nil {
 		http.Error(w, "Failed to delete tool", http.StatusInternalServerError)
 		return
 	}
 
-	LogAudit(r, "tool.delete", "tool", toolID, toolName, nil, nil)
+	LogAudit(r, "tool.delete", "tool", toolID, toolName, before, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Tool deleted successfully"})
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+)
+
+// CheckToolAccess answers whether the current user may use a tool, for reverse proxies
+// doing forward authentication. The tool is given by ID or name in the "tool" query
+// parameter. Allowed checks count as use of the tool.
+func CheckToolAccess(w http.ResponseWriter, r *http.Request) {
+	tool := r.URL.Query().Get("tool")
+	if tool == "" {
+		http.Error(w, "tool is required", http.StatusBadRequest)
+		return
+	}
+
+	toolID, err := resolveToolID(tool)
+	if err != nil {
+		http.Error(w, "Tool not found", http.StatusNotFound)
+		return
+	}
+
+	userID := GetActorID(r)
+	access, err := getEffectiveToolAccess(userID)
+	if err != nil {
+		http.Error(w, "Failed to check access", http.StatusInternalServerError)
+		return
+	}
+
+	for _, a := range access {
+		if a.ToolID != toolID {
+			continue
+		}
+
+		recordToolUsage(userID, toolID, time.Now(), "check")
+
+		w.Header().Set("X-Gatekeepr-User-Id", strconv.Itoa(userID))
+		w.Header().Set("X-Gatekeepr-Access-Level", a.AccessLevel)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true, "access_level": a.AccessLevel})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]interface{}{"allowed": false})
+}
+
+// usageClockSkew is how far in the future a reported use may be, to allow for clocks
+// that run slightly ahead of the server's
+const usageClockSkew = 5 * time.Minute
+
+// ReportToolUsage ingests last-use signals pushed by tools or log shippers. Events that
+// cannot be matched to a user and tool are reported back and skipped.
+func ReportToolUsage(w http.ResponseWriter, r *http.Request) {
+	var req models.UsageReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" {
+		req.Source = "report"
+	}
+
+	type rejectedEvent struct {
+		Index int    `json:"index"`
+		Error string `json:"error"`
+	}
+	accepted := 0
+	rejected := []rejectedEvent{}
+
+	for i, event := range req.Events {
+		userID := event.UserID
+		if userID == 0 && event.UserEmail != "" {
+			database.DB.QueryRow("SELECT id FROM users WHERE email = ?", event.UserEmail).Scan(&userID)
+		}
+		if userID == 0 {
+			rejected = append(rejected, rejectedEvent{i, "unknown user"})
+			continue
+		}
+
+		toolID := event.ToolID
+		if toolID == 0 && event.ToolName != "" {
+			toolID, _ = resolveToolID(event.ToolName)
+		}
+		if toolID == 0 {
+			rejected = append(rejected, rejectedEvent{i, "unknown tool"})
+			continue
+		}
+
+		usedAt := time.Now()
+		if event.UsedAt != "" {
+			parsed, err := time.Parse(time.RFC3339, event.UsedAt)
+			if err != nil {
+				rejected = append(rejected, rejectedEvent{i, "used_at must be an RFC 3339 timestamp"})
+				continue
+			}
+			usedAt = parsed
+		}
+		if usedAt.After(time.Now().Add(usageClockSkew)) {
+			rejected = append(rejected, rejectedEvent{i, "used_at is in the future"})
+			continue
+		}
+
+		if err := recordToolUsage(userID, toolID, usedAt, req.Source); err != nil {
+			rejected = append(rejected, rejectedEvent{i, "failed to record usage"})
+			continue
+		}
+		accepted++
+	}
+
+	LogAudit(r, "usage.report", "tool_usage", 0, req.Source, nil, map[string]int{
+		"accepted": accepted,
+		"rejected": len(rejected),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"accepted": accepted, "rejected": rejected})
+}
+
+// recordToolUsage moves a user's last use of a tool forward and clears any dormancy
+// warning on their grants for it that the use came after, so replaying an old event
+// does not undo a warning
+func recordToolUsage(userID int, toolID int, usedAt time.Time, source string) error {
+	// Stored in server local time so comparisons stay consistent with time.Now()
+	_, err := database.DB.Exec(`
+		INSERT INTO tool_usage (user_id, tool_id, last_used_at, source)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, tool_id) DO UPDATE SET last_used_at = excluded.last_used_at, source = excluded.source
+		WHERE excluded.last_used_at > tool_usage.last_used_at`,
+		userID, toolID, usedAt.Local(), source)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE access_requests SET dormant_notified_at = NULL, dormant_flagged_at = NULL
+		WHERE user_id = ? AND target_type = 'tool' AND target_id = ? AND status = 'APPROVED'
+		  AND ? > COALESCE(dormant_notified_at, dormant_flagged_at)`,
+		userID, toolID, usedAt.Local())
+	return err
+}
+
+// resolveToolID looks a tool up by numeric ID or by name
+func resolveToolID(tool string) (int, error) {
+	var id int
+	if n, err := strconv.Atoi(tool); err == nil {
+		err = database.DB.QueryRow("SELECT id FROM tools WHERE id = ?", n).Scan(&id)
+		return id, err
+	}
+	err := database.DB.QueryRow("SELECT id FROM tools WHERE name = ?", tool).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("tool %q: %w", tool, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UpdateUserOrg sets a user's department and manager, which drive access review scoping
+// and reviewer assignment
+func UpdateUserOrg(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateUserOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var old models.User
+	err := database.DB.QueryRow("SELECT email, department, manager_id FROM users WHERE id = ?", userID).
+		Scan(&old.Email, &old.Department, &old.ManagerID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.Department != nil {
+		updates = append(updates, "department = ?")
+		args = append(args, nullableString(*req.Department))
+	}
+	if req.ManagerID != nil {
+		if *req.ManagerID == userID {
+			http.Error(w, "A user cannot be their own manager", http.StatusBadRequest)
+			return
+		}
+		if *req.ManagerID != 0 {
+			var exists bool
+			database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", *req.ManagerID).Scan(&exists)
+			if !exists {
+				http.Error(w, "Manager not found", http.StatusBadRequest)
+				return
+			}
+		}
+		updates = append(updates, "manager_id = ?")
+		args = append(args, nullableID(*req.ManagerID))
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE users SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, userID)
+
+	if _, err := database.DB.Exec(query, args...); err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "user.org.update", "user", userID, old.Email,
+		models.UpdateUserOrgRequest{Department: old.Department, ManagerID: old.ManagerID}, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User updated successfully"})
+}
@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeepr/internal/database"
+	"gatekeepr/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+)
+
+// webhookClient sends webhook deliveries. Endpoints that take longer than this are
+// treated as failed and retried.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookEvent is the JSON body posted to a webhook for one audit log entry
+type webhookEvent struct {
+	EventID    int             `json:"event_id"`
+	Event      string          `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	ActorID    *int            `json:"actor_id"`
+	Target     webhookTarget   `json:"target"`
+	Severity   string          `json:"severity"`
+	Details    *string         `json:"details,omitempty"`
+	OldValue   json.RawMessage `json:"old_value,omitempty"`
+	NewValue   json.RawMessage `json:"new_value,omitempty"`
+	RequestID  *string         `json:"request_id,omitempty"`
+}
+
+type webhookTarget struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// webhookDelivery is a due delivery together with the webhook it goes to
+type webhookDelivery struct {
+	ID          int
+	Event       string
+	Payload     []byte
+	Attempts    int
+	URL         string
+	Secret      string
+	MaxAttempts int
+}
+
+const webhookColumns = `id, name, url, events, max_attempts, is_active, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, webhook_id, audit_log_id, event, payload, status, attempts, response_code,
+	response_body, last_error, next_attempt_at, delivered_at, created_at`
+
+// ListWebhooks returns all webhooks without their secrets
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY name")
+	if err != nil {
+		http.Error(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan webhook", http.StatusInternalServerError)
+			return
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// GetWebhook returns a single webhook without its secret
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	wh, err := scanWebhook(database.DB.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", webhookID))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wh)
+}
+
+// CreateWebhook subscribes a URL to events. A secret is generated when none is given
+// and is only ever returned in this response.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if err := validateWebhook(req.URL, req.Events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxAttempts := 8
+	if req.MaxAttempts != nil {
+		if *req.MaxAttempts <= 0 {
+			http.Error(w, "max_attempts must be positive", http.StatusBadRequest)
+			return
+		}
+		maxAttempts = *req.MaxAttempts
+	}
+	if req.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+			return
+		}
+		req.Secret = secret
+	}
+
+	events, _ := json.Marshal(req.Events)
+	result, err := database.DB.Exec(`
+		INSERT INTO webhooks (name, url, secret, events, max_attempts, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		req.Name, req.URL, req.Secret, string(events), maxAttempts, GetActorID(r))
+	if err != nil {
+		http.Error(w, "Failed to create webhook (name may already exist)", http.StatusConflict)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "webhook.create", "webhook", int(id), req.Name, nil, map[string]interface{}{
+		"url":    req.URL,
+		"events": req.Events,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"secret":  req.Secret,
+		"message": "Webhook created successfully",
+	})
+}
+
+// UpdateWebhook updates a webhook's endpoint, secret, event filters or state
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var req models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	old, err := scanWebhook(database.DB.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", webhookID))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	url, events := old.URL, old.Events
+	if req.URL != nil {
+		url = *req.URL
+	}
+	if req.Events != nil {
+		events = *req.Events
+	}
+	if err := validateWebhook(url, events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			http.Error(w, "name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "name = ?")
+		args = append(args, *req.Name)
+	}
+	if req.URL != nil {
+		updates = append(updates, "url = ?")
+		args = append(args, *req.URL)
+	}
+	if req.Secret != nil {
+		if *req.Secret == "" {
+			http.Error(w, "secret cannot be empty", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "secret = ?")
+		args = append(args, *req.Secret)
+	}
+	if req.Events != nil {
+		data, _ := json.Marshal(*req.Events)
+		updates = append(updates, "events = ?")
+		args = append(args, string(data))
+	}
+	if req.MaxAttempts != nil {
+		if *req.MaxAttempts <= 0 {
+			http.Error(w, "max_attempts must be positive", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "max_attempts = ?")
+		args = append(args, *req.MaxAttempts)
+	}
+	if req.IsActive != nil {
+		updates = append(updates, "is_active = ?")
+		args = append(args, *req.IsActive)
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	query := "UPDATE webhooks SET " + joinStrings(updates, ", ") + ", updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	args = append(args, webhookID)
+
+	if _, err := database.DB.Exec(query, args...); err != nil {
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Secret != nil {
+		rotated := "rotated"
+		req.Secret = &rotated
+	}
+	LogAudit(r, "webhook.update", "webhook", webhookID, old.Name, old, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook updated successfully"})
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	var name string
+	if err := database.DB.QueryRow("SELECT name FROM webhooks WHERE id = ?", webhookID).Scan(&name); err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	before, _, err := execWithSnapshots("webhooks", webhookID, "DELETE FROM webhooks WHERE id = ?", webhookID)
+	if err != nil {
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	LogAudit(r, "webhook.delete", "webhook", webhookID, name, before, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// ListWebhookDeliveries returns a webhook's delivery log, newest first, optionally
+// filtered by ?status=
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE webhook_id = ?"
+	args := []interface{}{webhookID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT 200"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan delivery", http.StatusInternalServerError)
+			return
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RedeliverWebhookDelivery queues a finished delivery again as a new delivery with the
+// same payload, keeping the original in the log
+func RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	d, err := scanWebhookDelivery(database.DB.QueryRow(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = ?", deliveryID))
+	if err != nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if d.Status == DeliveryPending {
+		http.Error(w, "Delivery is still pending", http.StatusConflict)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, audit_log_id, event, payload, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?)`, d.WebhookID, d.AuditLogID, d.Event, string(d.Payload), time.Now())
+	if err != nil {
+		http.Error(w, "Failed to queue delivery", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	LogAudit(r, "webhook.redeliver", "webhook_delivery", deliveryID, d.Event, nil, map[string]int64{"delivery_id": id})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Delivery queued"})
+}
+
+// StartWebhookWorker sends due webhook deliveries every interval until ctx is cancelled
+func StartWebhookWorker(ctx context.Context, interval time.Duration) {
+	startPeriodicJob(ctx, "webhook worker", interval, func(now time.Time) error {
+		return deliverWebhooks(ctx, now)
+	})
+}
+
+// enqueueWebhookEvent queues a delivery of an audit log entry to every active webhook
+// whose filters match its action. Webhook administration is never delivered, so a
+// failing endpoint subscribed to everything cannot feed itself.
+func enqueueWebhookEvent(auditLogID int, rec auditRecord, oldJSON *string, newJSON *string) {
+	if strings.HasPrefix(rec.Action, "webhook.") {
+		return
+	}
+
+	rows, err := database.DB.Query("SELECT id, events FROM webhooks WHERE is_active = TRUE")
+	if err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", rec.Action, err)
+		return
+	}
+	var targets []int
+	for rows.Next() {
+		var id int
+		var events string
+		if err := rows.Scan(&id, &events); err != nil {
+			continue
+		}
+		var patterns []string
+		json.Unmarshal([]byte(events), &patterns)
+		if matchesEventFilter(patterns, rec.Action) {
+			targets = append(targets, id)
+		}
+	}
+	rows.Close()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	event := webhookEvent{
+		EventID:    auditLogID,
+		Event:      rec.Action,
+		OccurredAt: time.Now().UTC(),
+		ActorID:    rec.ActorID,
+		Target:     webhookTarget{Type: rec.TargetType, ID: rec.TargetID, Name: rec.TargetName},
+		Severity:   rec.Severity,
+		Details:    rec.Details,
+		RequestID:  rec.RequestID,
+	}
+	if oldJSON != nil {
+		event.OldValue = json.RawMessage(*oldJSON)
+	}
+	if newJSON != nil {
+		event.NewValue = json.RawMessage(*newJSON)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode webhook event %s: %v", rec.Action, err)
+		return
+	}
+
+	for _, webhookID := range targets {
+		_, err := database.DB.Exec(`
+			INSERT INTO webhook_deliveries (webhook_id, audit_log_id, event, payload, next_attempt_at)
+			VALUES (?, ?, ?, ?, ?)`, webhookID, auditLogID, rec.Action, string(payload), time.Now())
+		if err != nil {
+			log.Printf("Failed to queue %s for webhook %d: %v", rec.Action, webhookID, err)
+		}
+	}
+}
+
+// matchesEventFilter reports whether an action matches any of a webhook's patterns. A
+// pattern is an exact action such as "role.update", a prefix ending in ".*" such as
+// "access.request.*", or "*" for every event.
+func matchesEventFilter(patterns []string, action string) bool {
+	for _, p := range patterns {
+		switch {
+		case p == "*" || p == action:
+			return true
+		case strings.HasSuffix(p, ".*") && strings.HasPrefix(action, strings.TrimSuffix(p, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhooks sends every due delivery of an active webhook
+func deliverWebhooks(ctx context.Context, now time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT d.id, d.event, d.payload, d.attempts, wh.url, wh.secret, wh.max_attempts
+		FROM webhook_deliveries d
+		JOIN webhooks wh ON d.webhook_id = wh.id
+		WHERE d.status = 'PENDING' AND d.next_attempt_at <= ? AND wh.is_active = TRUE
+		ORDER BY d.id`, now)
+	if err != nil {
+		return err
+	}
+
+	var due []webhookDelivery
+	for rows.Next() {
+		var d webhookDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.Event, &payload, &d.Attempts, &d.URL, &d.Secret, &d.MaxAttempts); err != nil {
+			rows.Close()
+			return err
+		}
+		d.Payload = []byte(payload)
+		due = append(due, d)
+	}
+	rows.Close()
+
+	for _, d := range due {
+		deliverWebhook(ctx, d, now)
+	}
+	return nil
+}
+
+// deliverWebhook posts a delivery and records the response. Any non-2xx response or
+// network failure is retried with exponential backoff until the webhook's attempt
+// budget runs out.
+func deliverWebhook(ctx context.Context, d webhookDelivery, now time.Time) {
+	code, body, err := postWebhook(ctx, d)
+	attempts := d.Attempts + 1
+
+	var codeArg, bodyArg interface{}
+	if code != 0 {
+		codeArg, bodyArg = code, body
+	}
+
+	if err == nil {
+		database.DB.Exec(`
+			UPDATE webhook_deliveries
+			SET status = ?, attempts = ?, response_code = ?, response_body = ?, last_error = NULL, delivered_at = ?
+			WHERE id = ?`, DeliveryDelivered, attempts, codeArg, bodyArg, now, d.ID)
+		return
+	}
+
+	if attempts >= d.MaxAttempts {
+		database.DB.Exec(`
+			UPDATE webhook_deliveries
+			SET status = ?, attempts = ?, response_code = ?, response_body = ?, last_error = ?
+			WHERE id = ?`, DeliveryFailed, attempts, codeArg, bodyArg, err.Error(), d.ID)
+		log.Printf("Webhook delivery %d (%s) failed after %d attempts: %v", d.ID, d.Event, attempts, err)
+		return
+	}
+
+	database.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET attempts = ?, response_code = ?, response_body = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`, attempts, codeArg, bodyArg, err.Error(), now.Add(retryBackoff(attempts)), d.ID)
+}
+
+// postWebhook sends the payload signed with the webhook's secret. Receivers verify the
+// X-Gatekeepr-Signature header, "sha256=" followed by the hex HMAC-SHA256 of the body.
+func postWebhook(ctx context.Context, d webhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gatekeepr-webhooks")
+	req.Header.Set("X-Gatekeepr-Event", d.Event)
+	req.Header.Set("X-Gatekeepr-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Gatekeepr-Signature", "sha256="+signWithKey([]byte(d.Secret), d.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+func validateWebhook(url string, events []string) error {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return errors.New("url must be an http or https URL")
+	}
+	if len(events) == 0 {
+		return errors.New("events must list at least one event pattern")
+	}
+	for _, e := range events {
+		if e == "" || (strings.Contains(e, "*") && e != "*" && !strings.HasSuffix(e, ".*")) ||
+			strings.Count(e, "*") > 1 {
+			return fmt.Errorf("invalid event pattern %q; use an action, a prefix ending in .*, or *", e)
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var wh models.Webhook
+	var events string
+	err := row.Scan(&wh.ID, &wh.Name, &wh.URL, &events, &wh.MaxAttempts, &wh.IsActive, &wh.CreatedBy,
+		&wh.CreatedAt, &wh.UpdatedAt)
+	if err != nil {
+		return wh, err
+	}
+	json.Unmarshal([]byte(events), &wh.Events)
+	return wh, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	err := row.Scan(&d.ID, &d.WebhookID, &d.AuditLogID, &d.Event, &payload, &d.Status, &d.Attempts,
+		&d.ResponseCode, &d.ResponseBody, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt)
+	d.Payload = json.RawMessage(payload)
+	return d, err
+}
@@ -2,11 +2,25 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"gatekeepr/internal/auth"
 	"gatekeepr/internal/database"
 )
 
+// OnForbidden, when set, is told about every request refused for lacking a role,
+// permission or hierarchy level. requirement names what was missing, such as
+// "permission:audit.read" or "role:admin|manager".
+var OnForbidden func(r *http.Request, requirement string)
+
+func forbidden(w http.ResponseWriter, r *http.Request, requirement string, message string) {
+	if OnForbidden != nil {
+		OnForbidden(r, requirement)
+	}
+	http.Error(w, message, http.StatusForbidden)
+}
+
 // RequireRole middleware checks if user has any of the specified roles
 func RequireRole(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -34,7 +48,7 @@ func RequireRole(roles ...string) func(http.Handler) http.Handler {
 			}
 
 			if !hasRole {
-				http.Error(w, "Forbidden: insufficient role", http.StatusForbidden)
+				forbidden(w, r, "role:"+strings.Join(roles, "|"), "Forbidden: insufficient role")
 				return
 			}
 
@@ -55,7 +69,7 @@ func RequirePermission(permission string) func(http.Handler) http.Handler {
 
 			hasPermission := UserHasPermission(claims.UserID, permission)
 			if !hasPermission {
-				http.Error(w, "Forbidden: insufficient permission", http.StatusForbidden)
+				forbidden(w, r, "permission:"+permission, "Forbidden: insufficient permission")
 				return
 			}
 
@@ -81,7 +95,7 @@ func RequireHierarchy(minLevel int) func(http.Handler) http.Handler {
 				JOIN roles r ON ur.role_id = r.id
 				WHERE ur.user_id = ?`, claims.UserID).Scan(&maxLevel)
 			if err != nil || maxLevel < minLevel {
-				http.Error(w, "Forbidden: insufficient hierarchy level", http.StatusForbidden)
+				forbidden(w, r, "hierarchy:"+strconv.Itoa(minLevel), "Forbidden: insufficient hierarchy level")
 				return
 			}
 
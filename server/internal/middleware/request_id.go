@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds IDs taken from clients, which end up in every audit entry
+// the request writes
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID, taken from the X-Request-Id header when the
+// caller sent a usable one. The ID is stored where chi's Logger prints it, returned in
+// the X-Request-Id response header and appended to plain-text error bodies.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(&requestIDWriter{ResponseWriter: w, id: id}, r.WithContext(ctx))
+	})
+}
+
+// GetRequestID returns the ID RequestID gave the request, or "" if it did not run
+func GetRequestID(r *http.Request) string {
+	return middleware.GetReqID(r.Context())
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDWriter appends the request ID to error bodies written by http.Error, so a
+// user reporting an error can quote it. JSON and HTML bodies are left alone.
+type requestIDWriter struct {
+	http.ResponseWriter
+	id        string
+	appending bool
+}
+
+func (w *requestIDWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.appending = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *requestIDWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if w.appending && err == nil {
+		w.appending = false
+		fmt.Fprintf(w.ResponseWriter, "Request ID: %s\n", w.id)
+	}
+	return n, err
+}
+
+// Flush keeps streaming responses such as the inbox event stream working
+func (w *requestIDWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *requestIDWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
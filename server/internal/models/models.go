@@ -1,17 +1,23 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User represents a system user
 type User struct {
-	ID           int       `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	FirstName    *string   `json:"first_name,omitempty"`
-	LastName     *string   `json:"last_name,omitempty"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"`
+	FirstName    *string    `json:"first_name,omitempty"`
+	LastName     *string    `json:"last_name,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	Department   *string    `json:"department,omitempty"`
+	ManagerID    *int       `json:"manager_id,omitempty"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 
 	// Computed fields
 	Roles       []Role   `json:"roles,omitempty"`
@@ -55,6 +61,7 @@ type Tool struct {
 	Description *string   `json:"description,omitempty"`
 	Category    *string   `json:"category,omitempty"`
 	Icon        *string   `json:"icon,omitempty"`
+	HighRisk    bool      `json:"high_risk"`
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -105,25 +112,64 @@ type ToolAccess struct {
 	ToolName    string `json:"tool_name,omitempty"`
 }
 
+// ToolAccessLevel is one of a tool's ordered access levels. Rank runs from least to most
+// privileged. Requests for an AutoApprove level skip review, and approvers of a level with
+// ApproverMinHierarchy must hold a role at that hierarchy level or above.
+type ToolAccessLevel struct {
+	ToolID               int     `json:"tool_id"`
+	Name                 string  `json:"name"`
+	Description          *string `json:"description,omitempty"`
+	Rank                 int     `json:"rank"`
+	AutoApprove          bool    `json:"auto_approve"`
+	ApproverMinHierarchy *int    `json:"approver_min_hierarchy,omitempty"`
+}
+
+// ToolOwner is a user or group responsible for a tool
+type ToolOwner struct {
+	ToolID    int       `json:"tool_id"`
+	OwnerType string    `json:"owner_type"`
+	OwnerID   int       `json:"owner_id"`
+	AddedBy   *int      `json:"added_by,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+
+	// Computed fields
+	OwnerName string `json:"owner_name,omitempty"`
+}
+
+// ToolAccessHolder is a user who currently has access to a tool and how they got it
+type ToolAccessHolder struct {
+	UserID      int        `json:"user_id"`
+	UserEmail   string     `json:"user_email"`
+	AccessLevel string     `json:"access_level"`
+	Source      string     `json:"source"`
+	SourceID    int        `json:"source_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
 // AccessRequest represents a request for access
 type AccessRequest struct {
-	ID              int        `json:"id"`
-	UserID          int        `json:"user_id"`
-	RequestType     string     `json:"request_type"`
-	TargetType      string     `json:"target_type"`
-	TargetID        int        `json:"target_id"`
-	AccessLevel     string     `json:"access_level"`
-	Status          string     `json:"status"`
-	Reason          *string    `json:"reason,omitempty"`
-	DurationMinutes *int       `json:"duration_minutes,omitempty"`
-	ApproverID      *int       `json:"approver_id,omitempty"`
-	ApprovedBy      *int       `json:"approved_by,omitempty"`
-	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
-	RejectedBy      *int       `json:"rejected_by,omitempty"`
-	RejectedAt      *time.Time `json:"rejected_at,omitempty"`
-	RejectionReason *string    `json:"rejection_reason,omitempty"`
-	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
+	ID                 int        `json:"id"`
+	UserID             int        `json:"user_id"`
+	RequestType        string     `json:"request_type"`
+	TargetType         string     `json:"target_type"`
+	TargetID           int        `json:"target_id"`
+	AccessLevel        string     `json:"access_level"`
+	Status             string     `json:"status"`
+	Reason             *string    `json:"reason,omitempty"`
+	DurationMinutes    *int       `json:"duration_minutes,omitempty"`
+	ApproverID         *int       `json:"approver_id,omitempty"`
+	ApprovedBy         *int       `json:"approved_by,omitempty"`
+	ApprovedAt         *time.Time `json:"approved_at,omitempty"`
+	RejectedBy         *int       `json:"rejected_by,omitempty"`
+	RejectedAt         *time.Time `json:"rejected_at,omitempty"`
+	RejectionReason    *string    `json:"rejection_reason,omitempty"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	AutoApprovalRuleID *int       `json:"auto_approval_rule_id,omitempty"`
+	IncidentReference  *string    `json:"incident_reference,omitempty"`
+	ParentRequestID    *int       `json:"parent_request_id,omitempty"`
+	BundleRequestID    *int       `json:"bundle_request_id,omitempty"`
+	ProvisioningStatus *string    `json:"provisioning_status,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
 
 	// Computed fields
 	UserEmail      string `json:"user_email,omitempty"`
@@ -133,6 +179,103 @@ type AccessRequest struct {
 	RejectedByName string `json:"rejected_by_name,omitempty"`
 }
 
+// BreakGlassReview is the post-hoc review opened for every break-glass grant
+type BreakGlassReview struct {
+	ID              int        `json:"id"`
+	AccessRequestID int        `json:"access_request_id"`
+	Status          string     `json:"status"`
+	ReviewerID      *int       `json:"reviewer_id,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	// Computed fields
+	UserID            int        `json:"user_id"`
+	UserEmail         string     `json:"user_email"`
+	TargetType        string     `json:"target_type"`
+	TargetID          int        `json:"target_id"`
+	AccessLevel       string     `json:"access_level"`
+	Reason            *string    `json:"reason,omitempty"`
+	IncidentReference *string    `json:"incident_reference,omitempty"`
+	GrantedAt         time.Time  `json:"granted_at"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+// AccessRequestComment is a message in the discussion thread of an access request
+type AccessRequestComment struct {
+	ID        int       `json:"id"`
+	RequestID int       `json:"request_id"`
+	UserID    int       `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Computed fields
+	UserEmail string `json:"user_email,omitempty"`
+}
+
+// ApprovalDelegation lets a delegate decide requests on behalf of an approver for a date range
+type ApprovalDelegation struct {
+	ID          int        `json:"id"`
+	DelegatorID int        `json:"delegator_id"`
+	DelegateID  int        `json:"delegate_id"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	Reason      *string    `json:"reason,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Computed fields
+	DelegatorEmail string `json:"delegator_email,omitempty"`
+	DelegateEmail  string `json:"delegate_email,omitempty"`
+}
+
+// ReviewCampaign is a periodic access certification. Launching it snapshots the
+// access in scope into review items that reviewers keep or revoke.
+type ReviewCampaign struct {
+	ID                 int        `json:"id"`
+	Name               string     `json:"name"`
+	Description        *string    `json:"description,omitempty"`
+	ScopeType          string     `json:"scope_type"`
+	ScopeID            *int       `json:"scope_id,omitempty"`
+	ScopeValue         *string    `json:"scope_value,omitempty"`
+	Status             string     `json:"status"`
+	DueAt              time.Time  `json:"due_at"`
+	RevokeOnNoResponse bool       `json:"revoke_on_no_response"`
+	CreatedBy          *int       `json:"created_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	ReportSignature    *string    `json:"report_signature,omitempty"`
+
+	// Computed fields
+	TotalItems   int `json:"total_items"`
+	DecidedItems int `json:"decided_items"`
+	RevokedItems int `json:"revoked_items"`
+}
+
+// ReviewItem is one entitlement of one user under review. EntitlementID is a role ID,
+// group ID or access request ID depending on EntitlementType.
+type ReviewItem struct {
+	ID              int        `json:"id"`
+	CampaignID      int        `json:"campaign_id"`
+	UserID          int        `json:"user_id"`
+	EntitlementType string     `json:"entitlement_type"`
+	EntitlementID   int        `json:"entitlement_id"`
+	TargetType      *string    `json:"target_type,omitempty"`
+	TargetID        *int       `json:"target_id,omitempty"`
+	AccessLevel     *string    `json:"access_level,omitempty"`
+	ReviewerID      int        `json:"reviewer_id"`
+	Decision        *string    `json:"decision,omitempty"`
+	Comment         *string    `json:"comment,omitempty"`
+	DecidedBy       *int       `json:"decided_by,omitempty"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	// Computed fields
+	UserEmail       string `json:"user_email,omitempty"`
+	ReviewerEmail   string `json:"reviewer_email,omitempty"`
+	EntitlementName string `json:"entitlement_name,omitempty"`
+}
+
 // AuditLog represents an audit log entry
 type AuditLog struct {
 	ID             int       `json:"id"`
@@ -147,12 +290,425 @@ type AuditLog struct {
 	NewValue       *string   `json:"new_value,omitempty"`
 	IPAddress      *string   `json:"ip_address,omitempty"`
 	UserAgent      *string   `json:"user_agent,omitempty"`
+	Severity       string    `json:"severity"`
 	CreatedAt      time.Time `json:"created_at"`
+	PrevHash       *string   `json:"prev_hash,omitempty"`
+	Hash           *string   `json:"hash,omitempty"`
+	RequestID      *string   `json:"request_id,omitempty"`
 
 	// Computed fields
 	ActorEmail string `json:"actor_email,omitempty"`
 }
 
+// AuditFieldChange is one field that differs between an audit entry's before and
+// after state. Added and Removed list the items that changed in a list field.
+type AuditFieldChange struct {
+	Field   string        `json:"field"`
+	Change  string        `json:"change"`
+	Old     interface{}   `json:"old,omitempty"`
+	New     interface{}   `json:"new,omitempty"`
+	Added   []interface{} `json:"added,omitempty"`
+	Removed []interface{} `json:"removed,omitempty"`
+}
+
+// AuditLogDetail is an audit entry together with its field-level diff
+type AuditLogDetail struct {
+	AuditLog
+	Changes []AuditFieldChange `json:"changes"`
+}
+
+// AuditSearchResponse is one page of audit search results, newest first unless asked
+// otherwise. NextCursor fetches the following page; it is absent on the last one.
+// Facets are only computed for the first page.
+type AuditSearchResponse struct {
+	Data       []AuditLog         `json:"data"`
+	NextCursor *string            `json:"next_cursor,omitempty"`
+	FullText   bool               `json:"full_text"`
+	Facets     *AuditSearchFacets `json:"facets,omitempty"`
+}
+
+// AuditSearchFacets break all the entries matching a search down by category,
+// severity and actor
+type AuditSearchFacets struct {
+	Total      int               `json:"total"`
+	Categories []AuditFacet      `json:"categories"`
+	Severities []AuditFacet      `json:"severities"`
+	Actors     []AuditActorFacet `json:"actors"`
+}
+
+// AuditFacet counts the matching entries that share one value of a field
+type AuditFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// AuditActorFacet counts the matching entries of one actor. ActorID is absent for
+// entries gatekeepr wrote itself.
+type AuditActorFacet struct {
+	ActorID *int   `json:"actor_id,omitempty"`
+	Email   string `json:"email"`
+	Count   int    `json:"count"`
+}
+
+// AuditCheckpoint is a signed record of the audit chain head at a point in time
+type AuditCheckpoint struct {
+	ID         int       `json:"id"`
+	LastLogID  int       `json:"last_log_id"`
+	Hash       string    `json:"hash"`
+	EntryCount int       `json:"entry_count"`
+	Signature  string    `json:"signature"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditRetentionPolicy sets how many days entries of an action category stay in the
+// database before they are archived. Category "*" is the default.
+type AuditRetentionPolicy struct {
+	ID             int       `json:"id"`
+	ActionCategory string    `json:"action_category"`
+	RetentionDays  int       `json:"retention_days"`
+	CreatedBy      *int      `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// AuditLegalHold keeps matching audit entries from being archived until it is released
+type AuditLegalHold struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Reason         *string    `json:"reason,omitempty"`
+	ActionCategory *string    `json:"action_category,omitempty"`
+	ActorID        *int       `json:"actor_id,omitempty"`
+	TargetType     *string    `json:"target_type,omitempty"`
+	TargetID       *int       `json:"target_id,omitempty"`
+	DateFrom       *string    `json:"date_from,omitempty"`
+	DateTo         *string    `json:"date_to,omitempty"`
+	HeldEntries    int        `json:"held_entries"`
+	CreatedBy      *int       `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReleasedBy     *int       `json:"released_by,omitempty"`
+	ReleasedAt     *time.Time `json:"released_at,omitempty"`
+}
+
+// AuditArchive is a compressed NDJSON file of audit entries moved out of the database
+type AuditArchive struct {
+	ID             int       `json:"id"`
+	FileName       string    `json:"file_name"`
+	SHA256         string    `json:"sha256"`
+	EntryCount     int       `json:"entry_count"`
+	ArchivedCount  int       `json:"archived_count"`
+	FirstLogID     int       `json:"first_log_id"`
+	LastLogID      int       `json:"last_log_id"`
+	FirstCreatedAt time.Time `json:"first_created_at"`
+	LastCreatedAt  time.Time `json:"last_created_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AuditSinkDeadLetter is an audit entry an external sink could not take
+type AuditSinkDeadLetter struct {
+	ID         int       `json:"id"`
+	Sink       string    `json:"sink"`
+	AuditLogID int       `json:"audit_log_id"`
+	Action     string    `json:"action"`
+	Attempts   int       `json:"attempts"`
+	Error      *string   `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuthzDenialPrincipal counts the requests a user was refused for lacking privileges
+type AuthzDenialPrincipal struct {
+	UserID       int       `json:"user_id"`
+	Email        string    `json:"email"`
+	Denials      int       `json:"denials"`
+	Requirements int       `json:"requirements"`
+	LastDeniedAt time.Time `json:"last_denied_at"`
+}
+
+// AuthzDenialRequirement counts the requests refused for lacking one role, permission
+// or hierarchy level
+type AuthzDenialRequirement struct {
+	Requirement  string    `json:"requirement"`
+	Denials      int       `json:"denials"`
+	Principals   int       `json:"principals"`
+	LastDeniedAt time.Time `json:"last_denied_at"`
+}
+
+// AutoApprovalRule approves matching access requests without human review.
+// Criteria left empty match any request.
+type AutoApprovalRule struct {
+	ID                 int       `json:"id"`
+	Name               string    `json:"name"`
+	Description        *string   `json:"description,omitempty"`
+	ToolID             *int      `json:"tool_id,omitempty"`
+	ToolCategory       *string   `json:"tool_category,omitempty"`
+	AccessLevel        *string   `json:"access_level,omitempty"`
+	RequesterRoleID    *int      `json:"requester_role_id,omitempty"`
+	RequesterGroupID   *int      `json:"requester_group_id,omitempty"`
+	MaxDurationMinutes *int      `json:"max_duration_minutes,omitempty"`
+	StartTime          *string   `json:"start_time,omitempty"`
+	EndTime            *string   `json:"end_time,omitempty"`
+	Priority           int       `json:"priority"`
+	IsActive           bool      `json:"is_active"`
+	CreatedBy          *int      `json:"created_by,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// SLAPolicy sets how long a pending request may wait for a decision before reminders,
+// escalation and a final timeout kick in. A policy with no tool or category is the default.
+type SLAPolicy struct {
+	ID                   int       `json:"id"`
+	Name                 string    `json:"name"`
+	ToolID               *int      `json:"tool_id,omitempty"`
+	ToolCategory         *string   `json:"tool_category,omitempty"`
+	SLAMinutes           int       `json:"sla_minutes"`
+	ReminderAfterMinutes *int      `json:"reminder_after_minutes,omitempty"`
+	EscalateAfterMinutes *int      `json:"escalate_after_minutes,omitempty"`
+	EscalationGroupID    *int      `json:"escalation_group_id,omitempty"`
+	TimeoutAfterMinutes  *int      `json:"timeout_after_minutes,omitempty"`
+	TimeoutAction        string    `json:"timeout_action"`
+	IsActive             bool      `json:"is_active"`
+	CreatedBy            *int      `json:"created_by,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// SLABreach is a pending request that has waited longer than its policy allows
+type SLABreach struct {
+	RequestID         int        `json:"request_id"`
+	UserID            int        `json:"user_id"`
+	UserEmail         string     `json:"user_email"`
+	TargetType        string     `json:"target_type"`
+	TargetID          int        `json:"target_id"`
+	AccessLevel       string     `json:"access_level"`
+	CreatedAt         time.Time  `json:"created_at"`
+	PolicyID          int        `json:"policy_id"`
+	PolicyName        string     `json:"policy_name"`
+	SLAMinutes        int        `json:"sla_minutes"`
+	WaitingMinutes    int        `json:"waiting_minutes"`
+	EscalatedAt       *time.Time `json:"escalated_at,omitempty"`
+	EscalationGroupID *int       `json:"escalation_group_id,omitempty"`
+}
+
+// DormancyPolicy flags or revokes tool grants that have not been used for InactiveDays.
+// Users are warned NoticeDays before a grant is flagged or revoked. A policy with no
+// tool or category is the default.
+type DormancyPolicy struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	ToolID       *int      `json:"tool_id,omitempty"`
+	ToolCategory *string   `json:"tool_category,omitempty"`
+	InactiveDays int       `json:"inactive_days"`
+	Action       string    `json:"action"`
+	NoticeDays   int       `json:"notice_days"`
+	IsActive     bool      `json:"is_active"`
+	CreatedBy    *int      `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DormantGrant is an approved tool grant that has gone unused
+type DormantGrant struct {
+	RequestID   int        `json:"request_id"`
+	UserID      int        `json:"user_id"`
+	UserEmail   string     `json:"user_email"`
+	ToolID      int        `json:"tool_id"`
+	ToolName    string     `json:"tool_name"`
+	AccessLevel string     `json:"access_level"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	IdleDays    int        `json:"idle_days"`
+	NotifiedAt  *time.Time `json:"notified_at,omitempty"`
+	FlaggedAt   *time.Time `json:"flagged_at,omitempty"`
+}
+
+// DormantAccount is an active user who has not logged in recently
+type DormantAccount struct {
+	UserID      int        `json:"user_id"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	IdleDays    int        `json:"idle_days"`
+}
+
+// Connector pushes grants for a tool to its target system. Config holds type-specific
+// settings and is returned with secrets redacted.
+type Connector struct {
+	ID            int             `json:"id"`
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	ToolID        int             `json:"tool_id"`
+	Config        json.RawMessage `json:"config"`
+	MaxAttempts   int             `json:"max_attempts"`
+	AutoRemediate bool            `json:"auto_remediate"`
+	IsActive      bool            `json:"is_active"`
+	CreatedBy     *int            `json:"created_by,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// ProvisioningJob is a queued call to a connector for one grant
+type ProvisioningJob struct {
+	ID              int        `json:"id"`
+	ConnectorID     int        `json:"connector_id"`
+	AccessRequestID int        `json:"access_request_id"`
+	Action          string     `json:"action"`
+	Status          string     `json:"status"`
+	Attempts        int        `json:"attempts"`
+	LastError       *string    `json:"last_error,omitempty"`
+	NextAttemptAt   time.Time  `json:"next_attempt_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ReconciliationRun compares the accounts a connector reports with the grants that
+// should exist in its target system
+type ReconciliationRun struct {
+	ID            int                     `json:"id"`
+	ConnectorID   int                     `json:"connector_id"`
+	TriggeredBy   *int                    `json:"triggered_by,omitempty"`
+	Remediate     bool                    `json:"remediate"`
+	Status        string                  `json:"status"`
+	AccountCount  int                     `json:"account_count"`
+	GrantCount    int                     `json:"grant_count"`
+	OrphanedCount int                     `json:"orphaned_count"`
+	MissingCount  int                     `json:"missing_count"`
+	MismatchCount int                     `json:"mismatch_count"`
+	Error         *string                 `json:"error,omitempty"`
+	StartedAt     time.Time               `json:"started_at"`
+	CompletedAt   *time.Time              `json:"completed_at,omitempty"`
+	Findings      []ReconciliationFinding `json:"findings,omitempty"`
+}
+
+// ReconciliationFinding is one account or grant that differs between gatekeepr and the
+// target system
+type ReconciliationFinding struct {
+	ID               int        `json:"id"`
+	RunID            int        `json:"run_id"`
+	Kind             string     `json:"kind"`
+	UserID           *int       `json:"user_id,omitempty"`
+	UserEmail        string     `json:"user_email"`
+	AccessRequestID  *int       `json:"access_request_id,omitempty"`
+	ExpectedLevel    *string    `json:"expected_level,omitempty"`
+	ActualLevel      *string    `json:"actual_level,omitempty"`
+	ExternalID       *string    `json:"external_id,omitempty"`
+	Remediation      *string    `json:"remediation,omitempty"`
+	RemediationError *string    `json:"remediation_error,omitempty"`
+	RemediatedAt     *time.Time `json:"remediated_at,omitempty"`
+}
+
+// Webhook subscribes an external URL to audit events. Secret is only returned when the
+// webhook is created.
+type Webhook struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      []string  `json:"events"`
+	MaxAttempts int       `json:"max_attempts"`
+	IsActive    bool      `json:"is_active"`
+	CreatedBy   *int      `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one queued or completed call to a webhook
+type WebhookDelivery struct {
+	ID            int             `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	AuditLogID    *int            `json:"audit_log_id,omitempty"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  *int            `json:"response_code,omitempty"`
+	ResponseBody  *string         `json:"response_body,omitempty"`
+	LastError     *string         `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// AccessBundle is a named set of tools, roles and groups users can request in one go
+type AccessBundle struct {
+	ID          int                `json:"id"`
+	Name        string             `json:"name"`
+	DisplayName string             `json:"display_name"`
+	Description *string            `json:"description,omitempty"`
+	IsActive    bool               `json:"is_active"`
+	CreatedBy   *int               `json:"created_by,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Items       []AccessBundleItem `json:"items"`
+}
+
+type AccessBundleItem struct {
+	ID          int    `json:"id"`
+	BundleID    int    `json:"bundle_id"`
+	TargetType  string `json:"target_type"`
+	TargetID    int    `json:"target_id"`
+	AccessLevel string `json:"access_level"`
+
+	// Computed fields
+	TargetName string `json:"target_name,omitempty"`
+}
+
+// BundleRequest is a user's request for a bundle. Status summarises the access
+// requests it fanned out into.
+type BundleRequest struct {
+	ID              int             `json:"id"`
+	BundleID        int             `json:"bundle_id"`
+	UserID          int             `json:"user_id"`
+	Reason          *string         `json:"reason,omitempty"`
+	DurationMinutes *int            `json:"duration_minutes,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Status          string          `json:"status"`
+	StatusCounts    map[string]int  `json:"status_counts"`
+	Items           []AccessRequest `json:"items,omitempty"`
+
+	// Computed fields
+	BundleName string `json:"bundle_name,omitempty"`
+	UserEmail  string `json:"user_email,omitempty"`
+}
+
+// SoDRule declares two entitlements that must not be held by the same user
+type SoDRule struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	Description      *string   `json:"description,omitempty"`
+	LeftType         string    `json:"left_type"`
+	LeftID           int       `json:"left_id"`
+	LeftAccessLevel  *string   `json:"left_access_level,omitempty"`
+	RightType        string    `json:"right_type"`
+	RightID          int       `json:"right_id"`
+	RightAccessLevel *string   `json:"right_access_level,omitempty"`
+	Enforcement      string    `json:"enforcement"`
+	IsActive         bool      `json:"is_active"`
+	CreatedBy        *int      `json:"created_by,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// SoDViolation records a conflict detected while changing a user's entitlements
+type SoDViolation struct {
+	ID              int        `json:"id"`
+	RuleID          int        `json:"rule_id"`
+	UserID          int        `json:"user_id"`
+	Action          string     `json:"action"`
+	EntitlementType string     `json:"entitlement_type"`
+	EntitlementID   int        `json:"entitlement_id"`
+	AccessLevel     *string    `json:"access_level,omitempty"`
+	Status          string     `json:"status"`
+	RequestedBy     *int       `json:"requested_by,omitempty"`
+	DecidedBy       *int       `json:"decided_by,omitempty"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty"`
+	DecisionReason  *string    `json:"decision_reason,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	// Computed fields
+	RuleName  string `json:"rule_name,omitempty"`
+	UserEmail string `json:"user_email,omitempty"`
+}
+
 // Resource represents a resource (kept for backward compatibility)
 type Resource struct {
 	ID          int    `json:"id"`
@@ -193,6 +749,7 @@ type CreateToolRequest struct {
 	Description *string `json:"description,omitempty"`
 	Category    *string `json:"category,omitempty"`
 	Icon        *string `json:"icon,omitempty"`
+	HighRisk    bool    `json:"high_risk"`
 }
 
 type UpdateToolRequest struct {
@@ -200,9 +757,28 @@ type UpdateToolRequest struct {
 	Description *string `json:"description,omitempty"`
 	Category    *string `json:"category,omitempty"`
 	Icon        *string `json:"icon,omitempty"`
+	HighRisk    *bool   `json:"high_risk,omitempty"`
 	IsActive    *bool   `json:"is_active,omitempty"`
 }
 
+type ToolAccessLevelRequest struct {
+	Name                 string  `json:"name"`
+	Description          *string `json:"description,omitempty"`
+	AutoApprove          bool    `json:"auto_approve"`
+	ApproverMinHierarchy *int    `json:"approver_min_hierarchy,omitempty"`
+}
+
+// SetToolAccessLevelsRequest replaces a tool's levels, listed from least to most
+// privileged. An empty list restores the default levels.
+type SetToolAccessLevelsRequest struct {
+	Levels []ToolAccessLevelRequest `json:"levels"`
+}
+
+type AddToolOwnerRequest struct {
+	OwnerType string `json:"owner_type"`
+	OwnerID   int    `json:"owner_id"`
+}
+
 type CreateGroupRequest struct {
 	Name        string  `json:"name"`
 	DisplayName string  `json:"display_name"`
@@ -246,6 +822,92 @@ type CreateAccessRequestDTO struct {
 	DurationMinutes *int    `json:"duration_minutes,omitempty"`
 }
 
+type CreateConnectorRequest struct {
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	ToolID        int             `json:"tool_id"`
+	Config        json.RawMessage `json:"config"`
+	MaxAttempts   *int            `json:"max_attempts,omitempty"`
+	AutoRemediate bool            `json:"auto_remediate"`
+}
+
+type UpdateConnectorRequest struct {
+	Config        json.RawMessage `json:"config,omitempty"`
+	MaxAttempts   *int            `json:"max_attempts,omitempty"`
+	AutoRemediate *bool           `json:"auto_remediate,omitempty"`
+	IsActive      *bool           `json:"is_active,omitempty"`
+}
+
+// NotificationPreference says whether a user receives an event on a channel
+type NotificationPreference struct {
+	Event   string `json:"event"`
+	Channel string `json:"channel"`
+	Enabled bool   `json:"enabled"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// Notification is an entry in a user's in-app inbox
+type Notification struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Event     string     `json:"event"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	RequestID *int       `json:"request_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"`
+	Events      []string `json:"events"`
+	MaxAttempts *int     `json:"max_attempts,omitempty"`
+}
+
+type UpdateWebhookRequest struct {
+	Name        *string   `json:"name,omitempty"`
+	URL         *string   `json:"url,omitempty"`
+	Secret      *string   `json:"secret,omitempty"`
+	Events      *[]string `json:"events,omitempty"`
+	MaxAttempts *int      `json:"max_attempts,omitempty"`
+	IsActive    *bool     `json:"is_active,omitempty"`
+}
+
+type ReconcileConnectorRequest struct {
+	Remediate bool `json:"remediate"`
+}
+
+type AccessBundleItemRequest struct {
+	TargetType  string `json:"target_type"`
+	TargetID    int    `json:"target_id"`
+	AccessLevel string `json:"access_level"`
+}
+
+type CreateAccessBundleRequest struct {
+	Name        string                    `json:"name"`
+	DisplayName string                    `json:"display_name"`
+	Description *string                   `json:"description,omitempty"`
+	Items       []AccessBundleItemRequest `json:"items"`
+}
+
+// UpdateAccessBundleRequest replaces the bundle's items when Items is set
+type UpdateAccessBundleRequest struct {
+	DisplayName *string                    `json:"display_name,omitempty"`
+	Description *string                    `json:"description,omitempty"`
+	IsActive    *bool                      `json:"is_active,omitempty"`
+	Items       *[]AccessBundleItemRequest `json:"items,omitempty"`
+}
+
+type RequestAccessBundleRequest struct {
+	Reason          *string `json:"reason,omitempty"`
+	DurationMinutes *int    `json:"duration_minutes,omitempty"`
+}
+
 type ApproveAccessRequest struct {
 	DurationMinutes *int `json:"duration_minutes,omitempty"`
 }
@@ -254,6 +916,57 @@ type RejectAccessRequest struct {
 	Reason string `json:"reason"`
 }
 
+type ExtendAccessRequest struct {
+	DurationMinutes int     `json:"duration_minutes"`
+	Reason          *string `json:"reason,omitempty"`
+}
+
+type ReRequestAccessRequest struct {
+	AccessLevel     *string `json:"access_level,omitempty"`
+	Reason          *string `json:"reason,omitempty"`
+	DurationMinutes *int    `json:"duration_minutes,omitempty"`
+}
+
+type CreateDelegationRequest struct {
+	DelegateID int     `json:"delegate_id"`
+	StartsAt   string  `json:"starts_at"`
+	EndsAt     string  `json:"ends_at"`
+	Reason     *string `json:"reason,omitempty"`
+}
+
+type CreateReviewCampaignRequest struct {
+	Name               string  `json:"name"`
+	Description        *string `json:"description,omitempty"`
+	ScopeType          string  `json:"scope_type"`
+	ScopeID            *int    `json:"scope_id,omitempty"`
+	ScopeValue         *string `json:"scope_value,omitempty"`
+	DueAt              string  `json:"due_at"`
+	RevokeOnNoResponse bool    `json:"revoke_on_no_response"`
+}
+
+type ReviewDecisionRequest struct {
+	Decision string  `json:"decision"`
+	Comment  *string `json:"comment,omitempty"`
+}
+
+type ReassignReviewItemRequest struct {
+	ReviewerID int `json:"reviewer_id"`
+}
+
+type VerifyReportRequest struct {
+	Report    json.RawMessage `json:"report"`
+	Signature string          `json:"signature"`
+}
+
+type UpdateUserOrgRequest struct {
+	Department *string `json:"department,omitempty"`
+	ManagerID  *int    `json:"manager_id,omitempty"`
+}
+
+type CreateCommentRequest struct {
+	Body string `json:"body"`
+}
+
 type DirectGrantRequest struct {
 	UserID          int    `json:"user_id"`
 	TargetType      string `json:"target_type"`
@@ -262,12 +975,163 @@ type DirectGrantRequest struct {
 	DurationMinutes *int   `json:"duration_minutes,omitempty"`
 }
 
+type BreakGlassRequest struct {
+	TargetType        string `json:"target_type"`
+	TargetID          int    `json:"target_id"`
+	AccessLevel       string `json:"access_level"`
+	Reason            string `json:"reason"`
+	IncidentReference string `json:"incident_reference"`
+	DurationMinutes   *int   `json:"duration_minutes,omitempty"`
+}
+
+type AcknowledgeBreakGlassRequest struct {
+	Notes *string `json:"notes,omitempty"`
+}
+
 type RevokeAccessRequest struct {
 	UserID     int    `json:"user_id"`
 	TargetType string `json:"target_type"`
 	TargetID   int    `json:"target_id"`
 }
 
+type CreateAutoApprovalRuleRequest struct {
+	Name               string  `json:"name"`
+	Description        *string `json:"description,omitempty"`
+	ToolID             *int    `json:"tool_id,omitempty"`
+	ToolCategory       *string `json:"tool_category,omitempty"`
+	AccessLevel        *string `json:"access_level,omitempty"`
+	RequesterRoleID    *int    `json:"requester_role_id,omitempty"`
+	RequesterGroupID   *int    `json:"requester_group_id,omitempty"`
+	MaxDurationMinutes *int    `json:"max_duration_minutes,omitempty"`
+	StartTime          *string `json:"start_time,omitempty"`
+	EndTime            *string `json:"end_time,omitempty"`
+	Priority           int     `json:"priority"`
+}
+
+type UpdateAutoApprovalRuleRequest struct {
+	Description        *string `json:"description,omitempty"`
+	ToolID             *int    `json:"tool_id,omitempty"`
+	ToolCategory       *string `json:"tool_category,omitempty"`
+	AccessLevel        *string `json:"access_level,omitempty"`
+	RequesterRoleID    *int    `json:"requester_role_id,omitempty"`
+	RequesterGroupID   *int    `json:"requester_group_id,omitempty"`
+	MaxDurationMinutes *int    `json:"max_duration_minutes,omitempty"`
+	StartTime          *string `json:"start_time,omitempty"`
+	EndTime            *string `json:"end_time,omitempty"`
+	Priority           *int    `json:"priority,omitempty"`
+	IsActive           *bool   `json:"is_active,omitempty"`
+}
+
+type CreateSLAPolicyRequest struct {
+	Name                 string  `json:"name"`
+	ToolID               *int    `json:"tool_id,omitempty"`
+	ToolCategory         *string `json:"tool_category,omitempty"`
+	SLAMinutes           int     `json:"sla_minutes"`
+	ReminderAfterMinutes *int    `json:"reminder_after_minutes,omitempty"`
+	EscalateAfterMinutes *int    `json:"escalate_after_minutes,omitempty"`
+	EscalationGroupID    *int    `json:"escalation_group_id,omitempty"`
+	TimeoutAfterMinutes  *int    `json:"timeout_after_minutes,omitempty"`
+	TimeoutAction        string  `json:"timeout_action"`
+}
+
+type UpdateSLAPolicyRequest struct {
+	ToolID               *int    `json:"tool_id,omitempty"`
+	ToolCategory         *string `json:"tool_category,omitempty"`
+	SLAMinutes           *int    `json:"sla_minutes,omitempty"`
+	ReminderAfterMinutes *int    `json:"reminder_after_minutes,omitempty"`
+	EscalateAfterMinutes *int    `json:"escalate_after_minutes,omitempty"`
+	EscalationGroupID    *int    `json:"escalation_group_id,omitempty"`
+	TimeoutAfterMinutes  *int    `json:"timeout_after_minutes,omitempty"`
+	TimeoutAction        *string `json:"timeout_action,omitempty"`
+	IsActive             *bool   `json:"is_active,omitempty"`
+}
+
+type CreateDormancyPolicyRequest struct {
+	Name         string  `json:"name"`
+	ToolID       *int    `json:"tool_id,omitempty"`
+	ToolCategory *string `json:"tool_category,omitempty"`
+	InactiveDays int     `json:"inactive_days"`
+	Action       string  `json:"action"`
+	NoticeDays   *int    `json:"notice_days,omitempty"`
+}
+
+type UpdateDormancyPolicyRequest struct {
+	ToolID       *int    `json:"tool_id,omitempty"`
+	ToolCategory *string `json:"tool_category,omitempty"`
+	InactiveDays *int    `json:"inactive_days,omitempty"`
+	Action       *string `json:"action,omitempty"`
+	NoticeDays   *int    `json:"notice_days,omitempty"`
+	IsActive     *bool   `json:"is_active,omitempty"`
+}
+
+// UsageEvent reports that a user used a tool. Users and tools may be identified by
+// ID or by email and name.
+type UsageEvent struct {
+	UserID    int    `json:"user_id,omitempty"`
+	UserEmail string `json:"user_email,omitempty"`
+	ToolID    int    `json:"tool_id,omitempty"`
+	ToolName  string `json:"tool_name,omitempty"`
+	UsedAt    string `json:"used_at,omitempty"`
+}
+
+type UsageReportRequest struct {
+	Source string       `json:"source"`
+	Events []UsageEvent `json:"events"`
+}
+
+type AutoApprovalDryRunRequest struct {
+	UserID          int     `json:"user_id"`
+	TargetType      string  `json:"target_type"`
+	TargetID        int     `json:"target_id"`
+	AccessLevel     string  `json:"access_level"`
+	DurationMinutes *int    `json:"duration_minutes,omitempty"`
+	At              *string `json:"at,omitempty"`
+}
+
+type CreateSoDRuleRequest struct {
+	Name             string  `json:"name"`
+	Description      *string `json:"description,omitempty"`
+	LeftType         string  `json:"left_type"`
+	LeftID           int     `json:"left_id"`
+	LeftAccessLevel  *string `json:"left_access_level,omitempty"`
+	RightType        string  `json:"right_type"`
+	RightID          int     `json:"right_id"`
+	RightAccessLevel *string `json:"right_access_level,omitempty"`
+	Enforcement      string  `json:"enforcement"`
+}
+
+type UpdateSoDRuleRequest struct {
+	Description *string `json:"description,omitempty"`
+	Enforcement *string `json:"enforcement,omitempty"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+}
+
+type DecideSoDExceptionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type SetAuditRetentionRequest struct {
+	RetentionDays int `json:"retention_days"`
+}
+
+type CreateAuditLegalHoldRequest struct {
+	Name           string  `json:"name"`
+	Reason         *string `json:"reason,omitempty"`
+	ActionCategory *string `json:"action_category,omitempty"`
+	ActorID        *int    `json:"actor_id,omitempty"`
+	TargetType     *string `json:"target_type,omitempty"`
+	TargetID       *int    `json:"target_id,omitempty"`
+	DateFrom       *string `json:"date_from,omitempty"`
+	DateTo         *string `json:"date_to,omitempty"`
+}
+
+// RestoreAuditArchiveRequest limits a restore to a range of audit log IDs; without
+// either bound the whole archive is restored
+type RestoreAuditArchiveRequest struct {
+	FromID *int `json:"from_id,omitempty"`
+	ToID   *int `json:"to_id,omitempty"`
+}
+
 type AuditLogFilter struct {
 	ActorID        *int    `json:"actor_id,omitempty"`
 	TargetID       *int    `json:"target_id,omitempty"`
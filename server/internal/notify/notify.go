@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Notification events users can set preferences for
+const (
+	EventRequestCreated  = "request_created"
+	EventRequestApproved = "request_approved"
+	EventRequestRejected = "request_rejected"
+	EventRequestExpiring = "request_expiring"
+	EventRequestExpired  = "request_expired"
+	// EventGeneral covers reminders, escalations, reviews and other one-off notices
+	EventGeneral = "general"
+)
+
+// Events lists every notification event
+var Events = []string{
+	EventRequestCreated,
+	EventRequestApproved,
+	EventRequestRejected,
+	EventRequestExpiring,
+	EventRequestExpired,
+	EventGeneral,
+}
+
+// Recipient is the user a message is addressed to
+type Recipient struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Message is a rendered notification for one recipient. RequestID is set when the
+// message is about an access request.
+type Message struct {
+	Event     string    `json:"event"`
+	Recipient Recipient `json:"recipient"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	RequestID int       `json:"request_id,omitempty"`
+}
+
+// Channel delivers messages over one medium such as email or chat
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// PrivateChannel is implemented by channels that deliver each message to its
+// recipient alone, such as email. Only these may carry per-user secrets like action
+// links.
+type PrivateChannel interface {
+	Channel
+	Private() bool
+}
+
+// IsPrivate reports whether a channel delivers each message to its recipient alone
+func IsPrivate(ch Channel) bool {
+	p, ok := ch.(PrivateChannel)
+	return ok && p.Private()
+}
+
+// LogChannel writes messages to the server log. It is used when no other channel is
+// configured so notifications are never silently dropped.
+type LogChannel struct{}
+
+func (LogChannel) Name() string { return "log" }
+
+func (LogChannel) Send(ctx context.Context, msg Message) error {
+	log.Printf("NOTIFY %s <%s>: %s - %s", msg.Event, msg.Recipient.Email, msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryChannel keeps messages in memory so tests and local runs can inspect what
+// would have been sent
+type MemoryChannel struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (c *MemoryChannel) Name() string { return "memory" }
+
+func (c *MemoryChannel) Send(ctx context.Context, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of every message sent so far
+func (c *MemoryChannel) Sent() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.sent...)
+}
+
+// Reset forgets all sent messages
+func (c *MemoryChannel) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = nil
+}
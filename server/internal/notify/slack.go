@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackChannel posts messages to a Slack-compatible incoming webhook. Incoming
+// webhooks post to a fixed channel, so each message names the user it is for.
+type SlackChannel struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackChannel builds a chat channel for an incoming webhook URL
+func NewSlackChannel(webhookURL string) (*SlackChannel, error) {
+	if !strings.HasPrefix(webhookURL, "http://") && !strings.HasPrefix(webhookURL, "https://") {
+		return nil, errors.New("slack webhook URL must be an http or https URL")
+	}
+	return &SlackChannel{webhookURL: webhookURL, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (c *SlackChannel) Name() string { return "slack" }
+
+func (c *SlackChannel) Send(ctx context.Context, msg Message) error {
+	text := fmt.Sprintf("*%s*\n%s", msg.Subject, msg.Body)
+	if msg.Recipient.Email != "" {
+		text = fmt.Sprintf("For %s: %s", msg.Recipient.Email, text)
+	}
+	payload, _ := json.Marshal(map[string]string{"text": text})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
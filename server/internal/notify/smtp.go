@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig configures the email channel. Username and Password are optional so a
+// local SMTP stand-in without authentication works.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPChannel sends plain-text email through an SMTP server
+type SMTPChannel struct {
+	config SMTPConfig
+}
+
+// NewSMTPChannel builds an email channel, defaulting to port 25
+func NewSMTPChannel(config SMTPConfig) (*SMTPChannel, error) {
+	if config.Host == "" || config.From == "" {
+		return nil, errors.New("smtp host and from address are required")
+	}
+	if config.Port == 0 {
+		config.Port = 25
+	}
+	return &SMTPChannel{config: config}, nil
+}
+
+func (c *SMTPChannel) Name() string { return "email" }
+
+// Private is true: each email goes to its recipient's own address
+func (c *SMTPChannel) Private() bool { return true }
+
+func (c *SMTPChannel) Send(ctx context.Context, msg Message) error {
+	if msg.Recipient.Email == "" {
+		return errors.New("recipient has no email address")
+	}
+
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.Recipient.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return c.sendMail(ctx, auth, msg.Recipient.Email, []byte(b.String()))
+}
+
+// sendMail does what smtp.SendMail does, but bounded by ctx so a server that stops
+// responding cannot hold up delivery indefinitely
+func (c *SMTPChannel) sendMail(ctx context.Context, auth smtp.Auth, to string, data []byte) error {
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Cancelling ctx unblocks any read or write in progress
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, c.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.config.Host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.config.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// headerValue keeps user-supplied text from injecting extra headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RequestNotice is the data the access request templates are rendered with
+type RequestNotice struct {
+	RequestID       int
+	Requester       string
+	TargetType      string
+	TargetName      string
+	AccessLevel     string
+	Reason          string
+	DecidedBy       string
+	RejectionReason string
+	ExpiresAt       *time.Time
+	ApproveURL      string
+	RejectURL       string
+	LinksExpireAt   *time.Time
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var funcs = template.FuncMap{
+	"when": func(t *time.Time) string {
+		if t == nil {
+			return "never"
+		}
+		return t.Format("2006-01-02 15:04 MST")
+	},
+}
+
+var templates = map[string]messageTemplate{
+	EventRequestCreated: parse(
+		`Access request #{{.RequestID}} needs your decision`,
+		`{{.Requester}} requested {{.AccessLevel}} access to {{.TargetName}}.
+{{if .Reason}}Reason: {{.Reason}}
+{{end}}{{if .ApproveURL}}
+Approve: {{.ApproveURL}}
+Reject: {{.RejectURL}}
+These links work once and expire {{when .LinksExpireAt}}.
+{{end}}`),
+	EventRequestApproved: parse(
+		`Your access to {{.TargetName}} was approved`,
+		`Your request #{{.RequestID}} for {{.AccessLevel}} access to {{.TargetName}} was approved{{if .DecidedBy}} by {{.DecidedBy}}{{end}}.
+{{if .ExpiresAt}}The access expires {{when .ExpiresAt}}.
+{{end}}`),
+	EventRequestRejected: parse(
+		`Your access to {{.TargetName}} was rejected`,
+		`Your request #{{.RequestID}} for {{.AccessLevel}} access to {{.TargetName}} was rejected{{if .DecidedBy}} by {{.DecidedBy}}{{end}}.
+{{if .RejectionReason}}Reason: {{.RejectionReason}}
+{{end}}`),
+	EventRequestExpiring: parse(
+		`Your access to {{.TargetName}} expires soon`,
+		`Your {{.AccessLevel}} access to {{.TargetName}} (request #{{.RequestID}}) expires {{when .ExpiresAt}}.
+Request an extension if you still need it.
+`),
+	EventRequestExpired: parse(
+		`Your access to {{.TargetName}} has expired`,
+		`Your {{.AccessLevel}} access to {{.TargetName}} (request #{{.RequestID}}) expired {{when .ExpiresAt}}.
+Request it again if you still need it.
+`),
+}
+
+func parse(subject string, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Funcs(funcs).Parse(subject)),
+		body:    template.Must(template.New("body").Funcs(funcs).Parse(body)),
+	}
+}
+
+// Render fills in the subject and body template of an event
+func Render(event string, data interface{}) (string, string, error) {
+	t, ok := templates[event]
+	if !ok {
+		return "", "", fmt.Errorf("no template for notification event %q", event)
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), strings.TrimSpace(body.String()), nil
+}